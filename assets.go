@@ -0,0 +1,99 @@
+package pdfmark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register JPEG for image validation
+	_ "image/png"  // register PNG for image validation
+	"io/fs"
+	"strconv"
+
+	"github.com/anujkumar-df/pdfmark/internal/decode"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+)
+
+// assets holds the files referenced by instructions, keyed by reference.
+type assets struct {
+	images map[string][]byte
+	pdfs   map[string][]byte
+}
+
+// loadAssets reads every image and stamp PDF referenced by instructions,
+// first from the caller-supplied maps and then from the matching file
+// system. Images must be PNG or JPEG; stamp PDFs must be readable and have
+// the referenced page. Every failing reference is reported.
+func loadAssets(instructions []spec.Instruction, cfg config) (assets, error) {
+	a := assets{images: make(map[string][]byte), pdfs: make(map[string][]byte)}
+	pageCounts := make(map[string]int)
+	var problems []*errs.InstructionError
+	fail := func(err error, ins spec.Instruction, column, value, detail string) {
+		problems = append(problems, &errs.InstructionError{Err: err, Line: ins.Line, Column: column, Value: value, Detail: detail})
+	}
+	for _, ins := range instructions {
+		switch {
+		case ins.Image != "":
+			if _, done := a.images[ins.Image]; done {
+				continue
+			}
+			data, err := openAsset(ins.Image, cfg.images, cfg.imageFS)
+			if err != nil {
+				fail(err, ins, decode.FieldImage, ins.Image, "")
+				continue
+			}
+			if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+				fail(errs.ErrInvalidImage, ins, decode.FieldImage, ins.Image, err.Error())
+				continue
+			}
+			a.images[ins.Image] = data
+
+		case ins.PDF != "":
+			n, done := pageCounts[ins.PDF]
+			if !done {
+				data, err := openAsset(ins.PDF, cfg.pdfs, cfg.pdfFS)
+				if err == nil {
+					n, err = stamp.StampPageCount(data)
+				}
+				if err != nil {
+					// Remember the failure so the stamp is reported once.
+					pageCounts[ins.PDF] = -1
+					fail(err, ins, decode.FieldPDF, ins.PDF, "")
+					continue
+				}
+				a.pdfs[ins.PDF] = data
+				pageCounts[ins.PDF] = n
+			}
+			if n < 0 {
+				continue
+			}
+			if page := max(ins.PDFPage, 1); page > n {
+				fail(errs.ErrPageOutOfRange, ins, decode.FieldPDFPage, strconv.Itoa(page),
+					fmt.Sprintf("stamp %q has %d pages", ins.PDF, n))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return assets{}, errs.Join(problems)
+	}
+	return a, nil
+}
+
+// openAsset looks name up in m and then in fsys.
+func openAsset(name string, m map[string][]byte, fsys fs.FS) ([]byte, error) {
+	if data, ok := m[name]; ok {
+		return data, nil
+	}
+	if fsys != nil {
+		data, err := fs.ReadFile(fsys, name)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrInvalid) {
+			return nil, err
+		}
+	}
+	return nil, errs.ErrAssetNotFound
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// runCheck implements "pdfmark check", validating a PDF and its
+// instructions and reporting what would be stamped without writing output.
+// It exits with status 1 if the instructions are invalid.
+func runCheck(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("pdfmark check", flag.ExitOnError)
+	pdfPath := fs.String("pdf", "", "path to input PDF")
+	csvPath := fs.String("csv", "", "path to watermark instructions (CSV, XLSX, JSON or YAML)")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	wf := addWatermarkFlags(fs)
+	fs.Parse(args)
+
+	if *pdfPath == "" || *csvPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: pdfmark check -pdf input.pdf -csv watermarks.csv [-json] [style flags]")
+		os.Exit(1)
+	}
+
+	opts, err := wf.options(*pdfPath, *csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pdfFile, err := os.Open(*pdfPath)
+	if err != nil {
+		log.Fatalf("opening PDF: %v", err)
+	}
+	defer pdfFile.Close()
+
+	csvFile, err := os.Open(*csvPath)
+	if err != nil {
+		log.Fatalf("opening CSV: %v", err)
+	}
+	defer csvFile.Close()
+
+	plan, err := pdfmark.Plan(ctx, pdfFile, csvFile, opts...)
+	var report *pdfmark.ValidationReport
+	if err != nil && !errors.As(err, &report) {
+		log.Fatalf("check failed: %v", err)
+	}
+
+	out := newCheckOutput(plan, report)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(out); err != nil {
+			log.Fatalf("writing JSON: %v", err)
+		}
+	} else {
+		out.print(os.Stdout, filepath.Base(*pdfPath))
+	}
+	if !out.OK {
+		os.Exit(1)
+	}
+}
+
+// checkOutput is the result of "pdfmark check", in the shape printed with
+// -json.
+type checkOutput struct {
+	OK         bool           `json:"ok"`
+	TotalPages int            `json:"total_pages,omitempty"`
+	Pages      []checkPage    `json:"pages,omitempty"`
+	Problems   []checkProblem `json:"problems,omitempty"`
+	Skipped    []checkProblem `json:"skipped,omitempty"`
+	Warnings   []string       `json:"warnings,omitempty"`
+}
+
+type checkPage struct {
+	Page   int          `json:"page"`
+	Stamps []checkStamp `json:"stamps"`
+}
+
+type checkStamp struct {
+	Line     int     `json:"line"`
+	Text     string  `json:"text,omitempty"`
+	Image    string  `json:"image,omitempty"`
+	PDF      string  `json:"pdf,omitempty"`
+	PDFPage  int     `json:"pdf_page,omitempty"`
+	Font     string  `json:"font"`
+	FontSize int     `json:"font_size"`
+	Color    string  `json:"color"`
+	Opacity  float64 `json:"opacity"`
+	Position string  `json:"position"`
+	OnTop    bool    `json:"on_top"`
+}
+
+type checkProblem struct {
+	Line   int    `json:"line,omitempty"`
+	Column string `json:"column,omitempty"`
+	Page   int    `json:"page,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Error  string `json:"error"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func newCheckProblem(p *pdfmark.InstructionError) checkProblem {
+	return checkProblem{
+		Line:   p.Line,
+		Column: p.Column,
+		Page:   p.Page,
+		Value:  p.Value,
+		Error:  p.Err.Error(),
+		Detail: p.Detail,
+	}
+}
+
+func newCheckOutput(plan *pdfmark.StampPlan, report *pdfmark.ValidationReport) checkOutput {
+	var out checkOutput
+	if report != nil {
+		for _, p := range report.Problems {
+			out.Problems = append(out.Problems, newCheckProblem(p))
+		}
+		for _, w := range report.Warnings {
+			out.Warnings = append(out.Warnings, w.Error())
+		}
+	}
+	if plan == nil {
+		return out
+	}
+	out.OK = true
+	out.TotalPages = plan.TotalPages
+	for _, w := range plan.Warnings {
+		out.Warnings = append(out.Warnings, w.Error())
+	}
+	for _, p := range plan.Skipped {
+		out.Skipped = append(out.Skipped, newCheckProblem(p))
+	}
+	for _, pp := range plan.Pages {
+		page := checkPage{Page: pp.Page}
+		for _, s := range pp.Stamps {
+			page.Stamps = append(page.Stamps, checkStamp{
+				Line:     s.Line,
+				Text:     s.Text,
+				Image:    s.Image,
+				PDF:      s.PDF,
+				PDFPage:  s.PDFPage,
+				Font:     s.Style.FontName,
+				FontSize: s.Style.FontSize,
+				Color:    s.Style.Color.String(),
+				Opacity:  s.Style.Opacity,
+				Position: s.Style.Position.String(),
+				OnTop:    s.Style.OnTop,
+			})
+		}
+		out.Pages = append(out.Pages, page)
+	}
+	return out
+}
+
+// print writes out in human-readable form; name identifies the PDF.
+func (out checkOutput) print(w io.Writer, name string) {
+	for _, warning := range out.Warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+	if !out.OK {
+		fmt.Fprintf(w, "%s: %d problem(s) found\n", name, len(out.Problems))
+		for _, p := range out.Problems {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+		return
+	}
+	for _, p := range out.Skipped {
+		fmt.Fprintf(w, "skipped: %s\n", p)
+	}
+	fmt.Fprintf(w, "%s: %d pages, %d to be watermarked\n", name, out.TotalPages, len(out.Pages))
+	for _, page := range out.Pages {
+		for _, s := range page.Stamps {
+			fmt.Fprintf(w, "  page %d: %s (line %d)\n", page.Page, s, s.Line)
+		}
+	}
+}
+
+func (s checkStamp) String() string {
+	switch {
+	case s.Image != "":
+		return fmt.Sprintf("image %s", s.Image)
+	case s.PDF != "":
+		return fmt.Sprintf("page %d of %s", s.PDFPage, s.PDF)
+	}
+	return fmt.Sprintf("%q, %s %dpt %s, %s", s.Text, s.Font, s.FontSize, s.Color, s.Position)
+}
+
+func (p checkProblem) String() string {
+	msg := p.Error
+	if p.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", p.Line, msg)
+	}
+	if p.Column != "" {
+		msg += ": " + p.Column
+		if p.Value != "" {
+			msg += fmt.Sprintf(" %q", p.Value)
+		}
+	} else if p.Value != "" {
+		msg += fmt.Sprintf(": %q", p.Value)
+	}
+	if p.Page > 0 {
+		msg += fmt.Sprintf(": page %d", p.Page)
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return msg
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// watermarkFlags holds the flags shared by the commands that stamp PDFs.
+type watermarkFlags struct {
+	fs         *flag.FlagSet
+	font       *string
+	size       *int
+	color      *string
+	opacity    *float64
+	rotation   *float64
+	position   *string
+	underlay   *bool
+	tileGapX   *float64
+	tileGapY   *float64
+	stagger    *bool
+	tileMargin *float64
+	margin     *float64
+	strict     *bool
+	layer      *bool
+	policy     *string
+	imagesDir  *string
+	maxSize    *int64
+	format     *string
+	vars       nameValues
+	delimiter  *string
+	comment    *string
+	headers    nameValues
+	noHeader   *bool
+	columns    *string
+	charset    *string
+	sheet      *string
+
+	password      *string
+	ownerPassword *string
+	encryptUser   *string
+	encryptOwner  *string
+	encryptKey    *int
+	noPrint       *bool
+	noCopy        *bool
+	noModify      *bool
+	forensicMark  *string
+	forensicKey   *string
+	replace       *bool
+}
+
+func addWatermarkFlags(fs *flag.FlagSet) *watermarkFlags {
+	def := pdfmark.DefaultStyle()
+	f := &watermarkFlags{fs: fs, vars: make(nameValues), headers: make(nameValues)}
+	f.font = fs.String("font", def.FontName, "watermark font (Helvetica, Times-Roman, Courier)")
+	f.size = fs.Int("size", def.FontSize, "watermark font size in points")
+	f.color = fs.String("color", "gray", "watermark color: name, #RRGGBB or \"r g b\"")
+	f.opacity = fs.Float64("opacity", def.Opacity, "watermark opacity (0, 1]")
+	f.rotation = fs.Float64("rotation", 0, "rotation in degrees (default: diagonal)")
+	f.position = fs.String("position", def.Position.String(), "anchor: center, top-left, bottom-right, ..., header, footer-left, left-margin, ..., or tiled to repeat the text across the page")
+	f.underlay = fs.Bool("underlay", false, "draw the watermark beneath the page content")
+	f.tileGapX = fs.Float64("tile-gap-x", def.Tile.GapX, "with -position tiled: horizontal space between tiles in points")
+	f.tileGapY = fs.Float64("tile-gap-y", def.Tile.GapY, "with -position tiled: vertical space between tiles in points")
+	f.stagger = fs.Bool("tile-stagger", false, "with -position tiled: shift every other row by half a tile")
+	f.tileMargin = fs.Float64("tile-margin", def.Tile.Margin, "with -position tiled: space kept free along the page edges in points")
+	f.margin = fs.Float64("margin", def.Margin, "with a header, footer or margin -position: distance from the page edge in points")
+	f.strict = fs.Bool("strict-columns", false, "reject unknown CSV columns instead of warning")
+	f.layer = fs.Bool("layer", false, "allow several CSV rows per page, stacked in CSV order")
+	f.replace = fs.Bool("replace", false, "remove existing watermarks from the pages stamped, e.g. DRAFT before FINAL")
+	f.policy = fs.String("page-policy", "strict", "pages past the end or repeated: strict (fail), skip or clamp (to the last page)")
+	f.imagesDir = fs.String("images", "", "directory for image and stamp PDF paths in the CSV (default: the CSV's directory)")
+	f.format = fs.String("format", "auto", "instruction format: auto (from the file extension or content), csv, xlsx, json or yaml")
+	f.maxSize = fs.Int64("max-size", 0, "reject input PDFs larger than this many bytes (0: no limit)")
+	fs.Var(f.vars, "var", "template variable name=value for the watermark text (repeatable)")
+	f.delimiter = fs.String("delimiter", "", "CSV delimiter, e.g. ';' or 'tab' (default: detected)")
+	f.comment = fs.String("comment", "", "CSV comment character, e.g. '#'")
+	fs.Var(f.headers, "header", "CSV header alias name=field, e.g. Seite=page (repeatable)")
+	f.noHeader = fs.Bool("no-header", false, "the CSV has no header row; see -columns")
+	f.columns = fs.String("columns", "page,watermark_text", "fields of a CSV without header row, in order")
+	f.sheet = fs.String("sheet", "", "worksheet to read from an XLSX file (default: the first)")
+	f.charset = fs.String("charset", "", "CSV character encoding, e.g. windows-1252 (default: UTF-8)")
+	f.password = fs.String("password", "", "user password of an encrypted input PDF")
+	f.ownerPassword = fs.String("owner-password", "", "owner password of an encrypted input PDF")
+	f.encryptUser = fs.String("encrypt-password", "", "encrypt the output; password needed to open it")
+	f.encryptOwner = fs.String("encrypt-owner-password", "", "encrypt the output; password granting all permissions (default: -encrypt-password)")
+	f.encryptKey = fs.Int("encrypt-key", 256, "AES key length of the output encryption: 128 or 256")
+	f.noPrint = fs.Bool("no-print", false, "forbid printing the encrypted output")
+	f.noCopy = fs.Bool("no-copy", false, "forbid copying text and images from the encrypted output")
+	f.noModify = fs.Bool("no-modify", false, "forbid editing, annotating, filling forms in and assembling the encrypted output")
+	f.forensicMark = fs.String("forensic-mark", "", "hide this ID in the output for \"pdfmark trace\"; may use placeholders such as {{email}}")
+	f.forensicKey = fs.String("forensic-key-file", "", "file holding the secret key sealing the -forensic-mark, at least 16 bytes")
+	return f
+}
+
+// options turns the parsed flags into library options for watermarking
+// pdfPath with the instructions in csvPath.
+func (f *watermarkFlags) options(pdfPath, csvPath string) ([]pdfmark.Option, error) {
+	style := pdfmark.DefaultStyle()
+	style.FontName = *f.font
+	style.FontSize = *f.size
+	style.Opacity = *f.opacity
+	style.OnTop = !*f.underlay
+	style.Tile = pdfmark.Tiling{GapX: *f.tileGapX, GapY: *f.tileGapY, Stagger: *f.stagger, Margin: *f.tileMargin}
+	style.Margin = *f.margin
+	c, err := pdfmark.ParseColor(*f.color)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -color: %w", err)
+	}
+	style.Color = c
+	pos, err := pdfmark.ParsePosition(*f.position)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -position: %w", err)
+	}
+	style.Position = pos
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "rotation" {
+			style.Diagonal = pdfmark.NoDiagonal
+			style.Rotation = *f.rotation
+		}
+	})
+
+	opts := []pdfmark.Option{
+		pdfmark.WithStyle(style),
+		pdfmark.WithWarningHandler(func(err error) { log.Printf("warning: %v", err) }),
+		pdfmark.WithTemplateVars(f.vars),
+		pdfmark.WithFilename(filepath.Base(pdfPath)),
+	}
+	if *f.strict {
+		opts = append(opts, pdfmark.WithStrictColumns())
+	}
+	if *f.layer {
+		opts = append(opts, pdfmark.WithLayering())
+	}
+	if *f.replace {
+		opts = append(opts, pdfmark.WithReplace())
+	}
+	policy, err := pdfmark.ParsePagePolicy(*f.policy)
+	if err != nil {
+		return nil, fmt.Errorf("parsing -page-policy: %w", err)
+	}
+	opts = append(opts, pdfmark.WithPagePolicy(policy))
+	format := pdfmark.Format(*f.format)
+	if format == "auto" {
+		format = formatFromExt(csvPath)
+	}
+	if _, err := pdfmark.DecoderFor(format); err != nil {
+		return nil, fmt.Errorf("parsing -format: %w", err)
+	}
+	opts = append(opts, pdfmark.WithFormat(format))
+	dialect, err := f.dialect()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, pdfmark.WithCSVDialect(dialect))
+	if *f.maxSize > 0 {
+		opts = append(opts, pdfmark.WithMaxInputSize(*f.maxSize))
+	}
+	if *f.password != "" || *f.ownerPassword != "" {
+		opts = append(opts, pdfmark.WithPasswords(*f.password, *f.ownerPassword))
+	}
+	if *f.forensicMark != "" {
+		key, err := readForensicKey(*f.forensicKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, pdfmark.WithForensicMark(*f.forensicMark), pdfmark.WithForensicKey(key))
+	}
+	if enc, ok := f.encryption(); ok {
+		opts = append(opts, pdfmark.WithEncryption(enc))
+	}
+	dir := *f.imagesDir
+	if dir == "" {
+		dir = filepath.Dir(csvPath)
+	}
+	assets := os.DirFS(dir)
+	opts = append(opts, pdfmark.WithImageFS(assets), pdfmark.WithPDFStampFS(assets))
+	return opts, nil
+}
+
+// dialect builds the CSV dialect from the flags.
+func (f *watermarkFlags) dialect() (pdfmark.CSVDialect, error) {
+	d := pdfmark.CSVDialect{Headers: f.headers, NoHeader: *f.noHeader, Sheet: *f.sheet}
+	var err error
+	if d.Delimiter, err = parseRune("-delimiter", *f.delimiter); err != nil {
+		return d, err
+	}
+	if d.Comment, err = parseRune("-comment", *f.comment); err != nil {
+		return d, err
+	}
+	if d.NoHeader {
+		d.Columns = strings.Split(*f.columns, ",")
+	}
+	if *f.charset != "" {
+		if d.Encoding, err = htmlindex.Get(*f.charset); err != nil {
+			return d, fmt.Errorf("parsing -charset: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// encryption builds the output encryption from the flags. It reports false
+// if no encryption flag was given.
+func (f *watermarkFlags) encryption() (pdfmark.Encryption, bool) {
+	set := false
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "encrypt-password", "encrypt-owner-password", "encrypt-key", "no-print", "no-copy", "no-modify":
+			set = true
+		}
+	})
+	perms := pdfmark.PermitAll
+	if *f.noPrint {
+		perms &^= pdfmark.PermitPrint
+	}
+	if *f.noCopy {
+		perms &^= pdfmark.PermitCopy
+	}
+	if *f.noModify {
+		perms &^= pdfmark.PermitModify | pdfmark.PermitAnnotate | pdfmark.PermitFillForms | pdfmark.PermitAssemble
+	}
+	return pdfmark.Encryption{
+		UserPassword:  *f.encryptUser,
+		OwnerPassword: *f.encryptOwner,
+		KeyLength:     *f.encryptKey,
+		Permissions:   perms,
+	}, set
+}
+
+// formatFromExt picks the instruction format from the extension of path,
+// leaving other files to content detection.
+func formatFromExt(path string) pdfmark.Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv", ".txt":
+		return pdfmark.FormatCSV
+	case ".xlsx", ".xlsm":
+		return pdfmark.FormatXLSX
+	case ".json", ".ndjson", ".jsonl":
+		return pdfmark.FormatJSON
+	case ".yaml", ".yml":
+		return pdfmark.FormatYAML
+	}
+	return pdfmark.FormatAuto
+}
+
+// parseRune parses a single-character flag value; "tab" stands for a tab.
+func parseRune(name, s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 {
+		return 0, fmt.Errorf("parsing %s: want a single character, got %q", name, s)
+	}
+	return r[0], nil
+}
+
+// nameValues collects repeated name=value flags such as -var.
+type nameValues map[string]string
+
+func (v nameValues) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v nameValues) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// readForensicKey reads the forensic mark key from the file at path,
+// ignoring surrounding white space so that keys written by "openssl rand
+// -hex 32" work. An empty path gives no key.
+func readForensicKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading -forensic-key-file: %w", err)
+	}
+	return bytes.TrimSpace(b), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// runInspect implements "pdfmark inspect", listing the watermarks already
+// on each page of a PDF.
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("pdfmark inspect", flag.ExitOnError)
+	password := fs.String("password", "", "user password of an encrypted PDF")
+	ownerPassword := fs.String("owner-password", "", "owner password of an encrypted PDF")
+	keyFile := fs.String("forensic-key-file", "", "file holding the key forensic marks were sealed under (default: marks are not shown)")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: pdfmark inspect [-password pw] [-forensic-key-file key] [-json] input.pdf")
+		os.Exit(1)
+	}
+	key, err := readForensicKey(*keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("opening PDF: %v", err)
+	}
+	defer f.Close()
+
+	report, err := pdfmark.Inspect(f, pdfmark.WithPasswords(*password, *ownerPassword), pdfmark.WithForensicKey(key))
+	if err != nil {
+		log.Fatalf("inspect failed: %v", err)
+	}
+
+	out := newInspectOutput(report)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(out); err != nil {
+			log.Fatalf("writing JSON: %v", err)
+		}
+		return
+	}
+	out.print(os.Stdout, filepath.Base(fs.Arg(0)))
+}
+
+// inspectOutput is the result of "pdfmark inspect", in the shape printed
+// with -json.
+type inspectOutput struct {
+	Version     string        `json:"pdf_version"`
+	Encrypted   bool          `json:"encrypted"`
+	Watermarked bool          `json:"watermarked"`
+	TotalPages  int           `json:"total_pages"`
+	Pages       []inspectPage `json:"pages"`
+}
+
+type inspectPage struct {
+	Page         int                `json:"page"`
+	Width        float64            `json:"width"`
+	Height       float64            `json:"height"`
+	Watermarks   []inspectWatermark `json:"watermarks"`
+	ForensicMark string             `json:"forensic_mark,omitempty"`
+}
+
+type inspectWatermark struct {
+	Kind     string  `json:"kind"`
+	Text     string  `json:"text,omitempty"`
+	Font     string  `json:"font,omitempty"`
+	FontSize float64 `json:"font_size,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	Opacity  float64 `json:"opacity"`
+	Rotation float64 `json:"rotation"`
+	OnTop    bool    `json:"on_top"`
+}
+
+func newInspectOutput(r *pdfmark.Report) inspectOutput {
+	out := inspectOutput{
+		Version:     r.Version,
+		Encrypted:   r.Encrypted,
+		Watermarked: r.Watermarked,
+		TotalPages:  len(r.Pages),
+		Pages:       make([]inspectPage, len(r.Pages)),
+	}
+	for i, p := range r.Pages {
+		page := inspectPage{
+			Page:         p.Page,
+			Width:        p.Width,
+			Height:       p.Height,
+			Watermarks:   []inspectWatermark{},
+			ForensicMark: p.ForensicMark,
+		}
+		for _, w := range p.Watermarks {
+			iw := inspectWatermark{
+				Kind:     string(w.Kind),
+				Text:     w.Text,
+				Opacity:  w.Opacity,
+				Rotation: w.Rotation,
+				OnTop:    w.OnTop,
+			}
+			if w.Kind == pdfmark.TextWatermark {
+				iw.Font = w.Font
+				iw.FontSize = w.FontSize
+				iw.Color = w.Color.String()
+			}
+			page.Watermarks = append(page.Watermarks, iw)
+		}
+		out.Pages[i] = page
+	}
+	return out
+}
+
+// print writes out in human-readable form; name identifies the PDF.
+func (out inspectOutput) print(w io.Writer, name string) {
+	marked := 0
+	for _, p := range out.Pages {
+		if len(p.Watermarks) > 0 {
+			marked++
+		}
+	}
+	fmt.Fprintf(w, "%s: PDF %s, %d pages, %d watermarked\n", name, out.Version, out.TotalPages, marked)
+	for _, p := range out.Pages {
+		if len(p.Watermarks) == 0 {
+			fmt.Fprintf(w, "  page %d: no watermark\n", p.Page)
+		}
+		for _, wm := range p.Watermarks {
+			fmt.Fprintf(w, "  page %d: %s\n", p.Page, wm)
+		}
+		if p.ForensicMark != "" {
+			fmt.Fprintf(w, "  page %d: forensic mark %q\n", p.Page, p.ForensicMark)
+		}
+	}
+}
+
+func (wm inspectWatermark) String() string {
+	var s string
+	switch wm.Kind {
+	case string(pdfmark.TextWatermark):
+		s = fmt.Sprintf("%q, %s %.4gpt %s", wm.Text, wm.Font, wm.FontSize, wm.Color)
+	default:
+		s = wm.Kind
+	}
+	s += fmt.Sprintf(", opacity %.2g, rotation %.4g", wm.Opacity, wm.Rotation)
+	if !wm.OnTop {
+		s += ", underlay"
+	}
+	return s
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/anujkumar-df/pdfmark"
@@ -16,11 +18,43 @@ import (
 )
 
 func main() {
-	pdfPath := flag.String("pdf", "", "path to input PDF")
-	csvPath := flag.String("csv", "", "path to CSV watermark file")
-	outPath := flag.String("out", "output.pdf", "path to output PDF")
-	demo := flag.Bool("demo", false, "run a self-contained demo (ignores -pdf and -csv)")
-	flag.Parse()
+	// Interrupting cancels the run between stages.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge":
+			runMerge(ctx, os.Args[2:])
+			return
+		case "check":
+			runCheck(ctx, os.Args[2:])
+			return
+		case "serve":
+			runServe(ctx, os.Args[2:])
+			return
+		case "trace":
+			runTrace(os.Args[2:])
+			return
+		case "inspect":
+			runInspect(os.Args[2:])
+			return
+		case "remove":
+			runRemove(os.Args[2:])
+			return
+		}
+	}
+	runWatermark(ctx, os.Args[1:])
+}
+
+func runWatermark(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("pdfmark", flag.ExitOnError)
+	pdfPath := fs.String("pdf", "", "path to input PDF")
+	csvPath := fs.String("csv", "", "path to watermark instructions (CSV, XLSX, JSON or YAML)")
+	outPath := fs.String("out", "output.pdf", "path to output PDF")
+	demo := fs.Bool("demo", false, "run a self-contained demo (ignores -pdf and -csv)")
+	wf := addWatermarkFlags(fs)
+	fs.Parse(args)
 
 	if *demo || (*pdfPath == "" && *csvPath == "") {
 		runDemo(*outPath)
@@ -28,11 +62,22 @@ func main() {
 	}
 
 	if *pdfPath == "" || *csvPath == "" {
-		fmt.Fprintln(os.Stderr, "usage: pdfmark -pdf input.pdf -csv watermarks.csv [-out output.pdf]")
+		fmt.Fprintln(os.Stderr, "usage: pdfmark -pdf input.pdf -csv watermarks.csv [-out output.pdf] [style flags]")
+		fmt.Fprintln(os.Stderr, "       pdfmark check -pdf input.pdf -csv watermarks.csv [-json]")
+		fmt.Fprintln(os.Stderr, "       pdfmark serve [-addr :8080]")
+		fmt.Fprintln(os.Stderr, "       pdfmark inspect input.pdf [-json]")
+		fmt.Fprintln(os.Stderr, "       pdfmark remove [-pages 1-3] [-out output.pdf] input.pdf")
+		fmt.Fprintln(os.Stderr, "       pdfmark trace leaked.pdf")
+		fmt.Fprintln(os.Stderr, "       pdfmark merge -pdf input.pdf -csv watermarks.csv -recipients people.csv -key column [-out dir | -zip out.zip]")
 		fmt.Fprintln(os.Stderr, "       pdfmark -demo [-out output.pdf]")
 		os.Exit(1)
 	}
 
+	opts, err := wf.options(*pdfPath, *csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	pdfFile, err := os.Open(*pdfPath)
 	if err != nil {
 		log.Fatalf("opening PDF: %v", err)
@@ -51,7 +96,7 @@ func main() {
 	}
 	defer outFile.Close()
 
-	if err := pdfmark.Watermark(outFile, pdfFile, csvFile); err != nil {
+	if err := pdfmark.WatermarkWithOptions(ctx, outFile, pdfFile, csvFile, opts...); err != nil {
 		log.Fatalf("watermarking failed: %v", err)
 	}
 
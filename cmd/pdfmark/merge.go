@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// runMerge implements "pdfmark merge", writing one watermarked copy of the
+// PDF per row of a recipients CSV.
+func runMerge(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("pdfmark merge", flag.ExitOnError)
+	pdfPath := fs.String("pdf", "", "path to input PDF")
+	csvPath := fs.String("csv", "", "path to watermark instructions (CSV, XLSX, JSON or YAML)")
+	recipientsPath := fs.String("recipients", "", "path to recipients CSV; its columns are template variables")
+	key := fs.String("key", "", "recipients column identifying each row")
+	outDir := fs.String("out", "merged", "directory for the output PDFs")
+	zipPath := fs.String("zip", "", "write the output PDFs into this zip file instead of -out")
+	name := fs.String("name", "", "output file name template, e.g. \"review-{{ticket}}.pdf\" (default: <key>.pdf)")
+	wf := addWatermarkFlags(fs)
+	fs.Parse(args)
+
+	if *pdfPath == "" || *csvPath == "" || *recipientsPath == "" || *key == "" {
+		fmt.Fprintln(os.Stderr, "usage: pdfmark merge -pdf input.pdf -csv watermarks.csv -recipients people.csv -key column [-out dir | -zip out.zip] [-name pattern] [style flags]")
+		os.Exit(1)
+	}
+
+	opts, err := wf.options(*pdfPath, *csvPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *name != "" {
+		opts = append(opts, pdfmark.WithOutputName(*name))
+	}
+
+	recipients, err := readRecipients(*recipientsPath, *key)
+	if err != nil {
+		log.Fatalf("reading recipients: %v", err)
+	}
+
+	pdfFile, err := os.Open(*pdfPath)
+	if err != nil {
+		log.Fatalf("opening PDF: %v", err)
+	}
+	defer pdfFile.Close()
+
+	csvFile, err := os.Open(*csvPath)
+	if err != nil {
+		log.Fatalf("opening CSV: %v", err)
+	}
+	defer csvFile.Close()
+
+	var out pdfmark.MergeOutput = pdfmark.DirOutput(*outDir)
+	var zo *pdfmark.ZipOutput
+	if *zipPath != "" {
+		zipFile, err := os.Create(*zipPath)
+		if err != nil {
+			log.Fatalf("creating zip: %v", err)
+		}
+		defer zipFile.Close()
+		zo = pdfmark.NewZipOutput(zipFile)
+		out = zo
+	}
+
+	if err := pdfmark.Merge(ctx, out, pdfFile, csvFile, recipients, opts...); err != nil {
+		log.Fatalf("merge failed: %v", err)
+	}
+
+	dest := *outDir
+	if zo != nil {
+		if err := zo.Close(); err != nil {
+			log.Fatalf("writing zip: %v", err)
+		}
+		dest = *zipPath
+	}
+	fmt.Printf("Done. %d watermarked PDFs written to %s\n", len(recipients), dest)
+}
+
+// readRecipients parses the recipients CSV at path.
+func readRecipients(path, key string) ([]pdfmark.Recipient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return pdfmark.ParseRecipients(f, key)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/anujkumar-df/pdfmark/internal/grpcserver"
+	"github.com/anujkumar-df/pdfmark/internal/httpserver"
+	"github.com/anujkumar-df/pdfmark/pdfmarkpb"
+)
+
+// runServe implements "pdfmark serve", answering watermarking requests over
+// HTTP, and over gRPC with -grpc-addr, until interrupted. On SIGINT or
+// SIGTERM it fails the readiness check, waits -drain for load balancers to
+// notice, then finishes the requests in flight before exiting.
+func runServe(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("pdfmark serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	grpcAddr := fs.String("grpc-addr", "", "address to serve the gRPC API on (default: no gRPC)")
+	maxRequest := fs.Int64("max-request-size", 64<<20, "largest upload accepted, in bytes")
+	maxConcurrent := fs.Int("max-concurrent", 0, "uploads processed at once (default: number of CPUs)")
+	timeout := fs.Duration("timeout", time.Minute, "time limit per request, including waiting for a slot")
+	drain := fs.Duration("drain", 5*time.Second, "time between failing /readyz and shutting down")
+	keyFile := fs.String("forensic-key-file", "", "file holding the secret key sealing forensic marks (default: forensic marks are refused)")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: pdfmark serve [-addr :8080] [-grpc-addr :9090] [-max-request-size bytes] [-max-concurrent n] [-timeout 1m] [-drain 5s] [-forensic-key-file key]")
+		os.Exit(1)
+	}
+	key, err := readForensicKey(*keyFile)
+	if err != nil {
+		log.Fatalf("serve failed: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	defer stop()
+
+	handler := httpserver.New(httpserver.Config{
+		MaxRequestBytes: *maxRequest,
+		MaxConcurrent:   *maxConcurrent,
+		Timeout:         *timeout,
+		ForensicKey:     key,
+	})
+	// There is no ReadTimeout: the handler bounds reading an upload by the
+	// request timeout once a slot is free.
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      *timeout + 10*time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	var grpcSrv *grpc.Server
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("serve failed: %v", err)
+		}
+		grpcSrv = grpc.NewServer(grpc.MaxRecvMsgSize(int(*maxRequest)))
+		pdfmarkpb.RegisterWatermarkServiceServer(grpcSrv, grpcserver.New(grpcserver.Config{
+			MaxInputBytes: *maxRequest,
+			MaxConcurrent: *maxConcurrent,
+			Timeout:       *timeout,
+			ForensicKey:   key,
+		}))
+		go func() {
+			log.Printf("serving gRPC on %s", *grpcAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("gRPC serve failed: %v", err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		log.Printf("draining")
+		handler.Drain()
+		time.Sleep(*drain)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if grpcSrv != nil {
+			go func() {
+				<-shutdownCtx.Done()
+				grpcSrv.Stop()
+			}()
+			defer grpcSrv.GracefulStop()
+		}
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutting down: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("serve failed: %v", err)
+	}
+	<-done
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// runTrace implements "pdfmark trace", printing the forensic mark of a
+// leaked PDF. It exits with status 2 if the PDF carries no mark.
+func runTrace(args []string) {
+	fs := flag.NewFlagSet("pdfmark trace", flag.ExitOnError)
+	password := fs.String("password", "", "user password of an encrypted PDF")
+	ownerPassword := fs.String("owner-password", "", "owner password of an encrypted PDF")
+	keyFile := fs.String("forensic-key-file", "", "file holding the key the mark was sealed under")
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: pdfmark trace -forensic-key-file key [-password pw] [-json] leaked.pdf")
+		os.Exit(1)
+	}
+	key, err := readForensicKey(*keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("opening PDF: %v", err)
+	}
+	defer f.Close()
+
+	r, err := pdfmark.Trace(f, pdfmark.WithPasswords(*password, *ownerPassword), pdfmark.WithForensicKey(key))
+	if errors.Is(err, pdfmark.ErrNoForensicMark) {
+		fmt.Fprintf(os.Stderr, "%s: no forensic mark found\n", fs.Arg(0))
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("trace failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(struct {
+			ID       string   `json:"id"`
+			Pages    []int    `json:"pages,omitempty"`
+			Metadata bool     `json:"metadata"`
+			Others   []string `json:"others,omitempty"`
+		}{r.ID, r.Pages, r.Metadata, r.Others})
+		if err != nil {
+			log.Fatalf("writing JSON: %v", err)
+		}
+		return
+	}
+	fmt.Println(r.ID)
+	if len(r.Pages) > 0 {
+		fmt.Fprintf(os.Stderr, "marked on %d page(s)", len(r.Pages))
+	} else {
+		fmt.Fprint(os.Stderr, "marked in the metadata only")
+	}
+	if r.Metadata && len(r.Pages) > 0 {
+		fmt.Fprint(os.Stderr, " and in the metadata")
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, id := range r.Others {
+		fmt.Fprintf(os.Stderr, "also found: %s\n", id)
+	}
+}
@@ -1,4 +1,4 @@
-// Package pdfmark applies text watermarks to PDF documents.
+// Package pdfmark applies text, image and PDF-page watermarks to PDF documents.
 //
 // It reads a PDF template from an io.Reader, watermark instructions from a CSV
 // io.Reader, and writes the watermarked PDF to an io.WriteCloser. The function
@@ -10,7 +10,173 @@
 //	1,CONFIDENTIAL
 //	3,DRAFT
 //
+// The page column accepts a page number or a selector: a range ("1-5",
+// "3-last"), "odd", "even", "last", "all", a negative number counting from
+// the end ("-1" is the last page), or a quoted comma-separated list of these
+// ("1,3,5-7"). Selectors are resolved once the page count is known, so an
+// explicit page past the end still fails with ErrPageOutOfRange.
+//
+// By default each page may be selected by only one row. With WithLayering,
+// several rows may target the same page and are stacked in CSV order, e.g. a
+// diagonal "CONFIDENTIAL" plus a small footer naming the recipient.
+//
+// WithPagePolicy relaxes both checks for CSVs shared by documents of
+// different lengths: SkipInvalid drops pages past the end and repeated
+// pages, and ClampToLastPage moves pages past the end onto the last page.
+// Each dropped or moved page is reported to the warning handler.
+//
+// Optional columns, matched by header name, override the style per row:
+// font, font_size, color, opacity, rotation, position, dx, dy, margin and
+// render_mode. Empty cells inherit the base style:
+//
+//	page,watermark_text,font_size,color,position
+//	1,CONFIDENTIAL,,,
+//	2,Copy for Jane Doe,10,#000000,bottom-center
+//
+// The position "tiled" repeats the text in a grid over the whole page
+// instead, fitted to each page's crop box so mixed page sizes are covered
+// alike; Style.Tile sets the gaps between tiles, staggered rows and the
+// margin, and the rotation applies to every tile:
+//
+//	page,watermark_text,position,font_size,rotation
+//	all,CONFIDENTIAL – {{recipient}},tiled,18,30
+//
+// The positions header, header-left, header-right, footer, footer-left and
+// footer-right place a horizontal line of text the margin column (default
+// Style.Margin, a quarter inch) inside the top or bottom edge of the crop
+// box; left-margin and right-margin run it along the side edges. They follow
+// the page as viewed, rotation included, draw the text at its font size
+// rather than scaled to the page, and dx and dy still shift them:
+//
+//	page,watermark_text,position,font_size
+//	all,Company Confidential – Page {{page}} of {{total_pages}},footer,9
+//	all,{{filename}},left-margin,8
+//
+// A row may set the image column instead of watermark_text to stamp a PNG or
+// JPEG logo. References are looked up in the map given to WithImages, then as
+// paths in the file system given to WithImageFS. The scale column controls
+// its size relative to the page ("0.2") or absolutely ("1.5 abs"):
+//
+//	page,watermark_text,image,scale,position,rotation
+//	all,,logo.png,0.2,top-right,0
+//
+// Similarly, the pdf column stamps a page of another PDF, such as a
+// letterhead or form overlay, chosen by pdf_page (default 1). Stamp PDFs are
+// supplied through WithPDFStamps or WithPDFStampFS. Like images, they follow
+// the style's diagonal unless the row sets a rotation:
+//
+//	page,watermark_text,pdf,pdf_page,opacity,rotation
+//	1,,letterhead.pdf,1,1,0
+//	2-last,,letterhead.pdf,2,1,0
+//
+// Watermark text is a template rendered for every page it is stamped on.
+// The placeholders {{page}}, {{total_pages}}, {{date}} (optionally with a
+// Go time layout, as in {{date "02 Jan 2006"}}) and {{filename}} are
+// provided, along with the upper and lower functions, as in
+// {{upper recipient}}, and any variables given to WithTemplateVars. Other
+// text/template actions, functions and pipelines are rejected, and the
+// rendered text is limited to 4 KiB:
+//
+//	page,watermark_text
+//	all,"Copy for {{recipient}} – page {{page}}/{{total_pages}}"
+//
+// Instructions may also be JSON, either an array of objects or one object
+// per line, or YAML, a sequence of mappings. Keys are the CSV column names
+// and the format is detected from the content:
+//
+//	[{"page": "all", "watermark_text": "DRAFT", "opacity": 0.2},
+//	 {"page": [1, 3], "image": "logo.png", "scale": "0.2"}]
+//
+//	- page: all
+//	  watermark_text: DRAFT
+//
+// Excel workbooks (.xlsx) are read like CSV, from the first worksheet
+// unless CSVDialect.Sheet names another; title rows above the header row
+// are skipped.
+//
+// WithFormat forces a format, and WithDecoder plugs in a custom Decoder,
+// which may build its instructions with DecodeRecords.
+//
+// CSV is comma-separated UTF-8 by default, but the delimiter is detected
+// from the header line and a UTF-8 or UTF-16 byte order mark is honoured.
+// WithCSVDialect handles other exports, such as semicolon-separated
+// Windows-1252 files with localized column names, files without a header
+// row and files with comment lines.
+//
+// Problems with an instruction are reported as *InstructionError, which
+// records the line, column, page and raw value involved and matches the
+// sentinel errors with errors.Is. Problems found in one pass are joined;
+// Problems lists them, and ValidateInstructions checks every instruction
+// rather than stopping at the first invalid one:
+//
+//	report, err := pdfmark.ValidateInstructions(ctx, csvFile)
+//	for _, p := range report.Problems {
+//		fmt.Printf("line %d: %v\n", p.Line, p)
+//	}
+//
+// Plan goes further and checks the instructions against the PDF, reporting
+// what would be stamped on each page without writing any output; the
+// "pdfmark check" command prints the plan for use in CI. "pdfmark serve"
+// offers watermarking to other services over HTTP, answering invalid
+// uploads with a 4xx status and a JSON body naming the sentinel error.
+// With -grpc-addr it also serves the gRPC API defined in package pdfmarkpb.
+//
 // Usage:
 //
 //	err := pdfmark.Watermark(dst, pdfReader, csvReader)
+//
+// WatermarkWithOptions accepts a context and Options such as WithStyle to
+// change the font, color, opacity, rotation and placement of the watermarks:
+//
+//	style := pdfmark.DefaultStyle()
+//	style.Color = pdfmark.Color{R: 1}
+//	style.Position = pdfmark.TopCenter
+//	err := pdfmark.WatermarkWithOptions(ctx, dst, pdfReader, csvReader, pdfmark.WithStyle(style))
+//
+// Merge writes one copy per recipient, reading the PDF and instructions only
+// once. Each recipient's columns become template variables:
+//
+//	recipients, err := pdfmark.ParseRecipients(peopleCSV, "email")
+//	out := pdfmark.NewZipOutput(zipFile)
+//	err = pdfmark.Merge(ctx, out, pdfReader, csvReader, recipients,
+//		pdfmark.WithOutputName("review-{{ticket}}.pdf"))
+//	err = out.Close()
+//
+// Encrypted input PDFs are opened with WithPasswords; without the right
+// password they fail with ErrEncryptedPDF, which like ErrTruncatedPDF and
+// ErrUnsupportedPDFVersion also matches ErrInvalidPDF. WithEncryption locks
+// the output in the same pass, so a confidential copy can be stamped and
+// restricted to printing at once:
+//
+//	err := pdfmark.WatermarkWithOptions(ctx, dst, pdfReader, csvReader,
+//		pdfmark.WithPasswords("", "source-owner"),
+//		pdfmark.WithEncryption(pdfmark.Encryption{
+//			UserPassword: "reader",
+//			Permissions:  pdfmark.PermitPrint,
+//		}))
+//
+// Visible watermarks are easily cropped out. WithForensicMark also hides a
+// recipient ID in each copy, as invisible text on every page and in the
+// XMP metadata, sealed under a secret key so that recipients can neither
+// read nor forge it. Trace recovers it from a leaked file with the same
+// key; "pdfmark trace" does the same from the command line. The mark is
+// meant for copies passed on carelessly: it does not survive printing or
+// a recipient who knows to strip invisible text and metadata.
+//
+//	err := pdfmark.Merge(ctx, out, pdfReader, csvReader, recipients,
+//		pdfmark.WithForensicMark("{{email}}"), pdfmark.WithForensicKey(key))
+//	report, err := pdfmark.Trace(leakedFile, pdfmark.WithForensicKey(key))
+//	fmt.Println("leaked by", report.ID)
+//
+// Inspect reports the watermarks already on each page of a PDF, stamped by
+// pdfmark or by pdfcpu, with their text and style as read back from the
+// page, and any forensic mark; "pdfmark inspect" prints the same report.
+//
+// Remove strips those watermarks from selected pages again, and
+// WithReplace does so on the pages it stamps, so a contract can go from
+// DRAFT to FINAL without keeping the unstamped original:
+//
+//	pages, err := pdfmark.ParsePages("all")
+//	err = pdfmark.Remove(dst, draftPDF, pages)
+//	err = pdfmark.WatermarkWithOptions(ctx, dst, draftPDF, finalCSV, pdfmark.WithReplace())
 package pdfmark
@@ -4,10 +4,46 @@ import "github.com/anujkumar-df/pdfmark/internal/errs"
 
 // Sentinel errors returned by Watermark.
 var (
-	ErrPageOutOfRange = errs.ErrPageOutOfRange
-	ErrInvalidPage    = errs.ErrInvalidPage
-	ErrDuplicatePage  = errs.ErrDuplicatePage
-	ErrMalformedCSV   = errs.ErrMalformedCSV
-	ErrInvalidPDF     = errs.ErrInvalidPDF
-	ErrEmptyCSV       = errs.ErrEmptyCSV
+	ErrPageOutOfRange     = errs.ErrPageOutOfRange
+	ErrInvalidPage        = errs.ErrInvalidPage
+	ErrDuplicatePage      = errs.ErrDuplicatePage
+	ErrMalformedCSV       = errs.ErrMalformedCSV
+	ErrInvalidPDF         = errs.ErrInvalidPDF
+	ErrEmptyCSV           = errs.ErrEmptyCSV
+	ErrInvalidStyle       = errs.ErrInvalidStyle
+	ErrUnknownColumn      = errs.ErrUnknownColumn
+	ErrAssetNotFound      = errs.ErrAssetNotFound
+	ErrInvalidImage       = errs.ErrInvalidImage
+	ErrInvalidTemplate    = errs.ErrInvalidTemplate
+	ErrInvalidOutputName  = errs.ErrInvalidOutputName
+	ErrInputTooLarge      = errs.ErrInputTooLarge
+	ErrUnknownFormat      = errs.ErrUnknownFormat
+	ErrInvalidEncryption  = errs.ErrInvalidEncryption
+	ErrNoForensicMark     = errs.ErrNoForensicMark
+	ErrInvalidForensicKey = errs.ErrInvalidForensicKey
+	ErrNoWatermark        = errs.ErrNoWatermark
 )
+
+// Specific reasons a PDF cannot be read, so callers can ask for a password
+// or reject an upload. Each wraps ErrInvalidPDF, and the underlying pdfcpu
+// error is kept in the chain.
+var (
+	ErrEncryptedPDF          = errs.ErrEncryptedPDF
+	ErrUnsupportedPDFVersion = errs.ErrUnsupportedPDFVersion
+	ErrTruncatedPDF          = errs.ErrTruncatedPDF
+)
+
+// InstructionError describes a problem with one instruction: its source
+// line, the column or field involved, the page and the raw value, where
+// known. It wraps the sentinel error, so errors.Is(err, ErrInvalidPage) and
+// the like keep working; use errors.As to get at the location.
+//
+// Several problems found in one pass are combined with errors.Join; use
+// Problems to list them.
+type InstructionError = errs.InstructionError
+
+// Problems returns every *InstructionError in err, including those joined
+// into it, in the order they were found.
+func Problems(err error) []*InstructionError {
+	return errs.Problems(err)
+}
@@ -2,7 +2,14 @@ module github.com/anujkumar-df/pdfmark
 
 go 1.25.0
 
-require github.com/pdfcpu/pdfcpu v0.11.1
+require (
+	github.com/pdfcpu/pdfcpu v0.11.1
+	golang.org/x/text v0.30.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b
+	google.golang.org/grpc v1.76.0
+	google.golang.org/protobuf v1.36.10
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
@@ -13,6 +20,7 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/crypto v0.43.0 // indirect
 	golang.org/x/image v0.32.0 // indirect
-	golang.org/x/text v0.30.0 // indirect
+	golang.org/x/net v0.45.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
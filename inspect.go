@@ -0,0 +1,83 @@
+package pdfmark
+
+import (
+	"io"
+
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+)
+
+// Report describes a PDF and the watermarks already on it. It is returned
+// by Inspect.
+type Report struct {
+	// Version is the PDF version, such as "1.7".
+	Version string
+	// Encrypted reports whether the PDF is encrypted.
+	Encrypted bool
+	// Watermarked reports whether any page carries a watermark.
+	Watermarked bool
+	// Pages describes every page, in page order.
+	Pages []PageReport
+}
+
+// PageReport describes one page of an inspected PDF.
+type PageReport struct {
+	Page          int
+	Width, Height float64 // in points
+	// Watermarks lists the pdfmark and pdfcpu watermarks found on the
+	// page, in drawing order.
+	Watermarks []DetectedWatermark
+	// ForensicMark is the ID hidden on the page by WithForensicMark, or ""
+	// if there is none or it was not sealed under the WithForensicKey key.
+	ForensicMark string
+}
+
+// DetectedWatermark is a watermark found by Inspect, with the style read
+// back from the drawing. FontSize is the size after scaling to the page,
+// which differs from Style.FontSize unless ScaleAbsolute was used. Text
+// is empty when the font draws glyph IDs rather than characters.
+type DetectedWatermark = stamp.Detected
+
+// WatermarkKind is what a detected watermark draws.
+type WatermarkKind = stamp.Kind
+
+// Kinds of detected watermarks.
+const (
+	TextWatermark  = stamp.KindText
+	ImageWatermark = stamp.KindImage
+	PDFWatermark   = stamp.KindPDF
+)
+
+// Inspect reports the watermarks already on each page of the PDF in src,
+// for example to avoid stamping a document twice. It recognizes the
+// watermarks added by pdfmark and by pdfcpu. WithPasswords and
+// WithMaxInputSize apply, and forensic marks are reported if they were
+// sealed under the key set with WithForensicKey; other options are ignored.
+func Inspect(src io.Reader, opts ...Option) (*Report, error) {
+	cfg := newConfig(opts)
+	rs, cleanup, err := stamp.OpenInput(src, cfg.maxInputSize)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	info, err := stamp.ReadInfo(rs, cfg.passwords, cfg.forensicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{
+		Version:     info.Version,
+		Encrypted:   info.Encrypted,
+		Watermarked: info.Watermarked,
+		Pages:       make([]PageReport, len(info.Pages)),
+	}
+	for i, p := range info.Pages {
+		r.Pages[i] = PageReport{
+			Page:         i + 1,
+			Width:        p.Width,
+			Height:       p.Height,
+			Watermarks:   p.Watermarks,
+			ForensicMark: p.Mark,
+		}
+	}
+	return r, nil
+}
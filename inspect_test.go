@@ -0,0 +1,101 @@
+package pdfmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+func TestInspect(t *testing.T) {
+	style := DefaultStyle()
+	style.Color = Color{R: 1}
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 3)),
+		csvString("page,watermark_text,image", "1,Copy for Jane – 1/3,", "3,,logo"),
+		WithStyle(style), WithImages(map[string][]byte{"logo": createTestPNG(t, 10, 10)}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+
+	r, err := Inspect(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if !r.Watermarked || r.Encrypted || len(r.Pages) != 3 {
+		t.Fatalf("report = %+v", r)
+	}
+	if w := r.Pages[0].Watermarks; len(w) != 1 || w[0].Kind != TextWatermark || w[0].Text != "Copy for Jane – 1/3" ||
+		w[0].Color != style.Color || w[0].Opacity != style.Opacity {
+		t.Errorf("page 1 watermarks = %+v", w)
+	}
+	if w := r.Pages[1].Watermarks; len(w) != 0 {
+		t.Errorf("page 2 watermarks = %+v, want none", w)
+	}
+	if w := r.Pages[2].Watermarks; len(w) != 1 || w[0].Kind != ImageWatermark {
+		t.Errorf("page 3 watermarks = %+v", w)
+	}
+	if p := r.Pages[2]; p.Page != 3 || p.Width <= 0 || p.Height <= 0 {
+		t.Errorf("page 3 = %+v", p)
+	}
+}
+
+func TestInspect_NotWatermarked(t *testing.T) {
+	r, err := Inspect(bytes.NewReader(createTestPDF(t, 2)))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if r.Watermarked || len(r.Pages) != 2 || len(r.Pages[0].Watermarks) != 0 {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestInspect_BlankPage(t *testing.T) {
+	var pdf bytes.Buffer
+	if err := api.InsertPages(bytes.NewReader(createTestPDF(t, 2)), &pdf, []string{"2"}, true, nil, nil); err != nil {
+		t.Fatalf("InsertPages: %v", err)
+	}
+	r, err := Inspect(bytes.NewReader(pdf.Bytes()))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if r.Watermarked || len(r.Pages) != 3 {
+		t.Errorf("report = %+v", r)
+	}
+
+	// Stamping the blank page and removing the stamp again leaves it
+	// without content.
+	var stamped, removed bytes.Buffer
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&stamped}, bytes.NewReader(pdf.Bytes()),
+		csvString("page,watermark_text", "2,DRAFT"))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	if err := Remove(nopWriteCloser{&removed}, bytes.NewReader(stamped.Bytes()), Pages{}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if r, err = Inspect(bytes.NewReader(removed.Bytes())); err != nil {
+		t.Fatalf("Inspect after Remove: %v", err)
+	}
+	if r.Watermarked {
+		t.Errorf("report after Remove = %+v, want no watermarks", r)
+	}
+}
+
+func TestInspect_Encrypted(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 1), "user", "owner")
+	if _, err := Inspect(bytes.NewReader(pdf)); !errors.Is(err, ErrEncryptedPDF) {
+		t.Errorf("without password: got error %v, want ErrEncryptedPDF", err)
+	}
+	r, err := Inspect(bytes.NewReader(pdf), WithPasswords("user", ""))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if !r.Encrypted {
+		t.Error("Encrypted = false")
+	}
+}
@@ -0,0 +1,143 @@
+package pdfmark
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/anujkumar-df/pdfmark/internal/csvparse"
+	"github.com/anujkumar-df/pdfmark/internal/decode"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// Instruction is one watermark directive, independent of the format it was
+// read from. It sets exactly one of Text, Image and PDF.
+type Instruction = spec.Instruction
+
+// Pages is a parsed page selector such as "3", "1-5" or "odd".
+type Pages = spec.Pages
+
+// StyleOverride holds the style fields an Instruction changes; nil fields
+// inherit the base style.
+type StyleOverride = spec.StyleOverride
+
+// ParsePages parses a page selector: a page number, a range such as "1-5"
+// or "3-last", "odd", "even", "last", "all", a negative number counting
+// from the end, or a comma-separated list of these.
+func ParsePages(s string) (Pages, error) {
+	return spec.ParsePages(s)
+}
+
+// Format names an instruction format.
+type Format = decode.Format
+
+// Instruction formats. FormatAuto, the default, detects the format from
+// the content: XLSX is a ZIP archive, JSON starts with '[' or '{', YAML
+// with "---", "- " or a "key:" line, and anything else is read as CSV.
+const (
+	FormatAuto = decode.FormatAuto
+	FormatCSV  = decode.FormatCSV
+	FormatJSON = decode.FormatJSON
+	FormatYAML = decode.FormatYAML
+	FormatXLSX = decode.FormatXLSX
+)
+
+// CSVDialect describes how a CSV file is written: its delimiter, comment
+// character, header names, whether it has a header at all, and its
+// character encoding. For XLSX input it selects the worksheet, and the
+// header names and headerless mode apply too. The zero value reads
+// comma-separated UTF-8 with a header row; a zero Delimiter is detected
+// from the first line, and a byte order mark is always honoured.
+type CSVDialect = csvparse.Dialect
+
+// DecodeOptions controls how a Decoder treats its input.
+type DecodeOptions = decode.Options
+
+// DecodeResult holds the instructions read by a Decoder and any non-fatal
+// warnings.
+type DecodeResult = decode.Result
+
+// Record is one instruction in format-neutral form, mapping the CSV column
+// names (page, watermark_text, image, font, ...) to raw values.
+type Record = decode.Record
+
+// Decoder reads watermark instructions in one input format. Use
+// WithDecoder to plug in a format pdfmark does not support.
+type Decoder interface {
+	Decode(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error)
+}
+
+// DecoderFunc adapts a function to the Decoder interface.
+type DecoderFunc func(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error)
+
+// Decode calls f.
+func (f DecoderFunc) Decode(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error) {
+	return f(ctx, r, opts)
+}
+
+// DecoderFor returns the built-in decoder for f. FormatAuto returns a
+// decoder that detects the format from the content. CSV and XLSX are read
+// in the default dialect; use WithCSVDialect to change it.
+func DecoderFor(f Format) (Decoder, error) {
+	return decoderFor(f, CSVDialect{})
+}
+
+// decoderFor is DecoderFor reading CSV in dialect d.
+func decoderFor(f Format, d CSVDialect) (Decoder, error) {
+	switch f {
+	case FormatAuto:
+		return DecoderFunc(func(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error) {
+			return decodeAuto(ctx, r, opts, d)
+		}), nil
+	case FormatCSV:
+		return DecoderFunc(func(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error) {
+			return csvparse.ParseDialect(ctx, r, opts, d)
+		}), nil
+	case FormatXLSX:
+		return DecoderFunc(func(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error) {
+			return csvparse.ParseXLSX(ctx, r, opts, d)
+		}), nil
+	case FormatJSON:
+		return DecoderFunc(decode.JSON), nil
+	case FormatYAML:
+		return DecoderFunc(decode.YAML), nil
+	}
+	return nil, fmt.Errorf("%w: %q", errs.ErrUnknownFormat, string(f))
+}
+
+// DecodeRecords turns records into instructions with the same checks as
+// the built-in decoders. Custom decoders can use it so that their input
+// follows the CSV field names and value syntax.
+func DecodeRecords(records []Record, opts DecodeOptions) (*DecodeResult, error) {
+	b := decode.NewBuilder(opts)
+	for _, rec := range records {
+		if err := b.Add(rec); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// sniffSize is how much input decodeAuto looks at to detect the format.
+const sniffSize = 4096
+
+func decodeAuto(ctx context.Context, r io.Reader, opts DecodeOptions, d CSVDialect) (*DecodeResult, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+	dec, err := decoderFor(decode.Detect(head), d)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decode(ctx, br, opts)
+}
+
+// instructionDecoder returns the decoder set with WithDecoder, or the
+// built-in one for the configured format and CSV dialect.
+func (c *config) instructionDecoder() (Decoder, error) {
+	if c.decoder != nil {
+		return c.decoder, nil
+	}
+	return decoderFor(c.format, c.dialect)
+}
@@ -0,0 +1,117 @@
+package pdfmark
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+func TestWatermarkWithOptions_Formats(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	tests := map[string]struct {
+		in   string
+		opts []Option
+	}{
+		"json array":     {in: `[{"page": 1, "watermark_text": "A"}, {"page": "2-3", "watermark_text": "B", "color": "red"}]`},
+		"ndjson":         {in: "{\"page\": 1, \"watermark_text\": \"A\"}\n{\"page\": 3, \"watermark_text\": \"B\"}\n"},
+		"yaml":           {in: "- page: 1\n  watermark_text: A\n- page: even\n  watermark_text: B\n"},
+		"commented yaml": {in: "# watermarks.yaml\n- page: 1\n  watermark_text: A\n"},
+		"forced csv":     {in: "page,watermark_text\n1,A\n", opts: []Option{WithFormat(FormatCSV)}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), strings.NewReader(tt.in), tt.opts...)
+			if err != nil {
+				t.Fatalf("WatermarkWithOptions: %v", err)
+			}
+			assertValidPDF(t, out.Bytes())
+			assertPageCount(t, out.Bytes(), 3)
+		})
+	}
+}
+
+func TestWatermarkWithOptions_WrongFormat(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf),
+		strings.NewReader("page,watermark_text\n1,A\n"), WithFormat(FormatJSON))
+	if !errors.Is(err, ErrMalformedCSV) {
+		t.Errorf("got error %v, want ErrMalformedCSV", err)
+	}
+}
+
+func TestDecoderFor_Unknown(t *testing.T) {
+	if _, err := DecoderFor("toml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got error %v, want ErrUnknownFormat", err)
+	}
+}
+
+// keyValueDecoder reads "page=text" lines, as an example of a custom format.
+func keyValueDecoder(ctx context.Context, r io.Reader, opts DecodeOptions) (*DecodeResult, error) {
+	var records []Record
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		page, text, _ := strings.Cut(sc.Text(), "=")
+		records = append(records, Record{
+			Fields: map[string]string{"page": page, "watermark_text": text},
+			Line:   line,
+		})
+	}
+	return DecodeRecords(records, opts)
+}
+
+func TestWatermarkWithOptions_CustomDecoder(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf),
+		strings.NewReader("1=DRAFT\n2=FINAL\n"), WithDecoder(DecoderFunc(keyValueDecoder)))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+
+	_, err = DecodeRecords([]Record{{Fields: map[string]string{"page": "0", "watermark_text": "A"}, Line: 1}}, DecodeOptions{})
+	if !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("got error %v, want ErrInvalidPage", err)
+	}
+}
+
+func TestWatermarkWithOptions_CSVDialect(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	in := "\ufeffSeite;Wasserzeichen\n1;VERTRAULICH\n2;ENTWURF\n"
+	var plan *StampPlan
+	for _, format := range []Format{FormatAuto, FormatCSV} {
+		var err error
+		plan, err = Plan(context.Background(), bytes.NewReader(pdf), strings.NewReader(in),
+			WithFormat(format),
+			WithCSVDialect(CSVDialect{Headers: map[string]string{"Seite": "page", "Wasserzeichen": "watermark_text"}}))
+		if err != nil {
+			t.Fatalf("Plan with format %q: %v", format, err)
+		}
+	}
+	if len(plan.Pages) != 2 || plan.Pages[1].Stamps[0].Text != "ENTWURF" {
+		t.Errorf("pages = %+v, want ENTWURF on page 2", plan.Pages)
+	}
+}
+
+func TestPlan_XLSX(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	xlsx := testutil.XLSX(t, testutil.Sheet{Name: "Plan", Rows: [][]string{
+		{"page", "watermark_text"},
+		{"odd", "CONFIDENTIAL"},
+	}})
+	plan, err := Plan(context.Background(), bytes.NewReader(pdf), bytes.NewReader(xlsx))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Pages) != 2 || plan.Pages[1].Page != 3 || plan.Pages[1].Stamps[0].Text != "CONFIDENTIAL" {
+		t.Errorf("pages = %+v, want pages 1 and 3", plan.Pages)
+	}
+}
@@ -0,0 +1,135 @@
+package csvparse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+func TestParseDialect_SemicolonAliases(t *testing.T) {
+	r := csvString(
+		"\ufeffSeite;Wasserzeichen;Farbe",
+		"1;VERTRAULICH;red",
+		"2;\"ENTWURF; INTERN\";",
+	)
+	res, err := ParseDialect(context.Background(), r, Options{}, Dialect{
+		Headers: map[string]string{"seite": "page", "WASSERZEICHEN": "watermark_text", "Farbe": "color"},
+	})
+	if err != nil {
+		t.Fatalf("ParseDialect: %v", err)
+	}
+	m := expand(t, res, 2)
+	if m[1].Text != "VERTRAULICH" || m[1].Style.Color == nil {
+		t.Errorf("page 1 = %+v, want VERTRAULICH in a color", m[1])
+	}
+	if m[2].Text != "ENTWURF; INTERN" {
+		t.Errorf("page 2 text = %q, want the quoted semicolon kept", m[2].Text)
+	}
+}
+
+func TestParseDialect_Windows1252(t *testing.T) {
+	raw, err := charmap.Windows1252.NewEncoder().String("page,watermark_text\n1,Geschäftsgeheimnis – intern\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ParseDialect(context.Background(), strings.NewReader(raw), Options{}, Dialect{Encoding: charmap.Windows1252})
+	if err != nil {
+		t.Fatalf("ParseDialect: %v", err)
+	}
+	if got := res.Instructions[0].Text; got != "Geschäftsgeheimnis – intern" {
+		t.Errorf("text = %q, want it decoded from Windows-1252", got)
+	}
+}
+
+func TestParseDialect_UTF16BOM(t *testing.T) {
+	enc := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	raw, err := enc.NewEncoder().String("page\twatermark_text\n1\tÜBERSICHT\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The byte order mark wins over the configured encoding.
+	res, err := ParseDialect(context.Background(), strings.NewReader(raw), Options{}, Dialect{Encoding: charmap.Windows1252})
+	if err != nil {
+		t.Fatalf("ParseDialect: %v", err)
+	}
+	if got := res.Instructions[0].Text; got != "ÜBERSICHT" {
+		t.Errorf("text = %q, want ÜBERSICHT", got)
+	}
+}
+
+func TestParseDialect_HeaderlessComments(t *testing.T) {
+	r := csvString(
+		"# exported 2024-05-01",
+		"1|red|CONFIDENTIAL",
+		"# page 2 left blank",
+		"3|blue|DRAFT",
+	)
+	res, err := ParseDialect(context.Background(), r, Options{}, Dialect{
+		Comment:  '#',
+		NoHeader: true,
+		Columns:  []string{"page", "color", "watermark_text"},
+	})
+	if err != nil {
+		t.Fatalf("ParseDialect: %v", err)
+	}
+	if len(res.Instructions) != 2 {
+		t.Fatalf("got %d instructions, want 2", len(res.Instructions))
+	}
+	if ins := res.Instructions[1]; ins.Text != "DRAFT" || ins.Line != 4 {
+		t.Errorf("second instruction = %q on line %d, want DRAFT on line 4", ins.Text, ins.Line)
+	}
+}
+
+func TestParseDialect_HeaderlessDefaultColumns(t *testing.T) {
+	res, err := ParseDialect(context.Background(), csvString("1,A", "2,B"), Options{}, Dialect{NoHeader: true})
+	if err != nil {
+		t.Fatalf("ParseDialect: %v", err)
+	}
+	if len(res.Instructions) != 2 || res.Instructions[0].Line != 1 {
+		t.Errorf("got %+v, want 2 instructions starting on line 1", res.Instructions)
+	}
+}
+
+func TestParseDialect_HeaderlessErrors(t *testing.T) {
+	tests := map[string]struct {
+		d    Dialect
+		in   string
+		want error
+	}{
+		"unknown column": {Dialect{NoHeader: true, Columns: []string{"page", "colour"}}, "1,red", errs.ErrUnknownColumn},
+		"no page":        {Dialect{NoHeader: true, Columns: []string{"watermark_text"}}, "A", errs.ErrMalformedCSV},
+		"empty":          {Dialect{NoHeader: true}, "", errs.ErrEmptyCSV},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDialect(context.Background(), strings.NewReader(tt.in), Options{}, tt.d)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := map[string]rune{
+		"page,watermark_text\n1,A;B":         ',',
+		"Seite;Wasserzeichen\n1;A":           ';',
+		"page\twatermark_text":               '\t',
+		"page|watermark_text|color":          '|',
+		"\"a;b;c\",x\n":                      ',',
+		"page":                               ',',
+		"page;\"watermark,text\";color\n1;2": ';',
+		"# a,b,c\n\npage;watermark_text":     ';',
+	}
+	for head, want := range tests {
+		if got := DetectDelimiter([]byte(head), '#'); got != want {
+			t.Errorf("DetectDelimiter(%q) = %q, want %q", head, got, want)
+		}
+	}
+}
@@ -2,76 +2,382 @@
 package csvparse
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"github.com/anujkumar-df/pdfmark/internal/decode"
 	"github.com/anujkumar-df/pdfmark/internal/errs"
 )
 
+// Options controls how Parse treats the CSV input.
+type Options = decode.Options
+
+// Result holds the instructions read from a CSV.
+type Result = decode.Result
+
+// Dialect describes how a CSV file or XLSX worksheet is laid out. The zero
+// value reads comma-separated UTF-8 with a header row.
+type Dialect struct {
+	// Delimiter separates fields. Zero detects ',', ';', tab or '|' from
+	// the first line.
+	Delimiter rune
+	// Comment, if set, starts lines that are ignored.
+	Comment rune
+	// Headers maps header names, such as "Seite", onto field names such as
+	// "page". Matching is case-insensitive.
+	Headers map[string]string
+	// NoHeader reads every line as data, with the fields in the order given
+	// by Columns.
+	NoHeader bool
+	// Columns names the fields of a headerless file, in order. It defaults
+	// to page and watermark_text.
+	Columns []string
+	// Encoding decodes the input; nil means UTF-8. A UTF-8 or UTF-16 byte
+	// order mark overrides it and is stripped.
+	Encoding encoding.Encoding
+	// Sheet names the worksheet read from an XLSX file. It defaults to
+	// the first one.
+	Sheet string
+}
+
 // Parse reads a CSV from r with the expected format:
 //
-//	page,watermark_text
+//	page,watermark_text[,image,pdf,pdf_page,font,font_size,color,opacity,rotation,position,dx,dy,margin,scale,render_mode]
 //	1,CONFIDENTIAL
-//	3,DRAFT
+//	3,DRAFT,,,,Courier,24,red
+//	5,,logo.png
+//	6,,,letterhead.pdf,2
 //
-// It returns a map from 1-indexed page number to watermark text.
-// Errors are returned for missing headers, duplicate pages, non-integer page
-// numbers, pages <= 0, and rows with fewer than 2 fields.
-func Parse(r io.Reader) (map[int]string, error) {
-	cr := csv.NewReader(r)
-	cr.FieldsPerRecord = -1
-	cr.TrimLeadingSpace = true
+// A row sets exactly one of watermark_text, image, a reference to an image
+// file, or pdf, a reference to a stamp PDF. Both references are resolved by
+// the caller. pdf_page picks the page of the stamp PDF and defaults to 1.
+//
+// The page column holds a page selector: a page number, a range such as
+// "1-5", "odd", "even", "last", "all", a negative number counting from the
+// end, or a quoted comma-separated list of these (see spec.ParsePages).
+//
+// The page and watermark_text columns are located by name when the header
+// names the page column and at least one of watermark_text, image and pdf,
+// otherwise they are the first two columns. The image, pdf, pdf_page and
+// optional style columns are located by name and may appear in any order;
+// an empty cell inherits the base style.
+//
+// Errors are returned for missing headers, repeated single page numbers
+// (unless opts.Layered is set), invalid page selectors, page 0, rows with
+// fewer than 2 fields and invalid style values. If ctx is done before all
+// rows are read, Parse returns an error wrapping ctx.Err().
+func Parse(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	return ParseDialect(ctx, r, opts, Dialect{})
+}
 
-	header, err := cr.Read()
-	if err == io.EOF {
-		return nil, errs.ErrEmptyCSV
-	}
+// ParseDialect is like Parse but reads the CSV dialect d.
+func ParseDialect(ctx context.Context, r io.Reader, opts Options, d Dialect) (*Result, error) {
+	cr, err := newReader(r, d)
 	if err != nil {
-		return nil, fmt.Errorf("%w: reading header: %v", errs.ErrMalformedCSV, err)
+		return nil, err
 	}
-	if len(header) < 2 {
-		return nil, fmt.Errorf("%w: header must have at least 2 columns, got %d", errs.ErrMalformedCSV, len(header))
+	next := func() ([]string, int, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return nil, 0, err
+		}
+		// Comments and quoted line breaks make a line count drift, so ask
+		// the reader where the record started.
+		line, _ := cr.FieldPos(0)
+		return record, line, nil
 	}
+	return parseRecords(ctx, next, opts, d, false)
+}
 
-	instructions := make(map[int]string)
+// newReader returns a CSV reader for r in the encoding, delimiter and
+// comment character of d. A byte order mark is stripped and overrides the
+// encoding; a zero delimiter is detected from the first line.
+func newReader(r io.Reader, d Dialect) (*csv.Reader, error) {
+	var enc encoding.Encoding = unicode.UTF8
+	if d.Encoding != nil {
+		enc = d.Encoding
+	}
+	br := bufio.NewReader(transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder())))
 
-	for line := 2; ; line++ {
-		record, err := cr.Read()
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = d.Comment
+	cr.Comma = d.Delimiter
+	if cr.Comma == 0 {
+		first, err := br.Peek(sniffSize)
+		if len(first) == 0 && err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%w: reading header: %v", errs.ErrMalformedCSV, err)
+		}
+		cr.Comma = DetectDelimiter(first, d.Comment)
+	}
+	return cr, nil
+}
+
+// parseRecords turns the records returned by next into instructions. next
+// returns io.EOF after the last record; a *csv.ParseError skips one record.
+// With findHeader set, the header is the first record among the first few
+// that names the page column and a content column, and the records before
+// it are ignored.
+func parseRecords(ctx context.Context, next func() ([]string, int, error), opts Options, d Dialect, findHeader bool) (*Result, error) {
+	var cols map[string]int
+	b := decode.NewBuilder(opts)
+	var pending [][]string
+	var pendingLines []int
+	read, line := 0, 0
+	if d.NoHeader {
+		var err error
+		if cols, err = positionalColumns(d.Columns); err != nil {
+			return nil, err
+		}
+	} else {
+		header, l, err := next()
+		line = l
+		if err == io.EOF {
+			return nil, errs.ErrEmptyCSV
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading header: %v", errs.ErrMalformedCSV, err)
+		}
+		if findHeader && !isHeader(header, d.Headers) {
+			// Look ahead for the header, remembering the records read in
+			// case none is found.
+			pending, pendingLines = [][]string{header}, []int{line}
+			for len(pending) < headerScan {
+				record, l, err := next()
+				if err != nil {
+					break
+				}
+				if isHeader(record, d.Headers) {
+					header, line, pending, pendingLines = record, l, nil, nil
+					break
+				}
+				pending, pendingLines = append(pending, record), append(pendingLines, l)
+			}
+			if pending != nil {
+				header, line = pending[0], pendingLines[0]
+				pending, pendingLines = pending[1:], pendingLines[1:]
+			}
+		}
+		if len(header) < 2 {
+			return nil, fmt.Errorf("%w: header must have at least 2 columns, got %d", errs.ErrMalformedCSV, len(header))
+		}
+
+		var warnings []error
+		cols, warnings, err = mapColumns(header, d.Headers, line, opts.StrictColumns)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range warnings {
+			b.Warn(w)
+		}
+	}
+
+	for {
+		var record []string
+		var err error
+		if len(pending) > 0 {
+			record, line = pending[0], pendingLines[0]
+			pending, pendingLines = pending[1:], pendingLines[1:]
+		} else {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("reading line %d: %w", line+1, err)
+			}
+			var l int
+			if record, l, err = next(); err == nil {
+				line = l
+			}
+		}
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("%w: line %d: %v", errs.ErrMalformedCSV, line, err)
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return nil, fmt.Errorf("%w: line %d: %v", errs.ErrMalformedCSV, line+1, err)
+			}
+			// The reader resumes at the next record after a parse error.
+			if err := b.Malformed(pe.StartLine, "", "", pe.Err.Error()); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		read++
 
 		if len(record) < 2 {
-			return nil, fmt.Errorf("%w: line %d: expected at least 2 fields, got %d", errs.ErrMalformedCSV, line, len(record))
+			if err := b.Malformed(line, "", "", fmt.Sprintf("expected at least 2 fields, got %d", len(record))); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
-		pageStr := strings.TrimSpace(record[0])
-		page, err := strconv.Atoi(pageStr)
-		if err != nil {
-			return nil, fmt.Errorf("%w: line %d: invalid page number %q", errs.ErrMalformedCSV, line, pageStr)
+		fields := make(map[string]string, len(cols))
+		for name, i := range cols {
+			if i < len(record) {
+				fields[name] = record[i]
+			}
+		}
+		if err := b.Add(decode.Record{Fields: fields, Line: line}); err != nil {
+			return nil, err
 		}
+	}
+	if d.NoHeader && read == 0 {
+		return nil, errs.ErrEmptyCSV
+	}
+
+	return b.Finish()
+}
+
+// headerScan is how many records parseRecords looks at to find the header.
+const headerScan = 10
+
+// isHeader reports whether record names the page column and one of
+// watermark_text, image and pdf, after renaming the columns in aliases.
+func isHeader(record []string, aliases map[string]string) bool {
+	names := renameColumns(record, aliases)
+	return indexOf(names, decode.FieldPage) >= 0 &&
+		(indexOf(names, decode.FieldText) >= 0 || indexOf(names, decode.FieldImage) >= 0 || indexOf(names, decode.FieldPDF) >= 0)
+}
 
-		if page <= 0 {
-			return nil, fmt.Errorf("%w: page %d on line %d", errs.ErrInvalidPage, page, line)
+// sniffSize is how much input is looked at to detect the delimiter.
+const sniffSize = 4096
+
+// delimiters lists the delimiters DetectDelimiter chooses from, in order of
+// preference.
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// DetectDelimiter returns the delimiter used in the first line of head that
+// is neither blank nor starts with comment: the candidate that occurs most
+// often outside quotes, or ',' if none does.
+func DetectDelimiter(head []byte, comment rune) rune {
+	for len(head) > 0 {
+		line, rest, _ := bytes.Cut(head, []byte("\n"))
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && (comment == 0 || !bytes.HasPrefix(trimmed, []byte(string(comment)))) {
+			head = line
+			break
+		}
+		head = rest
+	}
+	counts := make(map[rune]int)
+	quoted := false
+	for _, c := range string(head) {
+		if c == '"' {
+			quoted = !quoted
+			continue
 		}
+		if !quoted {
+			counts[c]++
+		}
+	}
+	best := ','
+	for _, d := range delimiters {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
 
-		text := strings.TrimSpace(record[1])
-		if text == "" {
-			return nil, fmt.Errorf("%w: line %d: watermark text is empty", errs.ErrMalformedCSV, line)
+// positionalColumns maps the fields of a headerless file onto their
+// positions.
+func positionalColumns(names []string) (map[string]int, error) {
+	if len(names) == 0 {
+		names = []string{decode.FieldPage, decode.FieldText}
+	}
+	cols := make(map[string]int, len(names))
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			// Skip the column.
+			continue
+		}
+		if !decode.IsKnownField(name) {
+			return nil, fmt.Errorf("%w: %q", errs.ErrUnknownColumn, name)
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("%w: repeated %q", errs.ErrUnknownColumn, name)
 		}
+		cols[name] = i
+	}
+	if _, ok := cols[decode.FieldPage]; !ok {
+		return nil, fmt.Errorf("%w: headerless columns must include %s", errs.ErrMalformedCSV, decode.FieldPage)
+	}
+	return cols, nil
+}
+
+// mapColumns resolves the header row into the field name of each known
+// column and its position, after renaming the columns named in aliases.
+// Unknown columns are returned as warnings, or as an error when strict is
+// set. line is the line of the header.
+func mapColumns(header []string, aliases map[string]string, line int, strict bool) (map[string]int, []error, error) {
+	names := renameColumns(header, aliases)
+
+	page, text := 0, 1
+	p, t := indexOf(names, decode.FieldPage), indexOf(names, decode.FieldText)
+	if p >= 0 && (t >= 0 || indexOf(names, decode.FieldImage) >= 0 || indexOf(names, decode.FieldPDF) >= 0) {
+		page, text = p, t
+	}
+	cols := map[string]int{decode.FieldPage: page}
+	if text >= 0 {
+		cols[decode.FieldText] = text
+	}
 
-		if _, exists := instructions[page]; exists {
-			return nil, fmt.Errorf("%w: page %d on line %d", errs.ErrDuplicatePage, page, line)
+	// The remaining columns are found by name, but never replace the
+	// positional page and watermark_text columns.
+	var warnings []error
+	for i, name := range names {
+		if i == page || i == text {
+			continue
 		}
+		err := fmt.Errorf("%w: %q in column %d", errs.ErrUnknownColumn, header[i], i+1)
+		if decode.IsKnownField(name) {
+			if _, dup := cols[name]; !dup {
+				cols[name] = i
+				continue
+			}
+			err = fmt.Errorf("%w: repeated %q in column %d", errs.ErrUnknownColumn, header[i], i+1)
+		}
+		if strict {
+			return nil, nil, fmt.Errorf("%w: %w", errs.ErrMalformedCSV, err)
+		}
+		warnings = append(warnings, err)
+	}
+	return cols, warnings, nil
+}
 
-		instructions[page] = text
+// renameColumns returns the lower-cased header names, with the names in
+// aliases replaced by their field names.
+func renameColumns(header []string, aliases map[string]string) []string {
+	lower := make(map[string]string, len(aliases))
+	for alias, field := range aliases {
+		lower[strings.ToLower(strings.TrimSpace(alias))] = strings.ToLower(strings.TrimSpace(field))
 	}
+	names := make([]string, len(header))
+	for i, h := range header {
+		names[i] = strings.ToLower(strings.TrimSpace(h))
+		if field, ok := lower[names[i]]; ok {
+			names[i] = field
+		}
+	}
+	return names
+}
 
-	return instructions, nil
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
 }
@@ -1,12 +1,14 @@
 package csvparse
 
 import (
+	"context"
 	"errors"
 	"io"
 	"strings"
 	"testing"
 
 	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
 	"github.com/anujkumar-df/pdfmark/internal/testutil"
 )
 
@@ -14,6 +16,21 @@ func csvString(lines ...string) io.Reader {
 	return testutil.CSVString(lines...)
 }
 
+// expand resolves the parsed instructions against a PDF of totalPages pages
+// and returns the single instruction for each page.
+func expand(t *testing.T, res *Result, totalPages int) map[int]spec.Instruction {
+	t.Helper()
+	m, _, err := spec.Expand(res.Instructions, totalPages, false, spec.Strict)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	single := make(map[int]spec.Instruction, len(m))
+	for page, list := range m {
+		single[page] = list[0]
+	}
+	return single
+}
+
 func TestParse_Valid(t *testing.T) {
 	r := csvString(
 		"page,watermark_text",
@@ -22,30 +39,32 @@ func TestParse_Valid(t *testing.T) {
 		"5,INTERNAL USE ONLY",
 	)
 
-	m, err := Parse(r)
+	res, err := Parse(context.Background(), r, Options{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	m := expand(t, res, 10)
 	if len(m) != 3 {
 		t.Fatalf("got %d entries, want 3", len(m))
 	}
-	if m[1] != "CONFIDENTIAL" {
-		t.Errorf("page 1 = %q, want %q", m[1], "CONFIDENTIAL")
+	if m[1].Text != "CONFIDENTIAL" {
+		t.Errorf("page 1 = %q, want %q", m[1].Text, "CONFIDENTIAL")
 	}
-	if m[3] != "DRAFT" {
-		t.Errorf("page 3 = %q, want %q", m[3], "DRAFT")
+	if m[3].Text != "DRAFT" {
+		t.Errorf("page 3 = %q, want %q", m[3].Text, "DRAFT")
 	}
-	if m[5] != "INTERNAL USE ONLY" {
-		t.Errorf("page 5 = %q, want %q", m[5], "INTERNAL USE ONLY")
+	if m[5].Text != "INTERNAL USE ONLY" {
+		t.Errorf("page 5 = %q, want %q", m[5].Text, "INTERNAL USE ONLY")
 	}
 }
 
 func TestParse_EmptyBody(t *testing.T) {
 	r := csvString("page,watermark_text")
-	m, err := Parse(r)
+	res, err := Parse(context.Background(), r, Options{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	m := expand(t, res, 10)
 	if len(m) != 0 {
 		t.Errorf("got %d entries, want 0", len(m))
 	}
@@ -53,7 +72,7 @@ func TestParse_EmptyBody(t *testing.T) {
 
 func TestParse_EmptyInput(t *testing.T) {
 	r := strings.NewReader("")
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrEmptyCSV) {
 		t.Errorf("got error %v, want ErrEmptyCSV", err)
 	}
@@ -65,18 +84,37 @@ func TestParse_DuplicatePage(t *testing.T) {
 		"1,CONFIDENTIAL",
 		"1,DRAFT",
 	)
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrDuplicatePage) {
 		t.Errorf("got error %v, want ErrDuplicatePage", err)
 	}
 }
 
+func TestParse_DuplicatePageLayered(t *testing.T) {
+	r := csvString(
+		"page,watermark_text",
+		"1,CONFIDENTIAL",
+		"1,Copy for Jane Doe",
+	)
+	res, err := Parse(context.Background(), r, Options{Layered: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, _, err := spec.Expand(res.Instructions, 1, true, spec.Strict)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(m[1]) != 2 || m[1][0].Text != "CONFIDENTIAL" || m[1][1].Text != "Copy for Jane Doe" {
+		t.Errorf("page 1 = %+v, want both rows in CSV order", m[1])
+	}
+}
+
 func TestParse_InvalidPageNumber(t *testing.T) {
 	r := csvString(
 		"page,watermark_text",
 		"abc,CONFIDENTIAL",
 	)
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrMalformedCSV) {
 		t.Errorf("got error %v, want ErrMalformedCSV", err)
 	}
@@ -87,9 +125,53 @@ func TestParse_NegativePage(t *testing.T) {
 		"page,watermark_text",
 		"-1,CONFIDENTIAL",
 	)
-	_, err := Parse(r)
-	if !errors.Is(err, errs.ErrInvalidPage) {
-		t.Errorf("got error %v, want ErrInvalidPage", err)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := expand(t, res, 4)
+	if len(m) != 1 || m[4].Text != "CONFIDENTIAL" {
+		t.Errorf("got %v, want page 4 (last) = CONFIDENTIAL", m)
+	}
+}
+
+func TestParse_PageSelectors(t *testing.T) {
+	r := csvString(
+		"page,watermark_text",
+		"1-2,HEAD",
+		`"4,6",LIST`,
+		"last,END",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Instructions) != 3 {
+		t.Fatalf("got %d instructions, want 3", len(res.Instructions))
+	}
+
+	m := expand(t, res, 7)
+	want := map[int]string{1: "HEAD", 2: "HEAD", 4: "LIST", 6: "LIST", 7: "END"}
+	if len(m) != len(want) {
+		t.Fatalf("got %d pages, want %d", len(m), len(want))
+	}
+	for page, text := range want {
+		if m[page].Text != text {
+			t.Errorf("page %d = %q, want %q", page, m[page].Text, text)
+		}
+	}
+}
+
+func TestParse_InvalidPageSelector(t *testing.T) {
+	for _, sel := range []string{"5-2", "x-3", "1-y", "first", `"1,,2"`} {
+		r := csvString(
+			"page,watermark_text",
+			sel+",BAD",
+		)
+		_, err := Parse(context.Background(), r, Options{})
+		if !errors.Is(err, errs.ErrMalformedCSV) {
+			t.Errorf("selector %s: got error %v, want ErrMalformedCSV", sel, err)
+		}
 	}
 }
 
@@ -98,7 +180,7 @@ func TestParse_ZeroPage(t *testing.T) {
 		"page,watermark_text",
 		"0,CONFIDENTIAL",
 	)
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrInvalidPage) {
 		t.Errorf("got error %v, want ErrInvalidPage", err)
 	}
@@ -109,7 +191,7 @@ func TestParse_MissingColumns(t *testing.T) {
 		"page,watermark_text",
 		"1",
 	)
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrMalformedCSV) {
 		t.Errorf("got error %v, want ErrMalformedCSV", err)
 	}
@@ -120,7 +202,7 @@ func TestParse_EmptyWatermarkText(t *testing.T) {
 		"page,watermark_text",
 		"1,",
 	)
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrMalformedCSV) {
 		t.Errorf("got error %v, want ErrMalformedCSV", err)
 	}
@@ -128,7 +210,7 @@ func TestParse_EmptyWatermarkText(t *testing.T) {
 
 func TestParse_HeaderOnlyOneColumn(t *testing.T) {
 	r := csvString("page")
-	_, err := Parse(r)
+	_, err := Parse(context.Background(), r, Options{})
 	if !errors.Is(err, errs.ErrMalformedCSV) {
 		t.Errorf("got error %v, want ErrMalformedCSV", err)
 	}
@@ -139,12 +221,264 @@ func TestParse_ExtraColumns(t *testing.T) {
 		"page,watermark_text,extra",
 		"1,CONFIDENTIAL,ignored",
 	)
-	m, err := Parse(r)
+	res, err := Parse(context.Background(), r, Options{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if m[1] != "CONFIDENTIAL" {
-		t.Errorf("page 1 = %q, want %q", m[1], "CONFIDENTIAL")
+	m := expand(t, res, 10)
+	if m[1].Text != "CONFIDENTIAL" {
+		t.Errorf("page 1 = %q, want %q", m[1].Text, "CONFIDENTIAL")
+	}
+	if len(res.Warnings) != 1 || !errors.Is(res.Warnings[0], errs.ErrUnknownColumn) {
+		t.Errorf("warnings = %v, want one ErrUnknownColumn", res.Warnings)
+	}
+}
+
+func TestParse_StrictColumns(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,extra",
+		"1,CONFIDENTIAL,ignored",
+	)
+	_, err := Parse(context.Background(), r, Options{StrictColumns: true})
+	if !errors.Is(err, errs.ErrMalformedCSV) || !errors.Is(err, errs.ErrUnknownColumn) {
+		t.Errorf("got error %v, want ErrMalformedCSV and ErrUnknownColumn", err)
+	}
+}
+
+func TestParse_StyleColumns(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,font,font_size,color,opacity,rotation,position,dx,dy,render_mode",
+		"1,CONFIDENTIAL,Courier,24,red,0.5,30,top-left,10,-5,stroke",
+		"2,DRAFT,,,,,,,,,",
+		"3,DIAGONAL,,,,,ul-lr",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := expand(t, res, 3)
+
+	got := m[1].Style.Apply(spec.DefaultStyle())
+	want := spec.DefaultStyle()
+	want.FontName = "Courier"
+	want.FontSize = 24
+	want.Color = spec.Red
+	want.Opacity = 0.5
+	want.Rotation = 30
+	want.Diagonal = spec.NoDiagonal
+	want.Position = spec.TopLeft
+	want.Dx, want.Dy = 10, -5
+	want.RenderMode = spec.RenderStroke
+	if got != want {
+		t.Errorf("page 1 style = %+v, want %+v", got, want)
+	}
+
+	if got := m[2].Style.Apply(spec.DefaultStyle()); got != spec.DefaultStyle() {
+		t.Errorf("page 2 style = %+v, want default", got)
+	}
+
+	if got := m[3].Style.Apply(spec.DefaultStyle()); got.Diagonal != spec.DiagonalULToLR {
+		t.Errorf("page 3 diagonal = %v, want DiagonalULToLR", got.Diagonal)
+	}
+}
+
+func TestParse_ColumnsByName(t *testing.T) {
+	r := csvString(
+		"opacity,watermark_text,page",
+		"0.9,DRAFT,4",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ins, ok := expand(t, res, 4)[4]
+	if !ok || ins.Text != "DRAFT" {
+		t.Fatalf("page 4 = %+v, want DRAFT", ins)
+	}
+	if ins.Style.Opacity == nil || *ins.Style.Opacity != 0.9 {
+		t.Errorf("page 4 opacity = %v, want 0.9", ins.Style.Opacity)
+	}
+}
+
+func TestParse_InvalidStyleValues(t *testing.T) {
+	tests := []struct {
+		column, value string
+	}{
+		{"font_size", "big"},
+		{"font_size", "0"},
+		{"color", "chartreuse"},
+		{"opacity", "1.5"},
+		{"rotation", "270"},
+		{"rotation", "sideways"},
+		{"position", "middle"},
+		{"dx", "left"},
+		{"margin", "-3"},
+		{"render_mode", "invisible"},
+		{"scale", "2"},
+		{"scale", "big abs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column+"="+tt.value, func(t *testing.T) {
+			r := csvString(
+				"page,watermark_text,"+tt.column,
+				"1,OK,",
+				"2,BAD,"+tt.value,
+			)
+			_, err := Parse(context.Background(), r, Options{})
+			if !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Fatalf("got error %v, want ErrMalformedCSV", err)
+			}
+			if !strings.Contains(err.Error(), "line 3") {
+				t.Errorf("error %q does not mention line 3", err)
+			}
+		})
+	}
+}
+
+func TestParse_InstructionError(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,opacity",
+		"1,OK,",
+		"2,BAD,1.5",
+	)
+	_, err := Parse(context.Background(), r, Options{})
+	var ie *errs.InstructionError
+	if !errors.As(err, &ie) {
+		t.Fatalf("got error %v, want *InstructionError", err)
+	}
+	if !errors.Is(err, errs.ErrMalformedCSV) {
+		t.Errorf("got error %v, want ErrMalformedCSV", err)
+	}
+	if ie.Line != 3 || ie.Column != "opacity" || ie.Value != "1.5" {
+		t.Errorf("got line %d column %q value %q, want line 3 column opacity value 1.5", ie.Line, ie.Column, ie.Value)
+	}
+}
+
+func TestParse_CollectAll(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,color",
+		"1,OK,",
+		"0,ZERO,",
+		"2,BAD,chartreuse",
+		"3",
+		"1,AGAIN,",
+		"4,\"unterminated",
+	)
+	res, err := Parse(context.Background(), r, Options{CollectAll: true})
+	want := []struct {
+		line     int
+		sentinel error
+	}{
+		{3, errs.ErrInvalidPage},
+		{4, errs.ErrMalformedCSV},
+		{5, errs.ErrMalformedCSV},
+		{6, errs.ErrDuplicatePage},
+		{7, errs.ErrMalformedCSV},
+	}
+	problems := errs.Problems(err)
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(problems), len(want), err)
+	}
+	for i, w := range want {
+		if problems[i].Line != w.line || !errors.Is(problems[i], w.sentinel) {
+			t.Errorf("problem %d = %v, want line %d matching %v", i, problems[i], w.line, w.sentinel)
+		}
+	}
+	if res == nil || len(res.Instructions) != 1 {
+		t.Errorf("got %v, want the 1 valid instruction before the unterminated quote", res)
+	}
+}
+
+func TestParse_ImageColumn(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,image,scale",
+		"1,CONFIDENTIAL,,",
+		"2,,logo.png,0.2",
+		"3,,logo.png,1.5 abs",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := expand(t, res, 3)
+	if m[1].Text != "CONFIDENTIAL" || m[1].Image != "" {
+		t.Errorf("page 1 = %+v, want text only", m[1])
+	}
+	if m[2].Image != "logo.png" || m[2].Text != "" {
+		t.Errorf("page 2 = %+v, want image only", m[2])
+	}
+	if got := m[2].Style.Apply(spec.DefaultStyle()); got.Scale != 0.2 || got.ScaleMode != spec.ScaleRelative {
+		t.Errorf("page 2 scale = %g %v, want 0.2 relative", got.Scale, got.ScaleMode)
+	}
+	if got := m[3].Style.Apply(spec.DefaultStyle()); got.Scale != 1.5 || got.ScaleMode != spec.ScaleAbsolute {
+		t.Errorf("page 3 scale = %g %v, want 1.5 absolute", got.Scale, got.ScaleMode)
+	}
+}
+
+func TestParse_ImageColumnByName(t *testing.T) {
+	r := csvString(
+		"image,page",
+		"logo.png,4",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ins := expand(t, res, 4)[4]; ins.Image != "logo.png" {
+		t.Errorf("page 4 = %+v, want image logo.png", ins)
+	}
+}
+
+func TestParse_TextAndImage(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,image",
+		"1,CONFIDENTIAL,logo.png",
+	)
+	_, err := Parse(context.Background(), r, Options{})
+	if !errors.Is(err, errs.ErrMalformedCSV) {
+		t.Errorf("got error %v, want ErrMalformedCSV", err)
+	}
+}
+
+func TestParse_PDFColumn(t *testing.T) {
+	r := csvString(
+		"page,watermark_text,pdf,pdf_page",
+		"1,,letterhead.pdf,",
+		"2,,letterhead.pdf,3",
+		"3,DRAFT,,",
+	)
+	res, err := Parse(context.Background(), r, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := expand(t, res, 3)
+	if m[1].PDF != "letterhead.pdf" || m[1].PDFPage != 1 {
+		t.Errorf("page 1 = %+v, want letterhead.pdf page 1", m[1])
+	}
+	if m[2].PDF != "letterhead.pdf" || m[2].PDFPage != 3 {
+		t.Errorf("page 2 = %+v, want letterhead.pdf page 3", m[2])
+	}
+	if m[3].Text != "DRAFT" || m[3].PDF != "" || m[3].PDFPage != 0 {
+		t.Errorf("page 3 = %+v, want text only", m[3])
+	}
+}
+
+func TestParse_InvalidPDFColumn(t *testing.T) {
+	tests := map[string]string{
+		"text and pdf":     "1,DRAFT,,stamp.pdf,",
+		"image and pdf":    "1,,logo.png,stamp.pdf,",
+		"page zero":        "1,,,stamp.pdf,0",
+		"page not number":  "1,,,stamp.pdf,x",
+		"page without pdf": "1,DRAFT,,,2",
+	}
+	for name, row := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := csvString("page,watermark_text,image,pdf,pdf_page", row)
+			_, err := Parse(context.Background(), r, Options{})
+			if !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Errorf("got error %v, want ErrMalformedCSV", err)
+			}
+		})
 	}
 }
 
@@ -153,11 +487,21 @@ func TestParse_WhitespaceTrimming(t *testing.T) {
 		"page, watermark_text",
 		" 2 , DRAFT ",
 	)
-	m, err := Parse(r)
+	res, err := Parse(context.Background(), r, Options{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if m[2] != "DRAFT" {
-		t.Errorf("page 2 = %q, want %q", m[2], "DRAFT")
+	m := expand(t, res, 10)
+	if m[2].Text != "DRAFT" {
+		t.Errorf("page 2 = %q, want %q", m[2].Text, "DRAFT")
+	}
+}
+
+func TestParse_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Parse(ctx, csvString("page,watermark_text", "1,DRAFT"), Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
 	}
 }
@@ -0,0 +1,93 @@
+package csvparse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
+)
+
+// Recipient is one row of a mail-merge recipients CSV.
+type Recipient struct {
+	// Key identifies the recipient; it is the value of the key column.
+	Key string
+	// Vars maps every column name to the row's value, for use as template
+	// variables.
+	Vars map[string]string
+	// Line is the source line of the row.
+	Line int
+}
+
+// ParseRecipients reads a recipients CSV from r. The header names the
+// template variables and must include keyColumn, whose values must be
+// non-empty and unique:
+//
+//	email,recipient,ticket
+//	jane@example.com,Jane Doe,T-1
+//	joe@example.com,Joe Bloggs,T-2
+//
+// The file is read like an instruction CSV in the default Dialect: a byte
+// order mark, as Excel writes, is stripped, and the delimiter is detected.
+func ParseRecipients(r io.Reader, keyColumn string) ([]Recipient, error) {
+	cr, err := newReader(r, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+	// Every row must have a value for every column.
+	cr.FieldsPerRecord = 0
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errs.ErrEmptyCSV
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", errs.ErrMalformedCSV, err)
+	}
+
+	key := -1
+	for i, h := range header {
+		header[i] = strings.TrimSpace(h)
+		if err := tmpl.ValidateName(header[i]); err != nil {
+			return nil, fmt.Errorf("%w: column %d: %w", errs.ErrMalformedCSV, i+1, err)
+		}
+		if header[i] == keyColumn {
+			key = i
+		}
+	}
+	if key < 0 {
+		return nil, fmt.Errorf("%w: key column %q not in header", errs.ErrMalformedCSV, keyColumn)
+	}
+
+	var recipients []Recipient
+	seen := make(map[string]int)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Parse errors name the line themselves.
+			return nil, fmt.Errorf("%w: %v", errs.ErrMalformedCSV, err)
+		}
+		// Quoted line breaks make a line count drift, so ask the reader
+		// where the record started.
+		line, _ := cr.FieldPos(0)
+
+		vars := make(map[string]string, len(header))
+		for i, name := range header {
+			vars[name] = strings.TrimSpace(record[i])
+		}
+		k := vars[keyColumn]
+		if k == "" {
+			return nil, fmt.Errorf("%w: line %d: empty %s", errs.ErrMalformedCSV, line, keyColumn)
+		}
+		if prev, ok := seen[k]; ok {
+			return nil, fmt.Errorf("%w: line %d: duplicate %s %q, already on line %d", errs.ErrMalformedCSV, line, keyColumn, k, prev)
+		}
+		seen[k] = line
+		recipients = append(recipients, Recipient{Key: k, Vars: vars, Line: line})
+	}
+	return recipients, nil
+}
@@ -0,0 +1,77 @@
+package csvparse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+func TestParseRecipients(t *testing.T) {
+	r := csvString(
+		"email, recipient",
+		"jane@example.com, Jane Doe",
+		`"joe@example.com","Joe`,
+		`Bloggs"`,
+		"ann@example.com,Ann",
+	)
+	got, err := ParseRecipients(r, "email")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d recipients, want 3", len(got))
+	}
+	if got[0].Key != "jane@example.com" || got[0].Vars["recipient"] != "Jane Doe" || got[0].Line != 2 {
+		t.Errorf("recipient 0 = %+v", got[0])
+	}
+	if got[1].Key != "joe@example.com" || got[1].Vars["email"] != "joe@example.com" || got[1].Line != 3 {
+		t.Errorf("recipient 1 = %+v", got[1])
+	}
+	// Joe's name spans two lines.
+	if got[2].Line != 5 {
+		t.Errorf("recipient 2 on line %d, want 5", got[2].Line)
+	}
+}
+
+func TestParseRecipients_Excel(t *testing.T) {
+	// Excel's "CSV UTF-8" starts with a byte order mark and uses the
+	// list separator of the locale.
+	r := csvString(
+		"\ufeffemail;recipient\r",
+		"jane@example.com;Jäne Doe\r",
+	)
+	got, err := ParseRecipients(r, "email")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Key != "jane@example.com" || got[0].Vars["recipient"] != "Jäne Doe" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestParseRecipients_Invalid(t *testing.T) {
+	tests := map[string][]string{
+		"missing key column": {"name", "Jane"},
+		"empty key":          {"email,name", ",Jane"},
+		"duplicate key":      {"email,name", "a@x,Jane", "a@x,Joe"},
+		"bad column name":    {"email,first name", "a@x,Jane"},
+		"reserved column":    {"email,page", "a@x,1"},
+		"short row":          {"email,name", "a@x"},
+	}
+	for name, lines := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRecipients(csvString(lines...), "email")
+			if !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Errorf("got error %v, want ErrMalformedCSV", err)
+			}
+		})
+	}
+}
+
+func TestParseRecipients_Empty(t *testing.T) {
+	_, err := ParseRecipients(csvString(), "email")
+	if !errors.Is(err, errs.ErrEmptyCSV) {
+		t.Errorf("got error %v, want ErrEmptyCSV", err)
+	}
+}
@@ -0,0 +1,44 @@
+package csvparse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/xlsx"
+)
+
+// ParseXLSX reads instructions from a worksheet of the XLSX file in r with
+// the same columns and checks as Parse. Lines are spreadsheet row numbers.
+//
+// d.Sheet selects the worksheet, by default the first. The header is the
+// first of the first few non-empty rows naming the page column and one of
+// watermark_text, image and pdf, so title rows above it are skipped; if no
+// row qualifies, the first row is the header. d.Headers, d.NoHeader and
+// d.Columns apply as for CSV, while the delimiter, comment character and
+// encoding are ignored.
+func ParseXLSX(ctx context.Context, r io.Reader, opts Options, d Dialect) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading instructions: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errs.ErrEmptyCSV
+	}
+	rows, err := xlsx.ReadSheet(bytes.NewReader(data), int64(len(data)), d.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	next := func() ([]string, int, error) {
+		if len(rows) == 0 {
+			return nil, 0, io.EOF
+		}
+		row := rows[0]
+		rows = rows[1:]
+		return row.Cells, row.Line, nil
+	}
+	return parseRecords(ctx, next, opts, d, true)
+}
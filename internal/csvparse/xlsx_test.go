@@ -0,0 +1,58 @@
+package csvparse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+func TestParseXLSX_MatchesCSV(t *testing.T) {
+	rows := [][]string{
+		{"page", "watermark_text", "color", "opacity"},
+		{"1", "CONFIDENTIAL", "red", ""},
+		{"2-3", "DRAFT", "", "0.5"},
+	}
+	data := testutil.XLSX(t, testutil.Sheet{Name: "Sheet1", Rows: rows})
+	got, err := ParseXLSX(context.Background(), bytes.NewReader(data), Options{}, Dialect{})
+	if err != nil {
+		t.Fatalf("ParseXLSX: %v", err)
+	}
+	want, err := Parse(context.Background(), csvString("page,watermark_text,color,opacity", "1,CONFIDENTIAL,red,", "2-3,DRAFT,,0.5"), Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseXLSX = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseXLSX_FindsHeader(t *testing.T) {
+	data := testutil.XLSX(t,
+		testutil.Sheet{Name: "Cover", Rows: [][]string{{"nothing here"}}},
+		testutil.Sheet{Name: "Watermarks", Rows: [][]string{
+			{"Q3 watermark plan"},
+			nil,
+			{"Seite", "Wasserzeichen"},
+			{"1", "VERTRAULICH"},
+			{"0", "BAD"},
+		}},
+	)
+	d := Dialect{Sheet: "Watermarks", Headers: map[string]string{"Seite": "page", "Wasserzeichen": "watermark_text"}}
+	_, err := ParseXLSX(context.Background(), bytes.NewReader(data), Options{}, d)
+	var ie *errs.InstructionError
+	if !errors.As(err, &ie) || ie.Line != 5 || !errors.Is(err, errs.ErrInvalidPage) {
+		t.Fatalf("got error %v, want ErrInvalidPage on row 5", err)
+	}
+}
+
+func TestParseXLSX_Empty(t *testing.T) {
+	data := testutil.XLSX(t, testutil.Sheet{Name: "Sheet1"})
+	if _, err := ParseXLSX(context.Background(), bytes.NewReader(data), Options{}, Dialect{}); !errors.Is(err, errs.ErrEmptyCSV) {
+		t.Errorf("got error %v, want ErrEmptyCSV", err)
+	}
+}
@@ -0,0 +1,349 @@
+// Package decode turns format-neutral instruction records into watermark
+// instructions. The CSV, JSON and YAML readers all produce records, so the
+// field names, value syntax and checks are the same in every format.
+package decode
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// Field names recognised in a record. They match the CSV column names.
+const (
+	FieldPage    = "page"
+	FieldText    = "watermark_text"
+	FieldImage   = "image"
+	FieldPDF     = "pdf"
+	FieldPDFPage = "pdf_page"
+)
+
+// Options controls how records are turned into instructions.
+type Options struct {
+	// StrictColumns rejects unknown fields instead of reporting them as
+	// warnings.
+	StrictColumns bool
+	// Layered allows several records to select the same page; they are
+	// stacked in input order.
+	Layered bool
+	// Policy is the page policy applied later by spec.Expand. Repeated
+	// single page numbers are only rejected here under spec.Strict.
+	Policy spec.PagePolicy
+	// CollectAll keeps going after an invalid record so that every problem
+	// in the input is reported, joined into one error.
+	CollectAll bool
+}
+
+// Result holds the instructions read from an input.
+type Result struct {
+	// Instructions lists one instruction per record, in input order. Page
+	// selectors are resolved later with spec.Expand.
+	Instructions []spec.Instruction
+	// Warnings lists non-fatal problems such as unknown fields.
+	Warnings []error
+}
+
+// Record is one instruction in format-neutral form: field name to raw
+// value. Empty values are treated as absent.
+type Record struct {
+	Fields map[string]string
+	Line   int
+}
+
+// IsKnownField reports whether name is a recognised field.
+func IsKnownField(name string) bool {
+	switch name {
+	case FieldPage, FieldText, FieldImage, FieldPDF, FieldPDFPage:
+		return true
+	}
+	_, ok := styleParsers[name]
+	return ok
+}
+
+// Builder accumulates instructions from records.
+type Builder struct {
+	opts     Options
+	res      Result
+	singles  map[int]int
+	unknown  map[string]bool
+	problems []*errs.InstructionError
+}
+
+// NewBuilder returns a Builder applying opts.
+func NewBuilder(opts Options) *Builder {
+	return &Builder{opts: opts, singles: make(map[int]int), unknown: make(map[string]bool)}
+}
+
+// Warn records a non-fatal problem found by the reader.
+func (b *Builder) Warn(err error) {
+	b.res.Warnings = append(b.res.Warnings, err)
+}
+
+// Fail records a problem with one record. It returns p, which the reader
+// should return, unless CollectAll is set; then it returns nil and the
+// reader should carry on with the next record.
+func (b *Builder) Fail(p *errs.InstructionError) error {
+	if !b.opts.CollectAll {
+		return p
+	}
+	b.problems = append(b.problems, p)
+	return nil
+}
+
+// Malformed records a malformed record through Fail.
+func (b *Builder) Malformed(line int, column, value, detail string) error {
+	return b.Fail(&errs.InstructionError{Err: errs.ErrMalformedCSV, Line: line, Column: column, Value: value, Detail: detail})
+}
+
+// Add converts rec into an instruction. Problems are reported through Fail:
+// invalid page selectors, page 0, records setting none or several of
+// watermark_text, image and pdf, invalid style values, repeated single page
+// numbers under the Strict policy unless Layered is set, and unknown fields
+// when StrictColumns is set.
+func (b *Builder) Add(rec Record) error {
+	line := rec.Line
+	for _, name := range slices.Sorted(maps.Keys(rec.Fields)) {
+		if IsKnownField(name) || b.unknown[name] {
+			continue
+		}
+		p := &errs.InstructionError{Err: errs.ErrUnknownColumn, Line: line, Column: name}
+		if b.opts.StrictColumns {
+			p.Err = fmt.Errorf("%w: %w", errs.ErrMalformedCSV, errs.ErrUnknownColumn)
+			return b.Fail(p)
+		}
+		b.unknown[name] = true
+		b.Warn(p)
+	}
+	get := func(name string) string {
+		return strings.TrimSpace(rec.Fields[name])
+	}
+
+	pageStr := get(FieldPage)
+	pages, err := spec.ParsePages(pageStr)
+	if errors.Is(err, errs.ErrInvalidPage) {
+		return b.Fail(&errs.InstructionError{Err: errs.ErrInvalidPage, Line: line, Column: FieldPage, Value: pageStr})
+	}
+	if err != nil {
+		return b.Malformed(line, FieldPage, pageStr, "invalid page selector")
+	}
+
+	text, image, pdf := get(FieldText), get(FieldImage), get(FieldPDF)
+	switch n := countSet(text, image, pdf); {
+	case n == 0:
+		return b.Malformed(line, FieldText, "", "watermark text is empty")
+	case n > 1:
+		return b.Malformed(line, "", "", "set only one of watermark text, image or pdf")
+	}
+
+	pdfPage := 0
+	if raw := get(FieldPDFPage); raw != "" {
+		if pdf == "" {
+			return b.Malformed(line, FieldPDFPage, raw, "pdf_page set without pdf")
+		}
+		if pdfPage, err = strconv.Atoi(raw); err != nil || pdfPage < 1 {
+			return b.Malformed(line, FieldPDFPage, raw, "want a page number >= 1")
+		}
+	}
+	if pdf != "" && pdfPage == 0 {
+		pdfPage = 1
+	}
+
+	var override spec.StyleOverride
+	for _, name := range styleOrder {
+		raw := get(name)
+		if raw == "" {
+			continue
+		}
+		if err := styleParsers[name](raw, &override); err != nil {
+			return b.Malformed(line, name, raw, err.Error())
+		}
+	}
+
+	// Overlapping selectors can only be detected once the page count is
+	// known, but plain page numbers are checked here already.
+	if page, ok := pages.Single(); ok && !b.opts.Layered && b.opts.Policy == spec.Strict {
+		if prev, exists := b.singles[page]; exists {
+			return b.Fail(&errs.InstructionError{
+				Err:    errs.ErrDuplicatePage,
+				Line:   line,
+				Page:   page,
+				Detail: fmt.Sprintf("already set on line %d", prev),
+			})
+		}
+		b.singles[page] = line
+	}
+
+	b.res.Instructions = append(b.res.Instructions, spec.Instruction{
+		Pages:   pages,
+		Text:    text,
+		Image:   image,
+		PDF:     pdf,
+		PDFPage: pdfPage,
+		Style:   override,
+		Line:    line,
+	})
+	return nil
+}
+
+// Finish returns the instructions added so far and, with CollectAll, every
+// problem recorded, joined into one error.
+func (b *Builder) Finish() (*Result, error) {
+	res := b.res
+	return &res, errs.Join(b.problems)
+}
+
+// countSet returns how many of values are non-empty.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// styleOrder fixes the order style fields are checked in, so the error
+// reported for a record with several bad values does not depend on map
+// iteration order.
+var styleOrder = []string{
+	"font", "font_size", "color", "opacity", "rotation", "position",
+	"dx", "dy", "margin", "scale", "render_mode",
+}
+
+// styleParsers maps optional column names onto functions that parse a cell
+// into the matching StyleOverride field.
+var styleParsers = map[string]func(raw string, o *spec.StyleOverride) error{
+	"font": func(raw string, o *spec.StyleOverride) error {
+		o.FontName = &raw
+		return nil
+	},
+	"font_size": func(raw string, o *spec.StyleOverride) error {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		if n <= 0 {
+			return errors.New("must be > 0")
+		}
+		o.FontSize = &n
+		return nil
+	},
+	"color": func(raw string, o *spec.StyleOverride) error {
+		c, err := spec.ParseColor(raw)
+		if err != nil {
+			return errors.New("unknown color")
+		}
+		o.Color = &c
+		return nil
+	},
+	"opacity": func(raw string, o *spec.StyleOverride) error {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		if f <= 0 || f > 1 {
+			return errors.New("must be in (0, 1]")
+		}
+		o.Opacity = &f
+		return nil
+	},
+	"rotation": parseRotation,
+	"position": func(raw string, o *spec.StyleOverride) error {
+		p, err := spec.ParsePosition(raw)
+		if err != nil {
+			return errors.New("unknown position")
+		}
+		o.Position = &p
+		return nil
+	},
+	"dx": func(raw string, o *spec.StyleOverride) error {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		o.Dx = &f
+		return nil
+	},
+	"dy": func(raw string, o *spec.StyleOverride) error {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		o.Dy = &f
+		return nil
+	},
+	"margin": func(raw string, o *spec.StyleOverride) error {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		if f < 0 {
+			return errors.New("must be >= 0")
+		}
+		o.Margin = &f
+		return nil
+	},
+	"scale": parseScale,
+	"render_mode": func(raw string, o *spec.StyleOverride) error {
+		m, err := spec.ParseRenderMode(raw)
+		if err != nil {
+			return errors.New("want fill, stroke or fill-stroke")
+		}
+		o.RenderMode = &m
+		return nil
+	},
+}
+
+// parseRotation accepts an angle in degrees, which disables the diagonal, or
+// one of the diagonal names "diagonal"/"ll-ur" and "ul-lr".
+func parseRotation(raw string, o *spec.StyleOverride) error {
+	var (
+		d   spec.Diagonal
+		rot float64
+	)
+	switch strings.ToLower(raw) {
+	case "diagonal", "ll-ur":
+		d = spec.DiagonalLLToUR
+	case "ul-lr":
+		d = spec.DiagonalULToLR
+	default:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("want degrees, diagonal, ll-ur or ul-lr")
+		}
+		if f < -180 || f > 180 {
+			return errors.New("must be in [-180, 180]")
+		}
+		d, rot = spec.NoDiagonal, f
+	}
+	o.Diagonal = &d
+	o.Rotation = &rot
+	return nil
+}
+
+// parseScale accepts a relative scale factor such as "0.5", or an absolute
+// one marked with "abs", such as "2 abs".
+func parseScale(raw string, o *spec.StyleOverride) error {
+	mode := spec.ScaleRelative
+	num := strings.ToLower(raw)
+	if n, ok := strings.CutSuffix(num, "abs"); ok {
+		mode, num = spec.ScaleAbsolute, strings.TrimSpace(n)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return errors.New("want a number, optionally followed by abs")
+	}
+	if f <= 0 || (mode == spec.ScaleRelative && f > 1) {
+		return errors.New("must be in (0, 1], or > 0 with abs")
+	}
+	o.Scale = &f
+	o.ScaleMode = &mode
+	return nil
+}
@@ -0,0 +1,200 @@
+package decode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+func TestJSON_Array(t *testing.T) {
+	in := `[
+  {"page": 1, "watermark_text": "CONFIDENTIAL", "opacity": 0.5},
+  {"page": [2, 4], "image": "logo.png", "scale": "0.2"},
+  {"page": "last", "pdf": "letterhead.pdf", "pdf_page": 2, "font": null}
+]`
+	res, err := JSON(context.Background(), strings.NewReader(in), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Instructions) != 3 {
+		t.Fatalf("got %d instructions, want 3", len(res.Instructions))
+	}
+
+	first := res.Instructions[0]
+	if first.Text != "CONFIDENTIAL" || first.Line != 2 {
+		t.Errorf("instruction 0 = %+v, want CONFIDENTIAL on line 2", first)
+	}
+	if got := first.Style.Apply(spec.DefaultStyle()).Opacity; got != 0.5 {
+		t.Errorf("opacity = %g, want 0.5", got)
+	}
+	if got := res.Instructions[1].Pages.Resolve(5); len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		t.Errorf("pages = %v, want [2 4]", got)
+	}
+	if last := res.Instructions[2]; last.PDF != "letterhead.pdf" || last.PDFPage != 2 || last.Line != 4 {
+		t.Errorf("instruction 2 = %+v, want letterhead.pdf page 2 on line 4", last)
+	}
+}
+
+func TestJSON_Lines(t *testing.T) {
+	in := `{"page": 1, "watermark_text": "A"}
+{"page": 2, "watermark_text": "B"}
+
+{"page": 3, "watermark_text": "C"}
+`
+	res, err := JSON(context.Background(), strings.NewReader(in), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var lines []int
+	for _, ins := range res.Instructions {
+		lines = append(lines, ins.Line)
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 2 || lines[2] != 4 {
+		t.Errorf("lines = %v, want [1 2 4]", lines)
+	}
+}
+
+func TestJSON_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax":        `[{"page": 1,]`,
+		"not an object": `[1, 2]`,
+		"nested":        `[{"page": 1, "watermark_text": {"a": 1}}]`,
+		"no text":       `[{"page": 1}]`,
+		"bad style":     `[{"page": 1, "watermark_text": "A", "opacity": 7}]`,
+		"unterminated":  `[{"page": 1, "watermark_text": "A"}`,
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := JSON(context.Background(), strings.NewReader(in), Options{})
+			if !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Errorf("got error %v, want ErrMalformedCSV", err)
+			}
+		})
+	}
+}
+
+func TestJSON_Empty(t *testing.T) {
+	_, err := JSON(context.Background(), strings.NewReader("  \n"), Options{})
+	if !errors.Is(err, errs.ErrEmptyCSV) {
+		t.Errorf("got error %v, want ErrEmptyCSV", err)
+	}
+}
+
+func TestJSON_UnknownField(t *testing.T) {
+	in := `[{"page": 1, "watermark_text": "A", "colour": "red"}, {"page": 2, "watermark_text": "B", "colour": "red"}]`
+	res, err := JSON(context.Background(), strings.NewReader(in), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Warnings) != 1 || !errors.Is(res.Warnings[0], errs.ErrUnknownColumn) {
+		t.Errorf("warnings = %v, want one ErrUnknownColumn", res.Warnings)
+	}
+
+	_, err = JSON(context.Background(), strings.NewReader(in), Options{StrictColumns: true})
+	if !errors.Is(err, errs.ErrMalformedCSV) || !errors.Is(err, errs.ErrUnknownColumn) {
+		t.Errorf("got error %v, want ErrMalformedCSV and ErrUnknownColumn", err)
+	}
+}
+
+func TestJSON_DuplicatePage(t *testing.T) {
+	in := `[{"page": 1, "watermark_text": "A"}, {"page": 1, "watermark_text": "B"}]`
+	_, err := JSON(context.Background(), strings.NewReader(in), Options{})
+	if !errors.Is(err, errs.ErrDuplicatePage) {
+		t.Errorf("got error %v, want ErrDuplicatePage", err)
+	}
+	if _, err := JSON(context.Background(), strings.NewReader(in), Options{Layered: true}); err != nil {
+		t.Errorf("layered: unexpected error: %v", err)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	in := `# watermarks
+- page: 1
+  watermark_text: CONFIDENTIAL
+  color: red
+- page: [2, 3]
+  image: logo.png
+  scale: 2 abs
+- &footer
+  page: last
+  watermark_text: "Page {{page}}"
+  position: bottom-center
+  font_size: 10
+`
+	res, err := YAML(context.Background(), strings.NewReader(in), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Instructions) != 3 {
+		t.Fatalf("got %d instructions, want 3", len(res.Instructions))
+	}
+	if got := res.Instructions[0]; got.Text != "CONFIDENTIAL" || got.Line != 2 {
+		t.Errorf("instruction 0 = %+v, want CONFIDENTIAL on line 2", got)
+	}
+	logo := res.Instructions[1].Style.Apply(spec.DefaultStyle())
+	if logo.Scale != 2 || logo.ScaleMode != spec.ScaleAbsolute {
+		t.Errorf("scale = %g %v, want 2 absolute", logo.Scale, logo.ScaleMode)
+	}
+	if got := res.Instructions[2]; got.Text != "Page {{page}}" || got.Line != 8 {
+		t.Errorf("instruction 2 = %+v, want footer on line 8", got)
+	}
+}
+
+func TestYAML_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax":      "- page: [1\n",
+		"mapping":     "page: 1\nwatermark_text: A\n",
+		"not mapping": "- 1\n- 2\n",
+		"nested":      "- page: 1\n  watermark_text: {a: 1}\n",
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := YAML(context.Background(), strings.NewReader(in), Options{})
+			if !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Errorf("got error %v, want ErrMalformedCSV", err)
+			}
+		})
+	}
+}
+
+func TestYAML_Empty(t *testing.T) {
+	_, err := YAML(context.Background(), strings.NewReader(""), Options{})
+	if !errors.Is(err, errs.ErrEmptyCSV) {
+		t.Errorf("got error %v, want ErrEmptyCSV", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]Format{
+		"page,watermark_text\n1,A\n":        FormatCSV,
+		"\xef\xbb\xbfpage,watermark_text\n": FormatCSV,
+		"\n\n  [{\"page\": 1}]":             FormatJSON,
+		"{\"page\": 1}\n{\"page\": 2}\n":    FormatJSON,
+		"---\n- page: 1\n":                  FormatYAML,
+		"- page: 1\n  watermark_text: A\n":  FormatYAML,
+		"page: 1\n":                         FormatYAML,
+		"# watermarks.yaml\n- page: 1\n":    FormatYAML,
+		"#\n\n# list\n[{\"page\": 1}]":      FormatJSON,
+		"# export\npage,watermark_text\n":   FormatCSV,
+		"page,text: with colon\n":           FormatCSV,
+		"":                                  FormatCSV,
+	}
+	for in, want := range tests {
+		if got := Detect([]byte(in)); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJSON_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := JSON(ctx, strings.NewReader(`[{"page": 1, "watermark_text": "A"}]`), Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,51 @@
+package decode
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/anujkumar-df/pdfmark/internal/xlsx"
+)
+
+// Format names an instruction format.
+type Format string
+
+// Supported formats. The zero Format asks for detection.
+const (
+	FormatAuto Format = ""
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatXLSX Format = "xlsx"
+)
+
+// yamlKey matches a line opening a YAML mapping, such as "page: 1".
+var yamlKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*:(\s|$)`)
+
+// Detect guesses the format of an input from its first bytes. XLSX is a
+// ZIP archive; JSON starts with '[' or '{'; YAML with a document marker, a
+// sequence entry or a "key:" mapping; anything else is taken to be CSV.
+// Blank lines and "#" comment lines are skipped before deciding.
+func Detect(head []byte) Format {
+	if xlsx.IsXLSX(head) {
+		return FormatXLSX
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	for len(head) > 0 {
+		line, rest, _ := bytes.Cut(head, []byte("\n"))
+		head = rest
+		line = bytes.TrimSpace(line)
+		switch {
+		case len(line) == 0 || line[0] == '#':
+			continue
+		case line[0] == '[' || line[0] == '{':
+			return FormatJSON
+		case bytes.HasPrefix(line, []byte("---")),
+			bytes.HasPrefix(line, []byte("- ")), bytes.Equal(line, []byte("-")),
+			yamlKey.Match(line):
+			return FormatYAML
+		}
+		return FormatCSV
+	}
+	return FormatCSV
+}
@@ -0,0 +1,133 @@
+package decode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// JSON reads instructions from r, either a JSON array of objects or
+// newline-delimited JSON with one object per line. Object keys are the
+// field names, and values may be strings, numbers, booleans or null; an
+// array of scalars, such as "page": [1, 3], is joined with commas.
+func JSON(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading instructions: %w", err)
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errs.ErrEmptyCSV
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	// Offsets only grow, so lines are counted on from the last one.
+	var counted int64
+	lines := 1
+	lineAt := func(offset int64) int {
+		// Skip the whitespace and separators before the value.
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+			offset++
+		}
+		lines += bytes.Count(data[counted:offset], []byte("\n"))
+		counted = offset
+		return lines
+	}
+
+	array := trimmed[0] == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrMalformedCSV, err)
+		}
+	}
+
+	b := NewBuilder(opts)
+	for {
+		if array && !dec.More() {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("reading instructions: %w", err)
+		}
+		line := lineAt(dec.InputOffset())
+		var obj map[string]any
+		err := dec.Decode(&obj)
+		if err == io.EOF && !array {
+			break
+		}
+		if err != nil {
+			// The decoder cannot resume after a syntax error.
+			return nil, &errs.InstructionError{Err: errs.ErrMalformedCSV, Line: line, Detail: err.Error()}
+		}
+		if obj == nil {
+			if err := b.Malformed(line, "", "", "want an object"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fields, err := jsonFields(b, obj, line)
+		if err == nil && fields != nil {
+			err = b.Add(Record{Fields: fields, Line: line})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errs.ErrMalformedCSV, err)
+		}
+	}
+	return b.Finish()
+}
+
+// jsonFields converts obj into record fields. It returns nil fields if a
+// value was rejected and the builder is collecting problems.
+func jsonFields(b *Builder, obj map[string]any, line int) (map[string]string, error) {
+	fields := make(map[string]string, len(obj))
+	for _, k := range slices.Sorted(maps.Keys(obj)) {
+		s, err := jsonScalar(obj[k])
+		if err != nil {
+			return nil, b.Malformed(line, k, "", err.Error())
+		}
+		fields[strings.ToLower(k)] = s
+	}
+	return fields, nil
+}
+
+// jsonScalar formats a decoded JSON value as a field value.
+func jsonScalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := jsonScalar(e)
+			if err != nil {
+				return "", err
+			}
+			if _, nested := e.([]any); nested {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("want a string, number, boolean or array")
+}
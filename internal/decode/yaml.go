@@ -0,0 +1,110 @@
+package decode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// YAML reads instructions from r, a YAML sequence of mappings whose keys are
+// the field names:
+//
+//	# watermarks.yaml
+//	- page: 1
+//	  watermark_text: CONFIDENTIAL
+//	- page: 2-last
+//	  image: logo.png
+//	  scale: 0.2
+//
+// Values must be scalars or sequences of scalars, which are joined with
+// commas.
+func YAML(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errs.ErrEmptyCSV
+		}
+		return nil, fmt.Errorf("%w: %v", errs.ErrMalformedCSV, err)
+	}
+	root := resolve(&doc)
+	if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+		root = resolve(root.Content[0])
+	}
+	if root.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w: line %d: want a sequence of instructions", errs.ErrMalformedCSV, root.Line)
+	}
+
+	b := NewBuilder(opts)
+	for _, item := range root.Content {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("reading instructions: %w", err)
+		}
+		item = resolve(item)
+		if item.Kind != yaml.MappingNode {
+			if err := b.Malformed(item.Line, "", "", "want a mapping"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fields, err := yamlFields(b, item)
+		if err == nil && fields != nil {
+			err = b.Add(Record{Fields: fields, Line: item.Line})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// yamlFields converts a mapping node into record fields. It returns nil
+// fields if a value was rejected and the builder is collecting problems.
+func yamlFields(b *Builder, item *yaml.Node) (map[string]string, error) {
+	fields := make(map[string]string, len(item.Content)/2)
+	for i := 0; i+1 < len(item.Content); i += 2 {
+		key, value := resolve(item.Content[i]), resolve(item.Content[i+1])
+		s, err := yamlScalar(value)
+		if err != nil {
+			return nil, b.Malformed(value.Line, key.Value, "", err.Error())
+		}
+		fields[strings.ToLower(key.Value)] = s
+	}
+	return fields, nil
+}
+
+// resolve follows aliases to the node they refer to.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// yamlScalar formats a YAML node as a field value.
+func yamlScalar(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.ShortTag() == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+	case yaml.SequenceNode:
+		parts := make([]string, len(n.Content))
+		for i, e := range n.Content {
+			e = resolve(e)
+			if e.Kind != yaml.ScalarNode {
+				return "", errors.New("nested collections are not supported")
+			}
+			parts[i], _ = yamlScalar(e)
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", errors.New("want a scalar or a sequence")
+}
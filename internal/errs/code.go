@@ -0,0 +1,45 @@
+package errs
+
+import "errors"
+
+// codes names the sentinel errors for clients of the HTTP and gRPC
+// services. The first match wins, so more specific errors come first: the
+// PDF sentinels wrap ErrInvalidPDF, and unknown columns in strict mode also
+// match ErrMalformedCSV.
+var codes = []struct {
+	err  error
+	code string
+}{
+	{ErrEncryptedPDF, "encrypted_pdf"},
+	{ErrUnsupportedPDFVersion, "unsupported_pdf_version"},
+	{ErrTruncatedPDF, "truncated_pdf"},
+	{ErrInvalidPDF, "invalid_pdf"},
+	{ErrInputTooLarge, "input_too_large"},
+	{ErrPageOutOfRange, "page_out_of_range"},
+	{ErrInvalidPage, "invalid_page"},
+	{ErrDuplicatePage, "duplicate_page"},
+	{ErrUnknownColumn, "unknown_column"},
+	{ErrMalformedCSV, "malformed_instructions"},
+	{ErrEmptyCSV, "empty_instructions"},
+	{ErrUnknownFormat, "unknown_format"},
+	{ErrInvalidStyle, "invalid_style"},
+	{ErrInvalidTemplate, "invalid_template"},
+	{ErrAssetNotFound, "asset_not_found"},
+	{ErrInvalidImage, "invalid_image"},
+	{ErrInvalidEncryption, "invalid_encryption"},
+	{ErrInvalidOutputName, "invalid_output_name"},
+	{ErrNoForensicMark, "no_forensic_mark"},
+	{ErrInvalidForensicKey, "invalid_forensic_key"},
+	{ErrNoWatermark, "no_watermark"},
+}
+
+// Code returns a stable snake_case name for the sentinel error err matches,
+// such as "page_out_of_range", or "" if it matches none.
+func Code(err error) string {
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return ""
+}
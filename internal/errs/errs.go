@@ -1,13 +1,36 @@
 // Package errs defines sentinel errors used throughout the pdfmark library.
 package errs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPageOutOfRange     = errors.New("pdfmark: page number out of range")
+	ErrInvalidPage        = errors.New("pdfmark: page number must be >= 1")
+	ErrDuplicatePage      = errors.New("pdfmark: duplicate page number in CSV")
+	ErrMalformedCSV       = errors.New("pdfmark: malformed CSV row")
+	ErrInvalidPDF         = errors.New("pdfmark: invalid or corrupt PDF input")
+	ErrEmptyCSV           = errors.New("pdfmark: CSV contains no header row")
+	ErrInvalidStyle       = errors.New("pdfmark: invalid watermark style")
+	ErrUnknownColumn      = errors.New("pdfmark: unknown CSV column")
+	ErrAssetNotFound      = errors.New("pdfmark: referenced image or PDF not found")
+	ErrInvalidImage       = errors.New("pdfmark: invalid or unsupported image")
+	ErrInvalidTemplate    = errors.New("pdfmark: invalid watermark text template")
+	ErrInvalidOutputName  = errors.New("pdfmark: invalid or duplicate merge output name")
+	ErrInputTooLarge      = errors.New("pdfmark: input PDF exceeds the size limit")
+	ErrUnknownFormat      = errors.New("pdfmark: unknown instruction format")
+	ErrInvalidEncryption  = errors.New("pdfmark: invalid output encryption settings")
+	ErrNoForensicMark     = errors.New("pdfmark: no forensic mark found")
+	ErrInvalidForensicKey = errors.New("pdfmark: forensic mark key missing or too short")
+	ErrNoWatermark        = errors.New("pdfmark: no watermark found")
+)
 
+// Specific reasons a PDF cannot be read. Each wraps ErrInvalidPDF, so
+// errors.Is(err, ErrInvalidPDF) holds for them as well.
 var (
-	ErrPageOutOfRange = errors.New("pdfmark: page number out of range")
-	ErrInvalidPage    = errors.New("pdfmark: page number must be >= 1")
-	ErrDuplicatePage  = errors.New("pdfmark: duplicate page number in CSV")
-	ErrMalformedCSV   = errors.New("pdfmark: malformed CSV row")
-	ErrInvalidPDF     = errors.New("pdfmark: invalid or corrupt PDF input")
-	ErrEmptyCSV       = errors.New("pdfmark: CSV contains no header row")
+	ErrEncryptedPDF          = fmt.Errorf("%w: encrypted, password missing or wrong", ErrInvalidPDF)
+	ErrUnsupportedPDFVersion = fmt.Errorf("%w: unsupported PDF version", ErrInvalidPDF)
+	ErrTruncatedPDF          = fmt.Errorf("%w: file is truncated", ErrInvalidPDF)
 )
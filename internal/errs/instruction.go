@@ -0,0 +1,110 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// InstructionError describes a problem with one instruction. Err is the
+// sentinel the problem matches with errors.Is; the other fields locate it
+// and are zero when not known.
+type InstructionError struct {
+	Line   int    // source line of the instruction
+	Column string // column or field name, such as "opacity"
+	Page   int    // page number involved
+	Value  string // raw value as written in the input
+	Err    error  // sentinel error
+	Detail string // human-readable explanation
+}
+
+func (e *InstructionError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ": line %d", e.Line)
+	}
+	switch {
+	case e.Column != "" && e.Value != "":
+		fmt.Fprintf(&b, ": %s %q", e.Column, e.Value)
+	case e.Column != "":
+		fmt.Fprintf(&b, ": %s", e.Column)
+	case e.Value != "":
+		fmt.Fprintf(&b, ": %q", e.Value)
+	}
+	if e.Page > 0 {
+		fmt.Fprintf(&b, ": page %d", e.Page)
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, ": %s", e.Detail)
+	}
+	return b.String()
+}
+
+func (e *InstructionError) Unwrap() error {
+	return e.Err
+}
+
+// Detailf returns an error matching sentinel, explained by the detail
+// formatted from format and args. Its message is the sentinel's followed by
+// the detail; Wrap takes the two apart again.
+func Detailf(sentinel error, format string, args ...any) error {
+	return &detailError{sentinel: sentinel, detail: fmt.Sprintf(format, args...)}
+}
+
+type detailError struct {
+	sentinel error
+	detail   string
+}
+
+func (e *detailError) Error() string { return e.sentinel.Error() + ": " + e.detail }
+
+func (e *detailError) Unwrap() error { return e.sentinel }
+
+// Wrap returns an InstructionError for err. An error returned by Detailf
+// becomes its sentinel and detail; any other error is kept whole as Err,
+// so everything it wraps still matches errors.Is and errors.As.
+func Wrap(err error) *InstructionError {
+	if d, ok := err.(*detailError); ok {
+		return &InstructionError{Err: d.sentinel, Detail: d.detail}
+	}
+	return &InstructionError{Err: err}
+}
+
+// Problems returns every InstructionError in err's tree, including those
+// combined with errors.Join, in tree order.
+func Problems(err error) []*InstructionError {
+	var out []*InstructionError
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case *InstructionError:
+			out = append(out, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+	return out
+}
+
+// Join combines problems into one error, or returns nil if there are none.
+// A single problem is returned as is.
+func Join(problems []*InstructionError) error {
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return problems[0]
+	}
+	list := make([]error, len(problems))
+	for i, p := range problems {
+		list[i] = p
+	}
+	return errors.Join(list...)
+}
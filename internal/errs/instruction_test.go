@@ -0,0 +1,26 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	p := Wrap(Detailf(ErrInvalidStyle, "opacity must be in (0, 1], got %g", 2.0))
+	if p.Err != ErrInvalidStyle || p.Detail != "opacity must be in (0, 1], got 2" {
+		t.Errorf("Wrap(Detailf) = %+v", p)
+	}
+	p.Line = 3
+	if got, want := p.Error(), "pdfmark: invalid watermark style: line 3: opacity must be in (0, 1], got 2"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	// Other errors keep their whole chain.
+	err := fmt.Errorf("%w: %w", ErrAssetNotFound, fs.ErrNotExist)
+	p = Wrap(err)
+	if !errors.Is(p, ErrAssetNotFound) || !errors.Is(p, fs.ErrNotExist) || p.Detail != "" {
+		t.Errorf("Wrap(%v) = %+v", err, p)
+	}
+}
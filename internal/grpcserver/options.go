@@ -0,0 +1,167 @@
+package grpcserver
+
+import (
+	"bytes"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anujkumar-df/pdfmark"
+	"github.com/anujkumar-df/pdfmark/pdfmarkpb"
+)
+
+// permissions maps the protocol permissions onto pdfmark's.
+var permissions = map[pdfmarkpb.Permission]pdfmark.Permissions{
+	pdfmarkpb.Permission_PERMISSION_PRINT:      pdfmark.PermitPrint,
+	pdfmarkpb.Permission_PERMISSION_COPY:       pdfmark.PermitCopy,
+	pdfmarkpb.Permission_PERMISSION_MODIFY:     pdfmark.PermitModify,
+	pdfmarkpb.Permission_PERMISSION_ANNOTATE:   pdfmark.PermitAnnotate,
+	pdfmarkpb.Permission_PERMISSION_FILL_FORMS: pdfmark.PermitFillForms,
+	pdfmarkpb.Permission_PERMISSION_ASSEMBLE:   pdfmark.PermitAssemble,
+}
+
+// options turns the options message into watermarking options and the
+// instructions reader.
+func (s *Server) options(o *pdfmarkpb.WatermarkOptions) ([]pdfmark.Option, io.Reader, error) {
+	if o == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "pdfmark: the first message must carry the options")
+	}
+	style, err := newStyle(o.GetStyle())
+	if err != nil {
+		return nil, nil, s.status(err)
+	}
+	opts := []pdfmark.Option{
+		pdfmark.WithStyle(style),
+		pdfmark.WithMaxInputSize(s.cfg.MaxInputBytes),
+		pdfmark.WithTemplateVars(o.GetVars()),
+		pdfmark.WithFilename(o.GetFilename()),
+		pdfmark.WithImages(o.GetAssets()),
+		pdfmark.WithPDFStamps(o.GetAssets()),
+		pdfmark.WithForensicKey(s.cfg.ForensicKey),
+	}
+	if f := o.GetFormat(); f != "" && f != "auto" {
+		opts = append(opts, pdfmark.WithFormat(pdfmark.Format(f)))
+	}
+	if p := o.GetPagePolicy(); p != "" {
+		policy, err := pdfmark.ParsePagePolicy(p)
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts = append(opts, pdfmark.WithPagePolicy(policy))
+	}
+	if o.GetLayered() {
+		opts = append(opts, pdfmark.WithLayering())
+	}
+	if o.GetReplace() {
+		opts = append(opts, pdfmark.WithReplace())
+	}
+	if o.GetStrictColumns() {
+		opts = append(opts, pdfmark.WithStrictColumns())
+	}
+	if m := o.GetForensicMark(); m != "" {
+		opts = append(opts, pdfmark.WithForensicMark(m))
+	}
+	if pw := o.GetPasswords(); pw != nil {
+		opts = append(opts, pdfmark.WithPasswords(pw.GetUser(), pw.GetOwner()))
+	}
+	if e := o.GetEncryption(); e != nil {
+		enc := pdfmark.Encryption{
+			UserPassword:  e.GetUserPassword(),
+			OwnerPassword: e.GetOwnerPassword(),
+			KeyLength:     int(e.GetKeyLength()),
+		}
+		for _, p := range e.GetPermissions() {
+			perm, ok := permissions[p]
+			if !ok {
+				return nil, nil, status.Errorf(codes.InvalidArgument, "pdfmark: unknown permission %v", p)
+			}
+			enc.Permissions |= perm
+		}
+		opts = append(opts, pdfmark.WithEncryption(enc))
+	}
+	return opts, bytes.NewReader(o.GetInstructions()), nil
+}
+
+// newStyle applies the fields set in m to the default style.
+func newStyle(m *pdfmarkpb.Style) (pdfmark.Style, error) {
+	style := pdfmark.DefaultStyle()
+	if m == nil {
+		return style, nil
+	}
+	if m.Font != nil {
+		style.FontName = m.GetFont()
+	}
+	if m.Size != nil {
+		style.FontSize = int(m.GetSize())
+	}
+	if m.Color != nil {
+		c, err := pdfmark.ParseColor(m.GetColor())
+		if err != nil {
+			return style, err
+		}
+		style.Color = c
+	}
+	if m.Opacity != nil {
+		style.Opacity = m.GetOpacity()
+	}
+	if m.Rotation != nil {
+		style.Diagonal = pdfmark.NoDiagonal
+		style.Rotation = m.GetRotation()
+	}
+	if m.Position != nil {
+		p, err := pdfmark.ParsePosition(m.GetPosition())
+		if err != nil {
+			return style, err
+		}
+		style.Position = p
+	}
+	if m.Underlay != nil {
+		style.OnTop = !m.GetUnderlay()
+	}
+	if m.TileGapX != nil {
+		style.Tile.GapX = m.GetTileGapX()
+	}
+	if m.TileGapY != nil {
+		style.Tile.GapY = m.GetTileGapY()
+	}
+	if m.TileMargin != nil {
+		style.Tile.Margin = m.GetTileMargin()
+	}
+	if m.TileStagger != nil {
+		style.Tile.Stagger = m.GetTileStagger()
+	}
+	if m.Margin != nil {
+		style.Margin = m.GetMargin()
+	}
+	return style, nil
+}
+
+// styleMessage describes the effective style s.
+func styleMessage(s pdfmark.Style) *pdfmarkpb.Style {
+	m := &pdfmarkpb.Style{
+		Font:     &s.FontName,
+		Size:     new(int32),
+		Color:    new(string),
+		Opacity:  &s.Opacity,
+		Position: new(string),
+		Underlay: new(bool),
+	}
+	*m.Size = int32(s.FontSize)
+	*m.Color = s.Color.String()
+	*m.Position = s.Position.String()
+	*m.Underlay = !s.OnTop
+	if s.Diagonal == pdfmark.NoDiagonal {
+		m.Rotation = &s.Rotation
+	}
+	if s.Position == pdfmark.Tiled {
+		m.TileGapX = &s.Tile.GapX
+		m.TileGapY = &s.Tile.GapY
+		m.TileMargin = &s.Tile.Margin
+		m.TileStagger = &s.Tile.Stagger
+	}
+	if s.Position.IsPreset() {
+		m.Margin = &s.Margin
+	}
+	return m
+}
@@ -0,0 +1,278 @@
+// Package grpcserver implements the gRPC WatermarkService defined in
+// pdfmarkpb on top of the pdfmark package, for "pdfmark serve -grpc-addr".
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"runtime"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/anujkumar-df/pdfmark"
+	"github.com/anujkumar-df/pdfmark/pdfmarkpb"
+)
+
+// Config configures a Server. Zero fields take the defaults noted.
+type Config struct {
+	// MaxInputBytes bounds the size of a PDF. It defaults to 64 MiB.
+	MaxInputBytes int64
+	// MaxConcurrent bounds the calls processed at once. Further calls wait
+	// for a slot until their timeout. It defaults to the number of CPUs.
+	MaxConcurrent int
+	// Timeout bounds the time a call may take, including waiting for a
+	// slot, unless the client's deadline is earlier. It defaults to one
+	// minute.
+	Timeout time.Duration
+	// ErrorLog receives server-side failures; nil uses the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+	// ForensicKey seals the forensic marks requested by clients and opens
+	// them for inspection; see pdfmark.WithForensicKey. Without it, requests
+	// for a forensic mark fail.
+	ForensicKey []byte
+}
+
+// Server implements pdfmarkpb.WatermarkServiceServer. It is safe for
+// concurrent use.
+type Server struct {
+	pdfmarkpb.UnimplementedWatermarkServiceServer
+	cfg   Config
+	slots chan struct{}
+}
+
+// New returns a Server configured by cfg. Register it with
+// pdfmarkpb.RegisterWatermarkServiceServer.
+func New(cfg Config) *Server {
+	if cfg.MaxInputBytes <= 0 {
+		cfg.MaxInputBytes = 64 << 20
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = runtime.NumCPU()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.ErrorLog == nil {
+		cfg.ErrorLog = log.Default()
+	}
+	return &Server{cfg: cfg, slots: make(chan struct{}, cfg.MaxConcurrent)}
+}
+
+// begin applies the timeout and waits for a free slot. The returned
+// function releases both. Handlers receive under the returned context, see
+// recvUntil, so a stalled client cannot hold the slot past the timeout.
+func (s *Server) begin(ctx context.Context) (context.Context, func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	select {
+	case s.slots <- struct{}{}:
+		return ctx, func() { <-s.slots; cancel() }, nil
+	case <-ctx.Done():
+		cancel()
+		return nil, nil, status.Error(codes.ResourceExhausted, "pdfmark: no free slot before the deadline")
+	}
+}
+
+// Watermark implements pdfmarkpb.WatermarkServiceServer.
+func (s *Server) Watermark(stream grpc.BidiStreamingServer[pdfmarkpb.WatermarkRequest, pdfmarkpb.WatermarkResponse]) error {
+	ctx, done, err := s.begin(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	recv := recvUntil(ctx, stream.Recv)
+	first, err := recv()
+	if err != nil {
+		return s.status(err)
+	}
+	opts, instructions, err := s.options(first.GetOptions())
+	if err != nil {
+		return err
+	}
+	src := pdfChunks(recv)
+	dst := &chunkWriter{send: func(b []byte) error {
+		return stream.Send(&pdfmarkpb.WatermarkResponse{Pdf: b})
+	}}
+	if err := pdfmark.WatermarkWithOptions(ctx, dst, src, instructions, opts...); err != nil {
+		return s.status(err)
+	}
+	return s.status(dst.flush())
+}
+
+// Plan implements pdfmarkpb.WatermarkServiceServer.
+func (s *Server) Plan(stream grpc.ClientStreamingServer[pdfmarkpb.PlanRequest, pdfmarkpb.PlanResponse]) error {
+	ctx, done, err := s.begin(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	recv := recvUntil(ctx, stream.Recv)
+	first, err := recv()
+	if err != nil {
+		return s.status(err)
+	}
+	opts, instructions, err := s.options(first.GetOptions())
+	if err != nil {
+		return err
+	}
+	src := pdfChunks(recv)
+	plan, err := pdfmark.Plan(ctx, src, instructions, opts...)
+	resp, err := planResponse(plan, err)
+	if err != nil {
+		return s.status(err)
+	}
+	return stream.SendAndClose(resp)
+}
+
+// Inspect implements pdfmarkpb.WatermarkServiceServer.
+func (s *Server) Inspect(stream grpc.ClientStreamingServer[pdfmarkpb.InspectRequest, pdfmarkpb.InspectResponse]) error {
+	ctx, done, err := s.begin(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	recv := recvUntil(ctx, stream.Recv)
+	first, err := recv()
+	if err != nil {
+		return s.status(err)
+	}
+	opts := first.GetOptions()
+	if opts == nil {
+		return status.Error(codes.InvalidArgument, "pdfmark: the first message must carry the options")
+	}
+	report, err := pdfmark.Inspect(pdfChunks(recv),
+		pdfmark.WithPasswords(opts.GetPasswords().GetUser(), opts.GetPasswords().GetOwner()),
+		pdfmark.WithMaxInputSize(s.cfg.MaxInputBytes),
+		pdfmark.WithForensicKey(s.cfg.ForensicKey))
+	if err != nil {
+		return s.status(err)
+	}
+	resp := &pdfmarkpb.InspectResponse{
+		PdfVersion:  report.Version,
+		Encrypted:   report.Encrypted,
+		Watermarked: report.Watermarked,
+		TotalPages:  int32(len(report.Pages)),
+	}
+	for _, p := range report.Pages {
+		page := &pdfmarkpb.PageInfo{Width: p.Width, Height: p.Height, ForensicMark: p.ForensicMark}
+		for _, w := range p.Watermarks {
+			page.Watermarks = append(page.Watermarks, &pdfmarkpb.DetectedWatermark{
+				Kind:     string(w.Kind),
+				Text:     w.Text,
+				Font:     w.Font,
+				FontSize: w.FontSize,
+				Color:    w.Color.String(),
+				Opacity:  w.Opacity,
+				Rotation: w.Rotation,
+				Underlay: !w.OnTop,
+			})
+		}
+		resp.Pages = append(resp.Pages, page)
+	}
+	return stream.SendAndClose(resp)
+}
+
+// recvUntil returns a function that receives the next message with recv
+// and gives up with ctx's error once ctx is done. A stalled recv is left
+// to return when gRPC cancels the stream, after the handler returns; no
+// further recv starts in the meantime.
+func recvUntil[M any](ctx context.Context, recv func() (M, error)) func() (M, error) {
+	type result struct {
+		m   M
+		err error
+	}
+	return func() (M, error) {
+		var zero M
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		ch := make(chan result, 1)
+		go func() {
+			m, err := recv()
+			ch <- result{m, err}
+		}()
+		select {
+		case r := <-ch:
+			return r.m, r.err
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// pdfChunks reads the PDF chunks sent after the options message with recv.
+func pdfChunks[M interface {
+	proto.Message
+	GetPdf() []byte
+}](recv func() (M, error)) *chunkReader {
+	return &chunkReader{recv: func() ([]byte, error) {
+		m, err := recv()
+		if err != nil {
+			return nil, err
+		}
+		r := m.ProtoReflect()
+		if f := r.WhichOneof(r.Descriptor().Oneofs().ByName("payload")); f == nil || f.Name() != "pdf" {
+			return nil, status.Error(codes.InvalidArgument, "pdfmark: every message after the options must carry a PDF chunk")
+		}
+		return m.GetPdf(), nil
+	}}
+}
+
+// chunkReader reads the PDF chunks of a request stream.
+type chunkReader struct {
+	recv func() ([]byte, error)
+	buf  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		b, err := r.recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = b
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// chunkWriter sends output in chunks of pdfmarkpb.ChunkSize.
+type chunkWriter struct {
+	send func([]byte) error
+	buf  bytes.Buffer
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for w.buf.Len() >= pdfmarkpb.ChunkSize {
+		// Each message gets its own slice, as sent messages must not change.
+		chunk := make([]byte, pdfmarkpb.ChunkSize)
+		w.buf.Read(chunk)
+		if err := w.send(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush sends what is left.
+func (w *chunkWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	return w.send(bytes.Clone(w.buf.Bytes()))
+}
+
+func (w *chunkWriter) Close() error { return nil }
+
+var _ io.WriteCloser = (*chunkWriter)(nil)
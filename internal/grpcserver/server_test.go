@@ -0,0 +1,280 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+	"github.com/anujkumar-df/pdfmark/pdfmarkpb"
+)
+
+// newClient serves s on an in-process listener and returns a client for it.
+func newClient(t *testing.T, cfg Config) pdfmarkpb.WatermarkServiceClient {
+	t.Helper()
+	cfg.ErrorLog = log.New(io.Discard, "", 0)
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pdfmarkpb.RegisterWatermarkServiceServer(srv, New(cfg))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pdfmarkpb.NewWatermarkServiceClient(conn)
+}
+
+// errorInfo returns the status code and ErrorInfo reason of err.
+func errorInfo(t *testing.T, err error) (codes.Code, string, *errdetails.BadRequest) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("error %v is not a status", err)
+	}
+	var reason string
+	var br *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.Reason
+		case *errdetails.BadRequest:
+			br = d
+		}
+	}
+	return st.Code(), reason, br
+}
+
+// noisePNG encodes a w x h PNG of random pixels, which does not compress.
+func noisePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		img.Pix[i] = byte(r.Uint32())
+		if i%4 == 3 {
+			img.Pix[i] = 0xff
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWatermark(t *testing.T) {
+	c := newClient(t, Config{})
+	pdf := testutil.CreateTestPDF(t, 3)
+
+	// The image makes the output span several chunks; stamping it again
+	// sends an input of several chunks.
+	var out bytes.Buffer
+	err := pdfmarkpb.Watermark(context.Background(), c, &out, bytes.NewReader(pdf), &pdfmarkpb.WatermarkOptions{
+		Instructions: []byte("page,image\n1,noise\n"),
+		Assets:       map[string][]byte{"noise": noisePNG(t, 256, 256)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() <= 2*pdfmarkpb.ChunkSize {
+		t.Fatalf("output is %d bytes; want several chunks", out.Len())
+	}
+	testutil.AssertPageCount(t, out.Bytes(), 3)
+
+	var again bytes.Buffer
+	err = pdfmarkpb.Watermark(context.Background(), c, &again, bytes.NewReader(out.Bytes()), &pdfmarkpb.WatermarkOptions{
+		Instructions: []byte("page,watermark_text\n2,CONFIDENTIAL\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertValidPDF(t, again.Bytes())
+	testutil.AssertPageCount(t, again.Bytes(), 3)
+}
+
+func TestWatermark_Encrypted(t *testing.T) {
+	c := newClient(t, Config{})
+	pdf := testutil.EncryptPDF(t, testutil.CreateTestPDF(t, 2), "secret", "owner")
+	opts := &pdfmarkpb.WatermarkOptions{Instructions: []byte("page,watermark_text\n1,X\n")}
+
+	err := pdfmarkpb.Watermark(context.Background(), c, io.Discard, bytes.NewReader(pdf), opts)
+	if code, reason, _ := errorInfo(t, err); code != codes.InvalidArgument || reason != "ENCRYPTED_PDF" {
+		t.Errorf("got %v %q, want InvalidArgument ENCRYPTED_PDF", code, reason)
+	}
+
+	opts.Passwords = &pdfmarkpb.Passwords{User: "secret", Owner: "owner"}
+	var out bytes.Buffer
+	if err := pdfmarkpb.Watermark(context.Background(), c, &out, bytes.NewReader(pdf), opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := testutil.PDFPermissions(t, out.Bytes(), "secret", "owner"); !ok {
+		t.Error("output is not encrypted, want the input's encryption kept")
+	}
+}
+
+func TestWatermark_Errors(t *testing.T) {
+	c := newClient(t, Config{MaxInputBytes: 1 << 10})
+	pdf := testutil.CreateTestPDF(t, 2)
+
+	tests := []struct {
+		name     string
+		pdf      []byte
+		opts     *pdfmarkpb.WatermarkOptions
+		code     codes.Code
+		reason   string
+		problems int
+	}{
+		{"out of range", pdf, &pdfmarkpb.WatermarkOptions{
+			Instructions: []byte("page,watermark_text\n5,X\n"),
+		}, codes.OutOfRange, "PAGE_OUT_OF_RANGE", 1},
+		{"too large", bytes.Repeat([]byte("x"), 4<<10), &pdfmarkpb.WatermarkOptions{
+			Instructions: []byte("page,watermark_text\n1,X\n"),
+		}, codes.ResourceExhausted, "INPUT_TOO_LARGE", 0},
+		{"bad style", pdf, &pdfmarkpb.WatermarkOptions{
+			Instructions: []byte("page,watermark_text\n1,X\n"),
+			Style:        &pdfmarkpb.Style{Color: new(string)},
+		}, codes.InvalidArgument, "INVALID_STYLE", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pdfmarkpb.Watermark(context.Background(), c, io.Discard, bytes.NewReader(tt.pdf), tt.opts)
+			code, reason, br := errorInfo(t, err)
+			if code != tt.code || reason != tt.reason {
+				t.Errorf("got %v %q, want %v %q (%v)", code, reason, tt.code, tt.reason, err)
+			}
+			if got := len(br.GetFieldViolations()); got != tt.problems {
+				t.Errorf("%d field violations, want %d", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	c := newClient(t, Config{})
+	pdf := testutil.CreateTestPDF(t, 3)
+
+	resp, err := pdfmarkpb.Plan(context.Background(), c, bytes.NewReader(pdf), &pdfmarkpb.WatermarkOptions{
+		Instructions: []byte("page,watermark_text,color\n1,A,red\n3,B,\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TotalPages != 3 || len(resp.Pages) != 2 || len(resp.Problems) != 0 {
+		t.Fatalf("got %v", resp)
+	}
+	st := resp.Pages[0].Stamps[0]
+	if resp.Pages[0].Page != 1 || st.Text != "A" || st.Style.GetColor() == resp.Pages[1].Stamps[0].Style.GetColor() {
+		t.Errorf("page 1 plan = %v", resp.Pages[0])
+	}
+
+	resp, err = pdfmarkpb.Plan(context.Background(), c, bytes.NewReader(pdf), &pdfmarkpb.WatermarkOptions{
+		Instructions: []byte("page,watermark_text\n0,A\n9,B\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Problems) != 2 || len(resp.Pages) != 0 {
+		t.Fatalf("got %v, want two problems", resp)
+	}
+	if got := resp.Problems[1]; got.Line != 3 || got.Reason != "PAGE_OUT_OF_RANGE" {
+		t.Errorf("second problem = %v", got)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	c := newClient(t, Config{})
+	pdf := testutil.EncryptPDF(t, testutil.CreateTestPDF(t, 2), "secret", "owner")
+
+	_, err := pdfmarkpb.Inspect(context.Background(), c, bytes.NewReader(pdf), &pdfmarkpb.InspectOptions{})
+	if _, reason, _ := errorInfo(t, err); reason != "ENCRYPTED_PDF" {
+		t.Errorf("reason %q, want ENCRYPTED_PDF", reason)
+	}
+
+	resp, err := pdfmarkpb.Inspect(context.Background(), c, bytes.NewReader(pdf), &pdfmarkpb.InspectOptions{
+		Passwords: &pdfmarkpb.Passwords{User: "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Encrypted || resp.TotalPages != 2 || len(resp.Pages) != 2 || resp.PdfVersion == "" {
+		t.Errorf("got %v", resp)
+	}
+	if p := resp.Pages[0]; p.Width <= 0 || p.Height <= 0 {
+		t.Errorf("page size %v", p)
+	}
+}
+
+func TestInspect_Watermarks(t *testing.T) {
+	c := newClient(t, Config{ForensicKey: []byte("0123456789abcdef")})
+	var pdf bytes.Buffer
+	err := pdfmarkpb.Watermark(context.Background(), c, &pdf, bytes.NewReader(testutil.CreateTestPDF(t, 2)), &pdfmarkpb.WatermarkOptions{
+		Instructions: []byte("page,watermark_text\n2,DRAFT\n"),
+		ForensicMark: "jane@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := pdfmarkpb.Inspect(context.Background(), c, bytes.NewReader(pdf.Bytes()), &pdfmarkpb.InspectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Watermarked || len(resp.Pages[0].Watermarks) != 0 {
+		t.Errorf("got %v", resp)
+	}
+	if w := resp.Pages[1].Watermarks; len(w) != 1 || w[0].Kind != "text" || w[0].Text != "DRAFT" || w[0].Underlay {
+		t.Errorf("page 2 watermarks = %v", w)
+	}
+	if m := resp.Pages[1].ForensicMark; m != "jane@example.com" {
+		t.Errorf("forensic mark = %q", m)
+	}
+}
+
+func TestTimeout_StalledClient(t *testing.T) {
+	c := newClient(t, Config{MaxConcurrent: 1, Timeout: 100 * time.Millisecond})
+
+	// The client sends the options and part of a PDF, then stalls without
+	// closing its side of the stream.
+	stream, err := c.Inspect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*pdfmarkpb.InspectRequest{
+		{Payload: &pdfmarkpb.InspectRequest_Options{Options: &pdfmarkpb.InspectOptions{}}},
+		{Payload: &pdfmarkpb.InspectRequest_Pdf{Pdf: []byte("%PDF-1.7\n")}},
+	} {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.RecvMsg(new(pdfmarkpb.InspectResponse)); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("stalled call: got %v, want DeadlineExceeded", err)
+	}
+
+	// The stalled call gave its slot back.
+	_, err = pdfmarkpb.Inspect(context.Background(), c, bytes.NewReader(testutil.CreateTestPDF(t, 1)), &pdfmarkpb.InspectOptions{})
+	if err != nil {
+		t.Errorf("next call: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/anujkumar-df/pdfmark"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/pdfmarkpb"
+)
+
+// errorDomain is the ErrorInfo domain of pdfmark's reasons.
+const errorDomain = "pdfmark"
+
+// grpcCodes maps the codes of the sentinel errors onto gRPC codes. Codes
+// not listed are answered with InvalidArgument: the request is at fault.
+var grpcCodes = map[string]codes.Code{
+	"input_too_large":   codes.ResourceExhausted,
+	"page_out_of_range": codes.OutOfRange,
+}
+
+// reason returns the ErrorInfo reason for err, such as
+// "PAGE_OUT_OF_RANGE", or "" if it matches no sentinel error.
+func reason(err error) string {
+	return strings.ToUpper(errs.Code(err))
+}
+
+// status converts err into a gRPC status error. The ErrorInfo detail names
+// the sentinel error and a BadRequest detail lists the invalid
+// instructions. Server-side failures are logged and their details withheld.
+func (s *Server) status(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+	code := errs.Code(err)
+	switch {
+	case code != "":
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		s.cfg.ErrorLog.Printf("pdfmark grpc: %v", err)
+		return status.Error(codes.Internal, "pdfmark: internal error")
+	}
+
+	c, ok := grpcCodes[code]
+	if !ok {
+		c = codes.InvalidArgument
+	}
+	st := status.New(c, err.Error())
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason(err), Domain: errorDomain}}
+	if problems := pdfmark.Problems(err); len(problems) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, p := range problems {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field(p),
+				Description: p.Error(),
+				Reason:      reason(p),
+			})
+		}
+		details = append(details, br)
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// field locates an invalid instruction as "instructions[line].column".
+func field(p *pdfmark.InstructionError) string {
+	f := "instructions"
+	if p.Line > 0 {
+		f += fmt.Sprintf("[%d]", p.Line)
+	}
+	if p.Column != "" {
+		f += "." + p.Column
+	}
+	return f
+}
+
+// problem describes an invalid or skipped instruction.
+func problem(p *pdfmark.InstructionError) *pdfmarkpb.Problem {
+	return &pdfmarkpb.Problem{
+		Line:    int32(p.Line),
+		Column:  p.Column,
+		Page:    int32(p.Page),
+		Value:   p.Value,
+		Reason:  reason(p),
+		Message: p.Error(),
+	}
+}
+
+// planResponse converts the result of pdfmark.Plan. Invalid instructions
+// are listed in the response; other errors are returned.
+func planResponse(plan *pdfmark.StampPlan, err error) (*pdfmarkpb.PlanResponse, error) {
+	resp := &pdfmarkpb.PlanResponse{}
+	var report *pdfmark.ValidationReport
+	if errors.As(err, &report) {
+		for _, p := range report.Problems {
+			resp.Problems = append(resp.Problems, problem(p))
+		}
+		for _, w := range report.Warnings {
+			resp.Warnings = append(resp.Warnings, w.Error())
+		}
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.TotalPages = int32(plan.TotalPages)
+	for _, w := range plan.Warnings {
+		resp.Warnings = append(resp.Warnings, w.Error())
+	}
+	for _, p := range plan.Skipped {
+		resp.Skipped = append(resp.Skipped, problem(p))
+	}
+	for _, pp := range plan.Pages {
+		page := &pdfmarkpb.PagePlan{Page: int32(pp.Page)}
+		for _, st := range pp.Stamps {
+			page.Stamps = append(page.Stamps, &pdfmarkpb.PlannedStamp{
+				Line:    int32(st.Line),
+				Text:    st.Text,
+				Image:   st.Image,
+				Pdf:     st.PDF,
+				PdfPage: int32(st.PDFPage),
+				Style:   styleMessage(st.Style),
+			})
+		}
+		resp.Pages = append(resp.Pages, page)
+	}
+	return resp, nil
+}
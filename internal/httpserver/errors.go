@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/anujkumar-df/pdfmark"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// errorBody is the JSON answer to a failed request.
+type errorBody struct {
+	// Code names the kind of failure, such as "page_out_of_range", for
+	// clients to act on; Error is the message for humans.
+	Code     string    `json:"code"`
+	Error    string    `json:"error"`
+	Problems []problem `json:"problems,omitempty"`
+}
+
+// problem locates one invalid instruction.
+type problem struct {
+	Line   int    `json:"line,omitempty"`
+	Column string `json:"column,omitempty"`
+	Page   int    `json:"page,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Error  string `json:"error"`
+}
+
+// statuses maps the codes of the sentinel errors onto a status. Codes not
+// listed are answered with 400 Bad Request: the upload is at fault.
+var statuses = map[string]int{
+	"encrypted_pdf":           http.StatusUnprocessableEntity,
+	"unsupported_pdf_version": http.StatusUnprocessableEntity,
+	"truncated_pdf":           http.StatusUnprocessableEntity,
+	"invalid_pdf":             http.StatusUnprocessableEntity,
+	"input_too_large":         http.StatusRequestEntityTooLarge,
+	"page_out_of_range":       http.StatusUnprocessableEntity,
+	"invalid_image":           http.StatusUnprocessableEntity,
+}
+
+// classify returns the status and code answering err.
+func classify(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, "request_too_large"
+	case isRequestError(err):
+		return http.StatusBadRequest, "bad_request"
+	}
+	if code := errs.Code(err); code != "" {
+		if status, ok := statuses[code]; ok {
+			return status, code
+		}
+		return http.StatusBadRequest, code
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, "timeout"
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "canceled"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+// fail answers the request with err. Server-side failures are logged and
+// their details withheld.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := classify(err)
+	body := errorBody{Code: code, Error: err.Error()}
+	if status == http.StatusInternalServerError {
+		s.cfg.ErrorLog.Printf("pdfmark serve: %s %s: %v", r.Method, r.URL.Path, err)
+		body.Error = http.StatusText(status)
+	}
+	for _, p := range pdfmark.Problems(err) {
+		body.Problems = append(body.Problems, problem{
+			Line:   p.Line,
+			Column: p.Column,
+			Page:   p.Page,
+			Value:  p.Value,
+			Error:  p.Error(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(body)
+}
@@ -0,0 +1,262 @@
+package httpserver
+
+import (
+	"io"
+	"mime/multipart"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// formOptions builds the watermarking options from the fields of f:
+//
+//	format                  instruction format: auto, csv, xlsx, json or yaml
+//	font, size, color       watermark font, size in points and color
+//	opacity, rotation       opacity in (0, 1] and rotation in degrees
+//	position                anchor such as center or top-left, a header,
+//	                        footer or margin preset, or tiled
+//	margin                  distance of a preset from the page edge
+//	underlay                "true" draws beneath the page content
+//	tile_gap_x, tile_gap_y  space between tiles with position tiled
+//	tile_stagger            "true" shifts every other row of tiles
+//	tile_margin             space kept free along the page edges by tiles
+//	page_policy             strict, skip or clamp
+//	layer                   "true" stacks several instructions per page
+//	replace                 "true" removes existing watermarks first
+//	strict_columns          "true" rejects unknown columns
+//	var                     template variable name=value, repeatable
+//	filename                value of {{filename}}; defaults to the PDF's name
+//	forensic_mark           ID hidden in the output for pdfmark.Trace,
+//	                        sealed under the server's forensic key
+//	password                user password of an encrypted PDF
+//	owner_password          owner password of an encrypted PDF
+//	encrypt_password        encrypts the output with this user password
+//	encrypt_owner_password  encrypts the output with this owner password
+//	encrypt_key             AES key length of the output: 128 or 256
+//	permissions             comma-separated permissions of the encrypted
+//	                        output: print, copy, modify, annotate, fill-forms,
+//	                        assemble, all or none (default: all)
+func formOptions(f *multipart.Form) ([]pdfmark.Option, error) {
+	v := formValues(f.Value)
+	var opts []pdfmark.Option
+
+	style := pdfmark.DefaultStyle()
+	if s := v.get("font"); s != "" {
+		style.FontName = s
+	}
+	if s := v.get("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, badRequest(err, "parsing size")
+		}
+		style.FontSize = n
+	}
+	if s := v.get("color"); s != "" {
+		c, err := pdfmark.ParseColor(s)
+		if err != nil {
+			return nil, err
+		}
+		style.Color = c
+	}
+	if s := v.get("opacity"); s != "" {
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, badRequest(err, "parsing opacity")
+		}
+		style.Opacity = x
+	}
+	if s := v.get("rotation"); s != "" {
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, badRequest(err, "parsing rotation")
+		}
+		style.Diagonal = pdfmark.NoDiagonal
+		style.Rotation = x
+	}
+	if s := v.get("position"); s != "" {
+		p, err := pdfmark.ParsePosition(s)
+		if err != nil {
+			return nil, err
+		}
+		style.Position = p
+	}
+	underlay, err := v.bool("underlay")
+	if err != nil {
+		return nil, err
+	}
+	style.OnTop = !underlay
+	for _, f := range []struct {
+		name  string
+		field *float64
+	}{
+		{"tile_gap_x", &style.Tile.GapX},
+		{"tile_gap_y", &style.Tile.GapY},
+		{"tile_margin", &style.Tile.Margin},
+		{"margin", &style.Margin},
+	} {
+		if s := v.get(f.name); s != "" {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, badRequest(err, "parsing %s", f.name)
+			}
+			*f.field = x
+		}
+	}
+	if style.Tile.Stagger, err = v.bool("tile_stagger"); err != nil {
+		return nil, err
+	}
+	opts = append(opts, pdfmark.WithStyle(style))
+
+	if s := v.get("format"); s != "" && s != "auto" {
+		opts = append(opts, pdfmark.WithFormat(pdfmark.Format(s)))
+	}
+	if s := v.get("page_policy"); s != "" {
+		p, err := pdfmark.ParsePagePolicy(s)
+		if err != nil {
+			return nil, badRequest(err, "parsing page_policy")
+		}
+		opts = append(opts, pdfmark.WithPagePolicy(p))
+	}
+	if ok, err := v.bool("layer"); err != nil {
+		return nil, err
+	} else if ok {
+		opts = append(opts, pdfmark.WithLayering())
+	}
+	if ok, err := v.bool("replace"); err != nil {
+		return nil, err
+	} else if ok {
+		opts = append(opts, pdfmark.WithReplace())
+	}
+	if ok, err := v.bool("strict_columns"); err != nil {
+		return nil, err
+	} else if ok {
+		opts = append(opts, pdfmark.WithStrictColumns())
+	}
+
+	vars := make(map[string]string)
+	for _, s := range f.Value["var"] {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || name == "" {
+			return nil, badRequest(nil, "parsing var: want name=value, got %q", s)
+		}
+		vars[name] = value
+	}
+	opts = append(opts, pdfmark.WithTemplateVars(vars))
+	filename := v.get("filename")
+	if filename == "" && len(f.File["pdf"]) > 0 {
+		filename = path.Base(f.File["pdf"][0].Filename)
+	}
+	opts = append(opts, pdfmark.WithFilename(filename))
+	if s := v.get("forensic_mark"); s != "" {
+		opts = append(opts, pdfmark.WithForensicMark(s))
+	}
+
+	if user, owner := v.get("password"), v.get("owner_password"); user != "" || owner != "" {
+		opts = append(opts, pdfmark.WithPasswords(user, owner))
+	}
+	enc, ok, err := encryption(v)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts = append(opts, pdfmark.WithEncryption(enc))
+	}
+
+	assets, err := formAssets(f)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, pdfmark.WithImages(assets), pdfmark.WithPDFStamps(assets))
+	return opts, nil
+}
+
+// permissionNames maps the names accepted by the permissions field.
+var permissionNames = map[string]pdfmark.Permissions{
+	"print":      pdfmark.PermitPrint,
+	"copy":       pdfmark.PermitCopy,
+	"modify":     pdfmark.PermitModify,
+	"annotate":   pdfmark.PermitAnnotate,
+	"fill-forms": pdfmark.PermitFillForms,
+	"assemble":   pdfmark.PermitAssemble,
+	"all":        pdfmark.PermitAll,
+	"none":       0,
+}
+
+// encryption builds the output encryption from the encrypt_* and
+// permissions fields. It reports false if none is set.
+func encryption(v formValues) (pdfmark.Encryption, bool, error) {
+	e := pdfmark.Encryption{
+		UserPassword:  v.get("encrypt_password"),
+		OwnerPassword: v.get("encrypt_owner_password"),
+		Permissions:   pdfmark.PermitAll,
+	}
+	set := e.UserPassword != "" || e.OwnerPassword != ""
+	if s := v.get("encrypt_key"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return e, false, badRequest(err, "parsing encrypt_key")
+		}
+		e.KeyLength = n
+		set = true
+	}
+	if s := v.get("permissions"); s != "" {
+		e.Permissions = 0
+		for _, name := range strings.Split(s, ",") {
+			p, ok := permissionNames[strings.TrimSpace(name)]
+			if !ok {
+				return e, false, badRequest(nil, "parsing permissions: unknown permission %q", name)
+			}
+			e.Permissions |= p
+		}
+		set = true
+	}
+	return e, set, nil
+}
+
+// formAssets reads the file parts other than the PDF and instructions,
+// keyed by file name.
+func formAssets(f *multipart.Form) (map[string][]byte, error) {
+	assets := make(map[string][]byte)
+	for name, files := range f.File {
+		if name == "pdf" || name == "instructions" {
+			continue
+		}
+		for _, fh := range files {
+			file, err := fh.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			assets[fh.Filename] = data
+		}
+	}
+	return assets, nil
+}
+
+// formValues gives access to single-valued form fields.
+type formValues map[string][]string
+
+func (v formValues) get(name string) string {
+	if len(v[name]) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(v[name][0])
+}
+
+func (v formValues) bool(name string) (bool, error) {
+	s := v.get(name)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, badRequest(err, "parsing %s", name)
+	}
+	return b, nil
+}
@@ -0,0 +1,257 @@
+// Package httpserver exposes watermarking over HTTP for "pdfmark serve".
+//
+// POST /v1/watermark takes a multipart/form-data upload with the PDF in the
+// "pdf" part and the instructions in the "instructions" part, either a file
+// or a plain field, plus optional style and option fields; see formOptions.
+// Any other file parts are made available to the instructions as images
+// and stamp PDFs under their file names. The watermarked PDF is streamed
+// back as application/pdf. Failures are answered with a JSON errorBody and
+// a 4xx status derived from the pdfmark sentinel errors.
+//
+// GET /healthz reports that the process is up; GET /readyz reports whether
+// it accepts work, failing once Drain has been called.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark"
+)
+
+// Config configures a Server. Zero fields take the defaults noted.
+type Config struct {
+	// MaxRequestBytes bounds the size of an upload, PDF, instructions and
+	// assets together. It defaults to 64 MiB.
+	MaxRequestBytes int64
+	// MaxMemory is how much of an upload is held in memory; the rest is
+	// spilled to temporary files. It defaults to 8 MiB.
+	MaxMemory int64
+	// MaxConcurrent bounds the uploads processed at once. Further requests
+	// wait for a slot until their timeout. It defaults to the number of
+	// CPUs.
+	MaxConcurrent int
+	// Timeout bounds the time a request may take, including waiting for a
+	// slot. It defaults to one minute.
+	Timeout time.Duration
+	// ErrorLog receives server-side failures; nil uses the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+	// ForensicKey seals the forensic marks requested by clients and opens
+	// them for inspection; see pdfmark.WithForensicKey. Without it, requests
+	// for a forensic mark fail.
+	ForensicKey []byte
+}
+
+// Server is an http.Handler serving the watermarking API. It is safe for
+// concurrent use.
+type Server struct {
+	cfg      Config
+	slots    chan struct{}
+	mux      *http.ServeMux
+	draining atomic.Bool
+}
+
+// New returns a Server configured by cfg.
+func New(cfg Config) *Server {
+	if cfg.MaxRequestBytes <= 0 {
+		cfg.MaxRequestBytes = 64 << 20
+	}
+	if cfg.MaxMemory <= 0 {
+		cfg.MaxMemory = 8 << 20
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = runtime.NumCPU()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	if cfg.ErrorLog == nil {
+		cfg.ErrorLog = log.Default()
+	}
+	s := &Server{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConcurrent),
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /v1/watermark", s.watermark)
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	s.mux.HandleFunc("GET /readyz", s.readyz)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Drain makes /readyz fail so load balancers stop sending work, ahead of
+// shutting down. Requests are still served.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "ok\n")
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ok\n")
+}
+
+func (s *Server) watermark(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		w.Header().Set("Retry-After", "1")
+		s.fail(w, r, fmt.Errorf("waiting for a free slot: %w", ctx.Err()))
+		return
+	}
+
+	// The upload is read only once a slot is free, so its deadline is the
+	// request's rather than a server-wide read timeout.
+	deadline, _ := ctx.Deadline()
+	http.NewResponseController(w).SetReadDeadline(deadline)
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxRequestBytes)
+	if err := r.ParseMultipartForm(s.cfg.MaxMemory); err != nil {
+		// Running out of time is the server's doing, not the upload's. A
+		// failed read may cancel the request before its deadline is seen to
+		// pass, so the deadline decides.
+		switch {
+		case errors.Is(err, os.ErrDeadlineExceeded) || !time.Now().Before(deadline):
+			s.fail(w, r, fmt.Errorf("reading upload: %w", context.DeadlineExceeded))
+		case ctx.Err() != nil:
+			s.fail(w, r, fmt.Errorf("reading upload: %w", ctx.Err()))
+		default:
+			s.fail(w, r, badRequest(err, "reading upload"))
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	pdf, err := formFile(r.MultipartForm, "pdf")
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	defer pdf.Close()
+	instructions, err := formReader(r.MultipartForm, "instructions")
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	defer instructions.Close()
+	opts, err := formOptions(r.MultipartForm)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	opts = append(opts, pdfmark.WithForensicKey(s.cfg.ForensicKey))
+
+	out := &pdfWriter{w: w}
+	err = pdfmark.WatermarkWithOptions(ctx, out, pdf, instructions, opts...)
+	if err == nil {
+		return
+	}
+	if out.started {
+		// The status is sent; cut the response short so the client does
+		// not take a partial PDF for a complete one.
+		s.cfg.ErrorLog.Printf("pdfmark serve: %s %s: writing PDF: %v", r.Method, r.URL.Path, err)
+		panic(http.ErrAbortHandler)
+	}
+	s.fail(w, r, err)
+}
+
+// pdfWriter sends the response headers on the first write, so errors found
+// before any output can still be answered with an error status.
+type pdfWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (p *pdfWriter) Write(b []byte) (int, error) {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Type", "application/pdf")
+		p.w.WriteHeader(http.StatusOK)
+	}
+	return p.w.Write(b)
+}
+
+func (p *pdfWriter) Close() error { return nil }
+
+// formFile opens the file part name of f.
+func formFile(f *multipart.Form, name string) (multipart.File, error) {
+	files := f.File[name]
+	if len(files) == 0 {
+		return nil, badRequest(nil, "missing %q file", name)
+	}
+	if len(files) > 1 {
+		return nil, badRequest(nil, "more than one %q file", name)
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", name, err)
+	}
+	return file, nil
+}
+
+// formReader opens the part name of f, which may be a file or a plain
+// field.
+func formReader(f *multipart.Form, name string) (io.ReadCloser, error) {
+	if len(f.File[name]) > 0 {
+		return formFile(f, name)
+	}
+	values := f.Value[name]
+	switch len(values) {
+	case 0:
+		return nil, badRequest(nil, "missing %q file or field", name)
+	case 1:
+		return io.NopCloser(strings.NewReader(values[0])), nil
+	}
+	return nil, badRequest(nil, "more than one %q field", name)
+}
+
+// requestError is a problem with the form rather than its contents.
+type requestError struct {
+	msg string
+	err error
+}
+
+func badRequest(err error, format string, args ...any) error {
+	return &requestError{msg: fmt.Sprintf(format, args...), err: err}
+}
+
+func (e *requestError) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *requestError) Unwrap() error { return e.err }
+
+// isRequestError reports whether err is a requestError, other than one
+// caused by an oversized body.
+func isRequestError(err error) bool {
+	var re *requestError
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &re) && !errors.As(err, &tooLarge)
+}
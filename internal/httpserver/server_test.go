@@ -0,0 +1,289 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+// upload builds a multipart request to /v1/watermark. files maps part names
+// to contents; fields are added as plain form fields.
+func upload(t *testing.T, files map[string][]byte, fields ...string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range files {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		mw.WriteField(fields[i], fields[i+1])
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/watermark", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func serve(s *Server, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding error body %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+func newTestServer(cfg Config) *Server {
+	cfg.ErrorLog = log.New(io.Discard, "", 0)
+	return New(cfg)
+}
+
+func TestWatermark(t *testing.T) {
+	s := newTestServer(Config{})
+	pdf := testutil.CreateTestPDF(t, 3)
+
+	rec := serve(s, upload(t, map[string][]byte{
+		"pdf":          pdf,
+		"instructions": []byte("page,watermark_text\n1,CONFIDENTIAL\n3,DRAFT\n"),
+	}, "color", "red", "opacity", "0.5"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Content-Type = %q, want application/pdf", ct)
+	}
+	testutil.AssertValidPDF(t, rec.Body.Bytes())
+	testutil.AssertPageCount(t, rec.Body.Bytes(), 3)
+}
+
+func TestWatermark_InstructionsField(t *testing.T) {
+	s := newTestServer(Config{})
+	rec := serve(s, upload(t, map[string][]byte{"pdf": testutil.CreateTestPDF(t, 2)},
+		"instructions", `[{"page": 2, "watermark_text": "Hello {{who}}"}]`,
+		"var", "who=world"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	testutil.AssertValidPDF(t, rec.Body.Bytes())
+}
+
+func TestWatermark_Errors(t *testing.T) {
+	pdf := testutil.CreateTestPDF(t, 2)
+	csv := []byte("page,watermark_text\n1,A\n")
+	tests := []struct {
+		name     string
+		req      *http.Request
+		status   int
+		code     string
+		problems int
+	}{
+		{
+			name:   "missing pdf",
+			req:    upload(t, map[string][]byte{"instructions": csv}),
+			status: http.StatusBadRequest, code: "bad_request",
+		},
+		{
+			name:   "not multipart",
+			req:    httptest.NewRequest(http.MethodPost, "/v1/watermark", bytes.NewReader(pdf)),
+			status: http.StatusBadRequest, code: "bad_request",
+		},
+		{
+			name:   "invalid pdf",
+			req:    upload(t, map[string][]byte{"pdf": []byte("not a pdf"), "instructions": csv}),
+			status: http.StatusUnprocessableEntity, code: "invalid_pdf",
+		},
+		{
+			name:   "encrypted pdf",
+			req:    upload(t, map[string][]byte{"pdf": testutil.EncryptPDF(t, pdf, "user", "owner"), "instructions": csv}),
+			status: http.StatusUnprocessableEntity, code: "encrypted_pdf",
+		},
+		{
+			name:   "truncated pdf",
+			req:    upload(t, map[string][]byte{"pdf": pdf[:len(pdf)/2], "instructions": csv}),
+			status: http.StatusUnprocessableEntity, code: "truncated_pdf",
+		},
+		{
+			name:     "page out of range",
+			req:      upload(t, map[string][]byte{"pdf": pdf, "instructions": []byte("page,watermark_text\n5,A\n9,B\n")}),
+			status:   http.StatusUnprocessableEntity,
+			code:     "page_out_of_range",
+			problems: 2,
+		},
+		{
+			name:     "invalid page",
+			req:      upload(t, map[string][]byte{"pdf": pdf, "instructions": []byte("page,watermark_text\n0,A\n")}),
+			status:   http.StatusBadRequest,
+			code:     "invalid_page",
+			problems: 1,
+		},
+		{
+			name:   "bad color",
+			req:    upload(t, map[string][]byte{"pdf": pdf, "instructions": csv}, "color", "plaid"),
+			status: http.StatusBadRequest, code: "invalid_style",
+		},
+		{
+			name:   "bad option",
+			req:    upload(t, map[string][]byte{"pdf": pdf, "instructions": csv}, "layer", "maybe"),
+			status: http.StatusBadRequest, code: "bad_request",
+		},
+		{
+			name:   "unknown format",
+			req:    upload(t, map[string][]byte{"pdf": pdf, "instructions": csv}, "format", "toml"),
+			status: http.StatusBadRequest, code: "unknown_format",
+		},
+		{
+			name:     "missing asset",
+			req:      upload(t, map[string][]byte{"pdf": pdf, "instructions": []byte("page,image\n1,logo.png\n")}),
+			status:   http.StatusBadRequest,
+			code:     "asset_not_found",
+			problems: 1,
+		},
+		{
+			name:   "bad encryption",
+			req:    upload(t, map[string][]byte{"pdf": pdf, "instructions": csv}, "encrypt_password", "x", "encrypt_key", "40"),
+			status: http.StatusBadRequest, code: "invalid_encryption",
+		},
+	}
+	s := newTestServer(Config{})
+	for _, tt := range tests {
+		rec := serve(s, tt.req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d; body %s", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		body := decodeError(t, rec)
+		if body.Code != tt.code {
+			t.Errorf("%s: code %q, want %q", tt.name, body.Code, tt.code)
+		}
+		if len(body.Problems) != tt.problems {
+			t.Errorf("%s: %d problems, want %d: %+v", tt.name, len(body.Problems), tt.problems, body.Problems)
+		}
+	}
+}
+
+func TestWatermark_RequestTooLarge(t *testing.T) {
+	s := newTestServer(Config{MaxRequestBytes: 1024})
+	rec := serve(s, upload(t, map[string][]byte{
+		"pdf":          testutil.CreateTestPDF(t, 2),
+		"instructions": []byte("page,watermark_text\n1,A\n"),
+	}))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413; body %s", rec.Code, rec.Body)
+	}
+	if body := decodeError(t, rec); body.Code != "request_too_large" {
+		t.Errorf("code %q, want request_too_large", body.Code)
+	}
+}
+
+func TestWatermark_Assets(t *testing.T) {
+	s := newTestServer(Config{})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range map[string][]byte{
+		"pdf":          testutil.CreateTestPDF(t, 2),
+		"instructions": []byte("page,image\n1,logo.png\n"),
+	} {
+		fw, _ := mw.CreateFormFile(name, name)
+		fw.Write(data)
+	}
+	fw, _ := mw.CreateFormFile("asset", "logo.png")
+	fw.Write(testutil.CreateTestPNG(t, 8, 8))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/watermark", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	rec := serve(s, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	testutil.AssertValidPDF(t, rec.Body.Bytes())
+}
+
+func TestWatermark_Busy(t *testing.T) {
+	s := newTestServer(Config{MaxConcurrent: 1, Timeout: 10 * time.Millisecond})
+	s.slots <- struct{}{} // occupy the only slot
+	rec := serve(s, upload(t, map[string][]byte{
+		"pdf":          testutil.CreateTestPDF(t, 1),
+		"instructions": []byte("page,watermark_text\n1,A\n"),
+	}))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503; body %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+	<-s.slots
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s := newTestServer(Config{})
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if rec := serve(s, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", path, rec.Code)
+		}
+	}
+	s.Drain()
+	if rec := serve(s, httptest.NewRequest(http.MethodGet, "/readyz", nil)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz while draining: status %d, want 503", rec.Code)
+	}
+	if rec := serve(s, httptest.NewRequest(http.MethodGet, "/healthz", nil)); rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz while draining: status %d, want 200", rec.Code)
+	}
+}
+
+func TestWatermark_SlowUpload(t *testing.T) {
+	s := newTestServer(Config{MaxConcurrent: 1, Timeout: 300 * time.Millisecond})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	// A queued request still gets its upload read once a slot frees up.
+	s.slots <- struct{}{}
+	time.AfterFunc(100*time.Millisecond, func() { <-s.slots })
+	req := upload(t, map[string][]byte{
+		"pdf":          testutil.CreateTestPDF(t, 1),
+		"instructions": []byte("page,watermark_text\n1,A\n"),
+	})
+	resp, err := http.Post(srv.URL+"/v1/watermark", req.Header.Get("Content-Type"), req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("queued request: status %d, want 200", resp.StatusCode)
+	}
+
+	// An upload that stalls past the timeout is answered as a timeout.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("--x\r\n"))
+	resp, err = http.Post(srv.URL+"/v1/watermark", "multipart/form-data; boundary=x", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body errorBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || body.Code != "timeout" {
+		t.Errorf("stalled upload: status %d, body %+v, want 503 timeout", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,74 @@
+package spec
+
+// Instruction is a single watermark directive, usually one input row.
+type Instruction struct {
+	Pages   Pages         // Pages the instruction applies to.
+	Text    string        // Watermark text, empty for image and PDF instructions.
+	Image   string        // Image reference; if set an image is stamped instead of text.
+	PDF     string        // Stamp PDF reference; if set a page of it is stamped.
+	PDFPage int           // 1-indexed page of the stamp PDF, used with PDF.
+	Style   StyleOverride // Per-instruction changes to the base style.
+	Line    int           // Source line the instruction came from, 0 if unknown.
+}
+
+// StyleOverride holds optional per-instruction style settings. A nil field
+// inherits the corresponding value from the base style.
+type StyleOverride struct {
+	FontName   *string
+	FontSize   *int
+	Color      *Color
+	Opacity    *float64
+	Rotation   *float64
+	Diagonal   *Diagonal
+	Position   *Position
+	Dx, Dy     *float64
+	Margin     *float64
+	RenderMode *RenderMode
+	Scale      *float64
+	ScaleMode  *ScaleMode
+}
+
+// Apply returns base with every non-nil field of o copied over it.
+func (o StyleOverride) Apply(base Style) Style {
+	s := base
+	if o.FontName != nil {
+		s.FontName = *o.FontName
+	}
+	if o.FontSize != nil {
+		s.FontSize = *o.FontSize
+	}
+	if o.Color != nil {
+		s.Color = *o.Color
+	}
+	if o.Opacity != nil {
+		s.Opacity = *o.Opacity
+	}
+	if o.Rotation != nil {
+		s.Rotation = *o.Rotation
+	}
+	if o.Diagonal != nil {
+		s.Diagonal = *o.Diagonal
+	}
+	if o.Position != nil {
+		s.Position = *o.Position
+	}
+	if o.Dx != nil {
+		s.Dx = *o.Dx
+	}
+	if o.Dy != nil {
+		s.Dy = *o.Dy
+	}
+	if o.Margin != nil {
+		s.Margin = *o.Margin
+	}
+	if o.RenderMode != nil {
+		s.RenderMode = *o.RenderMode
+	}
+	if o.Scale != nil {
+		s.Scale = *o.Scale
+	}
+	if o.ScaleMode != nil {
+		s.ScaleMode = *o.ScaleMode
+	}
+	return s
+}
@@ -0,0 +1,308 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// Pages is a parsed page selector. A selector is a comma-separated list of
+// terms, each one of:
+//
+//	7       a single page
+//	-1      a page counted from the end (-1 is the last page)
+//	2-5     an inclusive range
+//	3-last  a range up to the last page (also written "3-")
+//	last    the last page
+//	odd     all odd pages
+//	even    all even pages
+//	all     every page
+//
+// Selectors are resolved against the page count with Expand.
+type Pages struct {
+	raw   string
+	terms []pageTerm
+}
+
+// pageTerm selects pages from..to inclusive. Negative bounds count from the
+// end of the document, so -1 is the last page. parity restricts the range to
+// odd (1) or even (2) pages.
+type pageTerm struct {
+	from, to int
+	parity   int
+}
+
+const (
+	anyParity = iota
+	oddParity
+	evenParity
+)
+
+// SinglePage returns a selector for page n.
+func SinglePage(n int) Pages {
+	return Pages{raw: strconv.Itoa(n), terms: []pageTerm{{from: n, to: n}}}
+}
+
+// ParsePages parses a page selector. Page 0 is rejected with ErrInvalidPage;
+// any other syntax problem is reported as ErrMalformedCSV.
+func ParsePages(s string) (Pages, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return Pages{}, fmt.Errorf("%w: empty page selector", errs.ErrMalformedCSV)
+	}
+
+	var terms []pageTerm
+	for _, part := range strings.Split(raw, ",") {
+		t, err := parseTerm(strings.ToLower(strings.TrimSpace(part)))
+		if err != nil {
+			return Pages{}, err
+		}
+		terms = append(terms, t)
+	}
+	return Pages{raw: raw, terms: terms}, nil
+}
+
+func parseTerm(s string) (pageTerm, error) {
+	switch s {
+	case "all":
+		return pageTerm{from: 1, to: -1}, nil
+	case "odd":
+		return pageTerm{from: 1, to: -1, parity: oddParity}, nil
+	case "even":
+		return pageTerm{from: 1, to: -1, parity: evenParity}, nil
+	case "last":
+		return pageTerm{from: -1, to: -1}, nil
+	case "":
+		return pageTerm{}, fmt.Errorf("%w: empty term in page selector", errs.ErrMalformedCSV)
+	}
+
+	// A leading minus is a page counted from the end, not a range.
+	if n, err := strconv.Atoi(s); err == nil {
+		if n == 0 {
+			return pageTerm{}, fmt.Errorf("%w: page 0", errs.ErrInvalidPage)
+		}
+		return pageTerm{from: n, to: n}, nil
+	}
+
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		return pageTerm{}, fmt.Errorf("%w: invalid page selector %q", errs.ErrMalformedCSV, s)
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return pageTerm{}, fmt.Errorf("%w: invalid page selector %q", errs.ErrMalformedCSV, s)
+	}
+	if from <= 0 {
+		return pageTerm{}, fmt.Errorf("%w: page %d in range %q", errs.ErrInvalidPage, from, s)
+	}
+
+	hi = strings.TrimSpace(hi)
+	if hi == "" || hi == "last" {
+		return pageTerm{from: from, to: -1}, nil
+	}
+	to, err := strconv.Atoi(hi)
+	if err != nil {
+		return pageTerm{}, fmt.Errorf("%w: invalid page selector %q", errs.ErrMalformedCSV, s)
+	}
+	if to < from {
+		return pageTerm{}, fmt.Errorf("%w: descending page range %q", errs.ErrMalformedCSV, s)
+	}
+	return pageTerm{from: from, to: to}, nil
+}
+
+func (p Pages) String() string {
+	return p.raw
+}
+
+// IsZero reports whether p selects nothing, as for the zero value.
+func (p Pages) IsZero() bool {
+	return len(p.terms) == 0
+}
+
+// Single returns the page number if p is one positive page, as in the
+// classic "page,watermark_text" CSV format.
+func (p Pages) Single() (int, bool) {
+	if len(p.terms) == 1 {
+		t := p.terms[0]
+		if t.from > 0 && t.from == t.to && t.parity == anyParity {
+			return t.from, true
+		}
+	}
+	return 0, false
+}
+
+// Resolve returns the pages p selects in a document of totalPages pages, in
+// ascending order without duplicates. For each term reaching outside the
+// document, the first page outside it is kept so the caller can report it;
+// callers must check the range.
+func (p Pages) Resolve(totalPages int) []int {
+	pages, outside := p.resolve(totalPages)
+	for _, s := range outside {
+		if !slices.Contains(pages, s.from) {
+			pages = append(pages, s.from)
+		}
+	}
+	slices.Sort(pages)
+	return pages
+}
+
+// span is an inclusive range of pages.
+type span struct {
+	from, to int
+}
+
+func (s span) String() string {
+	if s.from == s.to {
+		return fmt.Sprintf("page %d", s.from)
+	}
+	return fmt.Sprintf("pages %d-%d", s.from, s.to)
+}
+
+// resolve returns the pages p selects within a document of totalPages pages,
+// in ascending order without duplicates, and the part of each term that
+// lies outside it, in term order. The work done is bounded by totalPages
+// and the number of terms, however large the ranges are.
+func (p Pages) resolve(totalPages int) (pages []int, outside []span) {
+	seen := make(map[int]bool)
+	for _, t := range p.terms {
+		from, to := resolveBound(t.from, totalPages), resolveBound(t.to, totalPages)
+		switch {
+		case from > to || from < 1:
+			// Explicit page outside the document, e.g. "9-last" in a
+			// 5-page PDF or "-9".
+			outside = append(outside, span{from, from})
+			continue
+		case to > totalPages:
+			// A range running past the end, e.g. "3-9" in a 5-page PDF.
+			outside = append(outside, span{max(from, totalPages+1), to})
+			to = totalPages
+		}
+		for n := from; n <= to; n++ {
+			switch {
+			case t.parity == oddParity && n%2 == 0:
+			case t.parity == evenParity && n%2 == 1:
+			case !seen[n]:
+				seen[n] = true
+				pages = append(pages, n)
+			}
+		}
+	}
+	slices.Sort(pages)
+	return pages, outside
+}
+
+func resolveBound(n, totalPages int) int {
+	if n < 0 {
+		return totalPages + 1 + n
+	}
+	return n
+}
+
+// PagePolicy decides what happens to instructions selecting pages outside
+// the document, or pages already selected by an earlier instruction.
+type PagePolicy int
+
+// Supported page policies.
+const (
+	// Strict rejects out-of-range and duplicate pages.
+	Strict PagePolicy = iota
+	// SkipInvalid drops out-of-range pages and duplicates, keeping the
+	// first instruction selecting a page.
+	SkipInvalid
+	// ClampToLastPage moves pages past the end onto the last page, and pages
+	// before the start onto the first; duplicates are dropped as with
+	// SkipInvalid.
+	ClampToLastPage
+)
+
+var pagePolicyNames = map[PagePolicy]string{
+	Strict:          "strict",
+	SkipInvalid:     "skip",
+	ClampToLastPage: "clamp",
+}
+
+func (p PagePolicy) String() string {
+	if s, ok := pagePolicyNames[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("PagePolicy(%d)", int(p))
+}
+
+// ParsePagePolicy parses "strict", "skip" or "clamp".
+func ParsePagePolicy(s string) (PagePolicy, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	for p, name := range pagePolicyNames {
+		if name == key {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown page policy %q", s)
+}
+
+// Expand resolves every instruction's page selector against totalPages and
+// returns the instructions keyed by page, in input order. Unless layered is
+// set, two instructions selecting the same page are reported as
+// ErrDuplicatePage; every such page is reported, joined into one error,
+// along with the pages that were not in conflict.
+//
+// Under a policy other than Strict, duplicates and pages outside the
+// document are not errors: they are dropped or clamped as the policy
+// says and listed in skipped, matching ErrDuplicatePage or
+// ErrPageOutOfRange: one entry per instruction and duplicate page, and one
+// per selector term reaching outside the document, such as "pages 13-900
+// skipped". Under Strict, the first page outside the document of each such
+// term is kept for ValidatePages in package stamp to report.
+func Expand(instructions []Instruction, totalPages int, layered bool, policy PagePolicy) (_ map[int][]Instruction, skipped []*errs.InstructionError, _ error) {
+	m := make(map[int][]Instruction)
+	var problems []*errs.InstructionError
+	for _, ins := range instructions {
+		var pages []int
+		if policy == Strict {
+			pages = ins.Pages.Resolve(totalPages)
+		} else {
+			var outside []span
+			pages, outside = ins.Pages.resolve(totalPages)
+			for _, s := range outside {
+				p := &errs.InstructionError{
+					Err:    errs.ErrPageOutOfRange,
+					Line:   ins.Line,
+					Page:   s.from,
+					Detail: fmt.Sprintf("%s skipped, PDF has %d pages", s, totalPages),
+				}
+				skipped = append(skipped, p)
+				if policy == SkipInvalid {
+					continue
+				}
+				page := min(max(s.from, 1), totalPages)
+				if !slices.Contains(pages, page) {
+					p.Detail = fmt.Sprintf("%s moved to page %d, PDF has %d pages", s, page, totalPages)
+					pages = append(pages, page)
+				}
+			}
+			slices.Sort(pages)
+		}
+
+		for _, page := range pages {
+			if prev := m[page]; len(prev) > 0 && !layered {
+				p := &errs.InstructionError{
+					Err:    errs.ErrDuplicatePage,
+					Line:   ins.Line,
+					Page:   page,
+					Detail: fmt.Sprintf("already set on line %d", prev[0].Line),
+				}
+				if policy == Strict {
+					problems = append(problems, p)
+				} else {
+					p.Detail += ", skipped"
+					skipped = append(skipped, p)
+				}
+				continue
+			}
+			m[page] = append(m[page], ins)
+		}
+	}
+	return m, skipped, errs.Join(problems)
+}
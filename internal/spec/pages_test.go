@@ -0,0 +1,241 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+func TestPagesResolve(t *testing.T) {
+	tests := []struct {
+		sel   string
+		total int
+		want  []int
+	}{
+		{"3", 5, []int{3}},
+		{"1-3", 5, []int{1, 2, 3}},
+		{"2-last", 5, []int{2, 3, 4, 5}},
+		{"4-", 5, []int{4, 5}},
+		{"odd", 5, []int{1, 3, 5}},
+		{"even", 5, []int{2, 4}},
+		{"last", 5, []int{5}},
+		{"-1", 5, []int{5}},
+		{"-2", 5, []int{4}},
+		{"all", 3, []int{1, 2, 3}},
+		{"1, 3-4, last", 6, []int{1, 3, 4, 6}},
+		{"odd,1-2", 4, []int{1, 2, 3}},
+		{"ALL", 2, []int{1, 2}},
+		{"4-8", 5, []int{4, 5, 6}},
+		{"1-50000000", 5, []int{1, 2, 3, 4, 5, 6}},
+		{"2-3,7-9,8-12", 5, []int{2, 3, 7, 8}},
+		{"9-last", 5, []int{9}},
+		{"-7", 5, []int{-1}},
+	}
+	for _, tt := range tests {
+		p, err := ParsePages(tt.sel)
+		if err != nil {
+			t.Errorf("ParsePages(%q): %v", tt.sel, err)
+			continue
+		}
+		if got := p.Resolve(tt.total); !slices.Equal(got, tt.want) {
+			t.Errorf("ParsePages(%q).Resolve(%d) = %v, want %v", tt.sel, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestParsePages_Invalid(t *testing.T) {
+	tests := map[string]error{
+		"":      errs.ErrMalformedCSV,
+		"abc":   errs.ErrMalformedCSV,
+		"3-1":   errs.ErrMalformedCSV,
+		"1,":    errs.ErrMalformedCSV,
+		"2-x":   errs.ErrMalformedCSV,
+		"0":     errs.ErrInvalidPage,
+		"0-3":   errs.ErrInvalidPage,
+		"1,0,2": errs.ErrInvalidPage,
+	}
+	for sel, want := range tests {
+		if _, err := ParsePages(sel); !errors.Is(err, want) {
+			t.Errorf("ParsePages(%q): got error %v, want %v", sel, err, want)
+		}
+	}
+}
+
+func TestPagesSingle(t *testing.T) {
+	if n, ok := SinglePage(4).Single(); !ok || n != 4 {
+		t.Errorf("SinglePage(4).Single() = %d, %v", n, ok)
+	}
+	for _, sel := range []string{"1-2", "last", "-1", "odd", "1,2"} {
+		p, _ := ParsePages(sel)
+		if _, ok := p.Single(); ok {
+			t.Errorf("ParsePages(%q).Single() = true, want false", sel)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	all, _ := ParsePages("all")
+	instructions := []Instruction{
+		{Pages: all, Text: "EVERY", Line: 2},
+	}
+	m, _, err := Expand(instructions, 3, false, Strict)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(m) != 3 || m[2][0].Text != "EVERY" {
+		t.Errorf("Expand = %v, want pages 1-3", m)
+	}
+}
+
+func TestExpand_Overlap(t *testing.T) {
+	odd, _ := ParsePages("odd")
+	instructions := []Instruction{
+		{Pages: odd, Text: "A", Line: 2},
+		{Pages: SinglePage(3), Text: "B", Line: 3},
+	}
+	_, _, err := Expand(instructions, 5, false, Strict)
+	if !errors.Is(err, errs.ErrDuplicatePage) {
+		t.Errorf("got error %v, want ErrDuplicatePage", err)
+	}
+}
+
+func TestExpand_ReportsEveryOverlap(t *testing.T) {
+	all, _ := ParsePages("all")
+	instructions := []Instruction{
+		{Pages: all, Text: "A", Line: 2},
+		{Pages: SinglePage(2), Text: "B", Line: 3},
+		{Pages: SinglePage(3), Text: "C", Line: 4},
+	}
+	_, _, err := Expand(instructions, 3, false, Strict)
+	problems := errs.Problems(err)
+	if len(problems) != 2 {
+		t.Fatalf("got %d problems (%v), want 2", len(problems), err)
+	}
+	for i, want := range []struct{ line, page int }{{3, 2}, {4, 3}} {
+		p := problems[i]
+		if !errors.Is(p, errs.ErrDuplicatePage) || p.Line != want.line || p.Page != want.page {
+			t.Errorf("problem %d = %+v, want duplicate page %d on line %d", i, p, want.page, want.line)
+		}
+	}
+}
+
+func TestExpand_Policies(t *testing.T) {
+	tail, _ := ParsePages("3-6")
+	instructions := []Instruction{
+		{Pages: SinglePage(1), Text: "A", Line: 2},
+		{Pages: SinglePage(1), Text: "B", Line: 3},
+		{Pages: tail, Text: "C", Line: 4},
+		{Pages: SinglePage(9), Text: "D", Line: 5},
+	}
+	tests := []struct {
+		policy  PagePolicy
+		pages   map[int]string
+		skipped []string
+	}{
+		{
+			policy:  SkipInvalid,
+			pages:   map[int]string{1: "A", 3: "C", 4: "C"},
+			skipped: []string{"3:1", "4:5", "5:9"},
+		},
+		{
+			// D moves to page 4, which C already has.
+			policy:  ClampToLastPage,
+			pages:   map[int]string{1: "A", 3: "C", 4: "C"},
+			skipped: []string{"3:1", "4:5", "5:9", "5:4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			m, skipped, err := Expand(instructions, 4, false, tt.policy)
+			if err != nil {
+				t.Fatalf("Expand: %v", err)
+			}
+			if len(m) != len(tt.pages) {
+				t.Errorf("got pages %v, want %v", m, tt.pages)
+			}
+			for page, text := range tt.pages {
+				if len(m[page]) != 1 || m[page][0].Text != text {
+					t.Errorf("page %d = %v, want %s", page, m[page], text)
+				}
+			}
+			var got []string
+			for _, p := range skipped {
+				got = append(got, fmt.Sprintf("%d:%d", p.Line, p.Page))
+			}
+			if !slices.Equal(got, tt.skipped) {
+				t.Errorf("skipped line:page = %v, want %v", got, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestExpand_HugeRange(t *testing.T) {
+	huge, _ := ParsePages("2-20000000")
+	instructions := []Instruction{{Pages: huge, Text: "A", Line: 2}}
+	tests := []struct {
+		policy PagePolicy
+		detail string
+	}{
+		{SkipInvalid, "pages 13-20000000 skipped, PDF has 12 pages"},
+		{ClampToLastPage, "pages 13-20000000 skipped, PDF has 12 pages"},
+	}
+	for _, tt := range tests {
+		m, skipped, err := Expand(instructions, 12, false, tt.policy)
+		if err != nil {
+			t.Fatalf("%s: Expand: %v", tt.policy, err)
+		}
+		if len(m) != 11 {
+			t.Errorf("%s: got %d pages, want 11", tt.policy, len(m))
+		}
+		if len(skipped) != 1 || skipped[0].Page != 13 || skipped[0].Detail != tt.detail {
+			t.Errorf("%s: skipped = %v, want one entry %q", tt.policy, skipped, tt.detail)
+		}
+	}
+
+	m, _, err := Expand(instructions, 12, false, Strict)
+	if err != nil {
+		t.Fatalf("strict: Expand: %v", err)
+	}
+	if len(m) != 12 || len(m[13]) != 1 {
+		t.Errorf("strict: got pages %v, want 2-12 and the marker 13", slices.Sorted(maps.Keys(m)))
+	}
+}
+
+func TestExpand_ClampLayered(t *testing.T) {
+	instructions := []Instruction{
+		{Pages: SinglePage(2), Text: "A", Line: 2},
+		{Pages: SinglePage(7), Text: "B", Line: 3},
+	}
+	m, skipped, err := Expand(instructions, 2, true, ClampToLastPage)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(m[2]) != 2 || m[2][1].Text != "B" {
+		t.Errorf("page 2 = %v, want A then B", m[2])
+	}
+	if len(skipped) != 1 || !errors.Is(skipped[0], errs.ErrPageOutOfRange) || skipped[0].Page != 7 {
+		t.Errorf("skipped = %v, want page 7 moved", skipped)
+	}
+}
+
+func TestExpand_Layered(t *testing.T) {
+	all, _ := ParsePages("all")
+	instructions := []Instruction{
+		{Pages: all, Text: "CONFIDENTIAL", Line: 2},
+		{Pages: SinglePage(2), Text: "FOOTER", Line: 3},
+	}
+	m, _, err := Expand(instructions, 3, true, Strict)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(m[1]) != 1 || len(m[3]) != 1 {
+		t.Errorf("pages 1 and 3 = %v, %v, want one instruction each", m[1], m[3])
+	}
+	if len(m[2]) != 2 || m[2][0].Text != "CONFIDENTIAL" || m[2][1].Text != "FOOTER" {
+		t.Errorf("page 2 = %v, want CONFIDENTIAL then FOOTER", m[2])
+	}
+}
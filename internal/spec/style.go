@@ -0,0 +1,279 @@
+// Package spec defines the watermark style model shared by the instruction
+// parsers and the stamper.
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/color"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// Position anchors a watermark on the page. The zero value is Center.
+type Position int
+
+// Supported anchor positions.
+const (
+	Center Position = iota
+	TopLeft
+	TopCenter
+	TopRight
+	Left
+	Right
+	BottomLeft
+	BottomCenter
+	BottomRight
+	// Tiled repeats a text watermark in a grid over the whole page, laid
+	// out by Style.Tile.
+	Tiled
+	// Header and footer presets place a line of text Style.Margin from the
+	// top or bottom edge; the margin presets run it up the left or down
+	// the right edge.
+	Header
+	HeaderLeft
+	HeaderRight
+	Footer
+	FooterLeft
+	FooterRight
+	LeftMargin
+	RightMargin
+)
+
+var positionNames = map[Position]string{
+	Center:       "center",
+	TopLeft:      "top-left",
+	TopCenter:    "top-center",
+	TopRight:     "top-right",
+	Left:         "left",
+	Right:        "right",
+	BottomLeft:   "bottom-left",
+	BottomCenter: "bottom-center",
+	BottomRight:  "bottom-right",
+	Tiled:        "tiled",
+	Header:       "header",
+	HeaderLeft:   "header-left",
+	HeaderRight:  "header-right",
+	Footer:       "footer",
+	FooterLeft:   "footer-left",
+	FooterRight:  "footer-right",
+	LeftMargin:   "left-margin",
+	RightMargin:  "right-margin",
+}
+
+// positionAliases maps the short pdfcpu anchor names onto positions.
+var positionAliases = map[string]Position{
+	"c":    Center,
+	"tl":   TopLeft,
+	"tc":   TopCenter,
+	"tr":   TopRight,
+	"l":    Left,
+	"r":    Right,
+	"bl":   BottomLeft,
+	"bc":   BottomCenter,
+	"br":   BottomRight,
+	"tile": Tiled,
+}
+
+// IsPreset reports whether p is a header, footer or margin preset.
+func (p Position) IsPreset() bool {
+	return p >= Header && p <= RightMargin
+}
+
+func (p Position) String() string {
+	if s, ok := positionNames[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("Position(%d)", int(p))
+}
+
+// ParsePosition parses a position name such as "center", "top-left" or the
+// short pdfcpu form "tl". Matching is case-insensitive and accepts spaces or
+// underscores in place of the hyphen.
+func ParsePosition(s string) (Position, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer(" ", "-", "_", "-").Replace(key)
+	if p, ok := positionAliases[key]; ok {
+		return p, nil
+	}
+	for p, name := range positionNames {
+		if name == key {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown position %q", errs.ErrInvalidStyle, s)
+}
+
+// Diagonal selects one of the page diagonals to paint along.
+type Diagonal int
+
+// Supported diagonals. NoDiagonal means Style.Rotation is used instead.
+const (
+	NoDiagonal Diagonal = iota
+	DiagonalLLToUR
+	DiagonalULToLR
+)
+
+// ScaleMode controls how Style.Scale is interpreted.
+type ScaleMode int
+
+// Supported scale modes.
+const (
+	// ScaleRelative scales the watermark relative to the page size, with
+	// Scale between 0 and 1.
+	ScaleRelative ScaleMode = iota
+	// ScaleAbsolute multiplies the font size by Scale.
+	ScaleAbsolute
+)
+
+// RenderMode selects how glyph outlines are painted.
+type RenderMode int
+
+// Supported render modes.
+const (
+	RenderFill RenderMode = iota
+	RenderStroke
+	RenderFillStroke
+)
+
+var renderModeNames = map[RenderMode]string{
+	RenderFill:       "fill",
+	RenderStroke:     "stroke",
+	RenderFillStroke: "fill-stroke",
+}
+
+func (m RenderMode) String() string {
+	if s, ok := renderModeNames[m]; ok {
+		return s
+	}
+	return fmt.Sprintf("RenderMode(%d)", int(m))
+}
+
+// ParseRenderMode parses "fill", "stroke" or "fill-stroke", or the numeric
+// PDF text render modes 0, 1 and 2.
+func ParseRenderMode(s string) (RenderMode, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer(" ", "-", "_", "-").Replace(key)
+	switch key {
+	case "fill", "0":
+		return RenderFill, nil
+	case "stroke", "1":
+		return RenderStroke, nil
+	case "fill-stroke", "fillstroke", "2":
+		return RenderFillStroke, nil
+	}
+	return 0, fmt.Errorf("%w: unknown render mode %q", errs.ErrInvalidStyle, s)
+}
+
+// Color is an RGB color with components in [0, 1].
+type Color struct {
+	R, G, B float32
+}
+
+// Predefined colors.
+var (
+	Black = Color{}
+	Gray  = Color{.5, .5, .5}
+	Red   = Color{1, 0, 0}
+)
+
+// ParseColor parses a color given as a name (black, gray, red, ...), a hex
+// code (#RRGGBB) or three space-separated intensities ("0.5 0.5 0.5").
+func ParseColor(s string) (Color, error) {
+	sc, err := color.ParseColor(strings.TrimSpace(s))
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: invalid color %q", errs.ErrInvalidStyle, s)
+	}
+	return Color{R: sc.R, G: sc.G, B: sc.B}, nil
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", channel(c.R), channel(c.G), channel(c.B))
+}
+
+func channel(v float32) uint8 {
+	return uint8(v*255 + 0.5)
+}
+
+// Tiling lays out the grid of a Tiled watermark. Tiles are spaced by
+// their extent once rotated plus the gaps, and the grid is centered in the
+// page's crop box, or media box if it has none, less the margin. A page
+// takes at most 1000 tiles.
+type Tiling struct {
+	GapX, GapY float64 // Space between neighbouring tiles in points.
+	Stagger    bool    // Shift every other row by half a tile, like bricks.
+	Margin     float64 // Space kept free along each page edge in points.
+}
+
+// Style describes how a watermark looks and where it is placed.
+type Style struct {
+	FontName   string     // Adobe base font, e.g. Helvetica, Times-Roman, Courier.
+	FontSize   int        // Font size in points.
+	Color      Color      // Fill and stroke color.
+	Opacity    float64    // 0 < Opacity <= 1.
+	Rotation   float64    // Degrees, -180..180; used when Diagonal is NoDiagonal.
+	Diagonal   Diagonal   // Page diagonal to paint along, overrides Rotation.
+	Position   Position   // Anchor on the page.
+	Dx, Dy     float64    // Offset from the anchor in points.
+	OnTop      bool       // true stamps over the content, false underlays it.
+	Scale      float64    // Scale factor, see ScaleMode.
+	ScaleMode  ScaleMode  // Relative to page size or absolute.
+	RenderMode RenderMode // Fill, stroke or both.
+	Tile       Tiling     // Grid of a Tiled watermark.
+	Margin     float64    // Distance of a preset Position from the page edge in points.
+}
+
+// DefaultStyle returns the library's default look: Helvetica 48pt, gray,
+// 0.3 opacity, drawn on top along the lower-left to upper-right diagonal,
+// centered on the page. Tiles, if chosen, are an inch apart, and headers,
+// footers and margin text a quarter inch from the edge.
+func DefaultStyle() Style {
+	return Style{
+		FontName:   "Helvetica",
+		FontSize:   48,
+		Color:      Gray,
+		Opacity:    0.3,
+		Diagonal:   DiagonalLLToUR,
+		Position:   Center,
+		OnTop:      true,
+		Scale:      1.0,
+		ScaleMode:  ScaleRelative,
+		RenderMode: RenderFill,
+		Tile:       Tiling{GapX: 72, GapY: 72},
+		Margin:     18,
+	}
+}
+
+// Validate reports whether every field of s is within its allowed range.
+func (s Style) Validate() error {
+	switch {
+	case s.FontName == "":
+		return errs.Detailf(errs.ErrInvalidStyle, "font name is empty")
+	case s.FontSize <= 0:
+		return errs.Detailf(errs.ErrInvalidStyle, "font size must be > 0, got %d", s.FontSize)
+	case s.Opacity <= 0 || s.Opacity > 1:
+		return errs.Detailf(errs.ErrInvalidStyle, "opacity must be in (0, 1], got %g", s.Opacity)
+	case s.Rotation < -180 || s.Rotation > 180:
+		return errs.Detailf(errs.ErrInvalidStyle, "rotation must be in [-180, 180], got %g", s.Rotation)
+	case s.Diagonal < NoDiagonal || s.Diagonal > DiagonalULToLR:
+		return errs.Detailf(errs.ErrInvalidStyle, "unknown diagonal %d", s.Diagonal)
+	case s.Position < Center || s.Position > RightMargin:
+		return errs.Detailf(errs.ErrInvalidStyle, "unknown position %d", s.Position)
+	case s.Scale <= 0:
+		return errs.Detailf(errs.ErrInvalidStyle, "scale must be > 0, got %g", s.Scale)
+	case s.ScaleMode == ScaleRelative && s.Scale > 1:
+		return errs.Detailf(errs.ErrInvalidStyle, "relative scale must be <= 1, got %g", s.Scale)
+	case s.ScaleMode != ScaleRelative && s.ScaleMode != ScaleAbsolute:
+		return errs.Detailf(errs.ErrInvalidStyle, "unknown scale mode %d", s.ScaleMode)
+	case s.RenderMode < RenderFill || s.RenderMode > RenderFillStroke:
+		return errs.Detailf(errs.ErrInvalidStyle, "unknown render mode %d", s.RenderMode)
+	case s.Tile.GapX < 0 || s.Tile.GapY < 0:
+		return errs.Detailf(errs.ErrInvalidStyle, "tile gaps must be >= 0, got %g and %g", s.Tile.GapX, s.Tile.GapY)
+	case s.Tile.Margin < 0:
+		return errs.Detailf(errs.ErrInvalidStyle, "tile margin must be >= 0, got %g", s.Tile.Margin)
+	case s.Margin < 0:
+		return errs.Detailf(errs.ErrInvalidStyle, "margin must be >= 0, got %g", s.Margin)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package spec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+func TestDefaultStyle_Valid(t *testing.T) {
+	if err := DefaultStyle().Validate(); err != nil {
+		t.Errorf("DefaultStyle().Validate() = %v", err)
+	}
+}
+
+func TestStyleValidate_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Style)
+	}{
+		{"empty font", func(s *Style) { s.FontName = "" }},
+		{"zero size", func(s *Style) { s.FontSize = 0 }},
+		{"zero opacity", func(s *Style) { s.Opacity = 0 }},
+		{"opacity above one", func(s *Style) { s.Opacity = 1.5 }},
+		{"rotation out of range", func(s *Style) { s.Rotation = 200 }},
+		{"unknown position", func(s *Style) { s.Position = Position(42) }},
+		{"zero scale", func(s *Style) { s.Scale = 0 }},
+		{"relative scale above one", func(s *Style) { s.Scale = 2 }},
+		{"negative tile gap", func(s *Style) { s.Tile.GapY = -1 }},
+		{"negative tile margin", func(s *Style) { s.Tile.Margin = -5 }},
+		{"negative margin", func(s *Style) { s.Margin = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultStyle()
+			tt.mutate(&s)
+			if err := s.Validate(); !errors.Is(err, errs.ErrInvalidStyle) {
+				t.Errorf("got error %v, want ErrInvalidStyle", err)
+			}
+		})
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := map[string]Position{
+		"center":        Center,
+		"Top-Left":      TopLeft,
+		"bottom right":  BottomRight,
+		"bottom_center": BottomCenter,
+		"tr":            TopRight,
+		"l":             Left,
+		"Tiled":         Tiled,
+		"tile":          Tiled,
+		"footer":        Footer,
+		"Header Right":  HeaderRight,
+		"left_margin":   LeftMargin,
+	}
+	for in, want := range tests {
+		got, err := ParsePosition(in)
+		if err != nil {
+			t.Errorf("ParsePosition(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParsePosition(%q) = %v, want %v", in, got, want)
+		}
+	}
+
+	if _, err := ParsePosition("middle"); !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("got error %v, want ErrInvalidStyle", err)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := map[string]Color{
+		"red":         Red,
+		"gray":        Gray,
+		"#000000":     Black,
+		"#ff0000":     Red,
+		"0.5 0.5 0.5": Gray,
+	}
+	for in, want := range tests {
+		got, err := ParseColor(in)
+		if err != nil {
+			t.Errorf("ParseColor(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseColor(%q) = %v, want %v", in, got, want)
+		}
+	}
+
+	if _, err := ParseColor("chartreuse"); !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("got error %v, want ErrInvalidStyle", err)
+	}
+}
+
+func TestColorString(t *testing.T) {
+	if got := Red.String(); got != "#ff0000" {
+		t.Errorf("Red.String() = %q, want %q", got, "#ff0000")
+	}
+}
+
+func TestParseRenderMode(t *testing.T) {
+	tests := map[string]RenderMode{
+		"fill":        RenderFill,
+		"Stroke":      RenderStroke,
+		"fill_stroke": RenderFillStroke,
+		"2":           RenderFillStroke,
+	}
+	for in, want := range tests {
+		got, err := ParseRenderMode(in)
+		if err != nil {
+			t.Errorf("ParseRenderMode(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseRenderMode(%q) = %v, want %v", in, got, want)
+		}
+	}
+
+	if _, err := ParseRenderMode("clip"); !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("got error %v, want ErrInvalidStyle", err)
+	}
+}
+
+func TestStyleOverride_Apply(t *testing.T) {
+	font := "Courier"
+	opacity := 0.7
+	pos := BottomLeft
+	o := StyleOverride{FontName: &font, Opacity: &opacity, Position: &pos}
+
+	got := o.Apply(DefaultStyle())
+	want := DefaultStyle()
+	want.FontName = font
+	want.Opacity = opacity
+	want.Position = pos
+	if got != want {
+		t.Errorf("Apply = %+v, want %+v", got, want)
+	}
+
+	if got := (StyleOverride{}).Apply(DefaultStyle()); got != DefaultStyle() {
+		t.Errorf("zero override changed the style: %+v", got)
+	}
+}
@@ -0,0 +1,295 @@
+package stamp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+)
+
+// operation is one operator of a content stream with its operands. The
+// operation spans content[start:end], operands included.
+type operation struct {
+	op         string
+	args       []operand
+	start, end int
+}
+
+// operand is a content stream operand. Dictionaries are kept as raw text.
+type operand struct {
+	kind  operandKind
+	num   float64
+	name  string
+	str   []byte
+	dict  string
+	array []operand
+}
+
+type operandKind int
+
+const (
+	opNumber operandKind = iota + 1
+	opName
+	opString
+	opDict
+	opArray
+	opOther
+)
+
+// number returns args[i] as a number, or def if it is missing or not one.
+func (o operation) number(i int, def float64) float64 {
+	if i < len(o.args) && o.args[i].kind == opNumber {
+		return o.args[i].num
+	}
+	return def
+}
+
+// name returns args[i] as a name, or "".
+func (o operation) name(i int) string {
+	if i < len(o.args) && o.args[i].kind == opName {
+		return o.args[i].name
+	}
+	return ""
+}
+
+// parseContent splits a content stream into operations. It is lenient:
+// malformed input ends the parse rather than failing it, as only
+// watermarks are looked for. pdfcpu keeps its content tokenizer to itself,
+// and its watermark detection only reports whether a document has any, so
+// describing and removing single watermarks needs a parser of our own.
+func parseContent(b []byte) []operation {
+	l := &lexer{b: b}
+	var ops []operation
+	var args []operand
+	start := -1
+	for {
+		l.skipSpace()
+		if start < 0 {
+			start = l.pos
+		}
+		t, ok := l.next()
+		if !ok {
+			return ops
+		}
+		if t.kind != opOther {
+			args = append(args, t)
+			continue
+		}
+		ops = append(ops, operation{op: t.name, args: args, start: start, end: l.pos})
+		args = nil
+		start = -1
+		if t.name == "ID" {
+			l.skipInlineImage()
+		}
+	}
+}
+
+type lexer struct {
+	b   []byte
+	pos int
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isSpace(c)
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.b) {
+		switch c := l.b[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// maxArrayDepth caps array nesting. Content streams never nest arrays
+// more than a level or two; past the cap the parse ends.
+const maxArrayDepth = 32
+
+// next returns the next operand, or an operator as an opOther operand
+// holding its keyword in name. Arrays are collected on an explicit stack
+// so that hostile nesting cannot exhaust the goroutine stack.
+func (l *lexer) next() (operand, bool) {
+	var stack [][]operand
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.b) {
+			return operand{}, false
+		}
+		var t operand
+		switch c := l.b[l.pos]; {
+		case c == '(':
+			t = operand{kind: opString, str: l.literal()}
+		case c == '<' && l.pos+1 < len(l.b) && l.b[l.pos+1] == '<':
+			t = operand{kind: opDict, dict: l.dict()}
+		case c == '<':
+			t = operand{kind: opString, str: l.hexString()}
+		case c == '[':
+			if len(stack) == maxArrayDepth {
+				return operand{}, false
+			}
+			l.pos++
+			stack = append(stack, nil)
+			continue
+		case c == ']' && len(stack) > 0:
+			l.pos++
+			t = operand{kind: opArray, array: stack[len(stack)-1]}
+			stack = stack[:len(stack)-1]
+		case c == '/':
+			l.pos++
+			t = operand{kind: opName, name: string(l.word())}
+		case c == ')' || c == '>' || c == ']' || c == '{' || c == '}':
+			// Stray delimiters are skipped.
+			l.pos++
+			continue
+		default:
+			w := l.word()
+			if n, err := strconv.ParseFloat(string(w), 64); err == nil {
+				t = operand{kind: opNumber, num: n}
+			} else {
+				t = operand{kind: opOther, name: string(w)}
+			}
+		}
+		if len(stack) == 0 {
+			return t, true
+		}
+		stack[len(stack)-1] = append(stack[len(stack)-1], t)
+	}
+}
+
+func (l *lexer) word() []byte {
+	start := l.pos
+	for l.pos < len(l.b) && !isDelimiter(l.b[l.pos]) {
+		l.pos++
+	}
+	return l.b[start:l.pos]
+}
+
+// literal reads a literal string, resolving escapes.
+func (l *lexer) literal() []byte {
+	l.pos++
+	var s []byte
+	depth := 0
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return s
+			}
+			depth--
+		case '\\':
+			if l.pos >= len(l.b) {
+				return s
+			}
+			c = l.b[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := int(c - '0')
+				for i := 0; i < 2 && l.pos < len(l.b) && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; i++ {
+					n = n*8 + int(l.b[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(n)
+			}
+		}
+		s = append(s, c)
+	}
+	return s
+}
+
+func (l *lexer) hexString() []byte {
+	l.pos++
+	end := bytes.IndexByte(l.b[l.pos:], '>')
+	if end < 0 {
+		end = len(l.b) - l.pos
+	}
+	digits := make([]byte, 0, end)
+	for _, c := range l.b[l.pos : l.pos+end] {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos = min(l.pos+end+1, len(l.b))
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s, _ := hex.DecodeString(string(digits))
+	return s
+}
+
+// dict returns the raw text of a dictionary, nested ones included.
+func (l *lexer) dict() string {
+	start := l.pos
+	depth := 0
+	for l.pos < len(l.b) {
+		switch {
+		case bytes.HasPrefix(l.b[l.pos:], []byte("<<")):
+			depth++
+			l.pos += 2
+		case bytes.HasPrefix(l.b[l.pos:], []byte(">>")):
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return string(l.b[start:l.pos])
+			}
+		case l.b[l.pos] == '(':
+			l.literal()
+		default:
+			l.pos++
+		}
+	}
+	return string(l.b[start:])
+}
+
+// skipInlineImage skips the data of an inline image up to its EI operator.
+func (l *lexer) skipInlineImage() {
+	if l.pos < len(l.b) {
+		l.pos++ // the single space after ID
+	}
+	for i := l.pos; i+2 <= len(l.b); i++ {
+		if l.b[i] == 'E' && l.b[i+1] == 'I' && isSpace(l.b[i-1]) && (i+2 == len(l.b) || isDelimiter(l.b[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.b)
+}
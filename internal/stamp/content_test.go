@@ -0,0 +1,93 @@
+package stamp
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseContent(t *testing.T) {
+	content := []byte(`% comment
+/Artifact <</Subtype /Watermark /Type /Pagination >>BDC
+q 1 0 0 1 10.5 -2 cm /GS0 gs /Fm0 Do Q EMC
+BT /F1 12 Tf (a \(nested\) \101\nb) Tj <48 49> Tj [(x) -20 (y)] TJ ET
+BI /W 1 /H 1 ID ` + "\x00EI\xff" + ` EI
+0.5 g`)
+	var got []string
+	for _, op := range parseContent(content) {
+		got = append(got, op.op)
+	}
+	want := []string{"BDC", "q", "cm", "gs", "Do", "Q", "EMC", "BT", "Tf", "Tj", "Tj", "TJ", "ET", "BI", "ID", "g"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("operators = %q, want %q", got, want)
+	}
+
+	ops := parseContent(content)
+	if d := ops[0].args[1].dict; d != "<</Subtype /Watermark /Type /Pagination >>" {
+		t.Errorf("BDC dict = %q", d)
+	}
+	if cm := ops[2]; cm.number(4, 0) != 10.5 || cm.number(5, 0) != -2 {
+		t.Errorf("cm = %+v", cm.args)
+	}
+	if s := string(ops[9].args[0].str); s != "a (nested) A\nb" {
+		t.Errorf("literal string = %q", s)
+	}
+	if s := string(ops[10].args[0].str); s != "HI" {
+		t.Errorf("hex string = %q", s)
+	}
+	if a := ops[11].args[0].array; len(a) != 3 || string(a[2].str) != "y" {
+		t.Errorf("TJ array = %+v", a)
+	}
+}
+
+func TestParseContentHostileNesting(t *testing.T) {
+	deep := bytes.Repeat([]byte("["), 5_000_000)
+	if ops := parseContent(append(deep, " 0 g"...)); len(ops) != 0 {
+		t.Errorf("nesting past the cap parsed %d operations, want 0", len(ops))
+	}
+
+	stray := append(bytes.Repeat([]byte(")>]}{"), 1_000_000), " 0.5 g [[1] [2 [3]]] TJ"...)
+	ops := parseContent(stray)
+	if len(ops) != 2 || ops[0].op != "g" || ops[1].op != "TJ" {
+		t.Fatalf("operations = %+v", ops)
+	}
+	a := ops[1].args[0].array
+	if len(a) != 2 || len(a[1].array) != 2 || a[1].array[1].array[0].num != 3 {
+		t.Errorf("nested array = %+v", a)
+	}
+}
+
+func FuzzParseContent(f *testing.F) {
+	for _, seed := range []string{
+		"q 1 0 0 1 0 0 cm /GS0 gs /Fm0 Do Q",
+		"/Artifact <</Subtype /Watermark /Type /Pagination >>BDC q /Fm0 Do Q EMC",
+		"BT (unterminated \\",
+		"BT <48 49",
+		"[(a) [1 [2",
+		"<< /A << /B (x) >>",
+		strings.Repeat("[", 100) + "0 g",
+		")>]}{ 0 g",
+		"BI /W 1 /H 1 ID \x00\xffEI\x00 EI Q",
+		"BI ID",
+		"(\\0\\12\\377\\\r\n) Tj % comment",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		prev := 0
+		for _, op := range parseContent(b) {
+			if op.start < prev || op.end < op.start || op.end > len(b) {
+				t.Fatalf("operation %q spans [%d:%d] after %d in %d bytes", op.op, op.start, op.end, prev, len(b))
+			}
+			prev = op.end
+		}
+		prev = 0
+		for _, a := range watermarkArtifacts(b) {
+			if a.start < prev || a.end < a.start || a.end > len(b) {
+				t.Fatalf("artifact spans [%d:%d] after %d in %d bytes", a.start, a.end, prev, len(b))
+			}
+			prev = a.end
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package stamp
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// Passwords unlock an encrypted input PDF. Modifying a PDF whose
+// permissions forbid it needs the owner password.
+type Passwords struct {
+	User  string
+	Owner string
+}
+
+// Permissions is a set of actions a reader of an encrypted PDF is allowed
+// without the owner password.
+type Permissions int
+
+// Permissions that may be granted.
+const (
+	PermitPrint Permissions = 1 << iota
+	PermitCopy
+	PermitModify
+	PermitAnnotate
+	PermitFillForms
+	PermitAssemble
+
+	PermitAll = PermitPrint | PermitCopy | PermitModify | PermitAnnotate | PermitFillForms | PermitAssemble
+)
+
+// permissionFlags maps each permission onto the PDF permission bits that
+// grant it.
+var permissionFlags = map[Permissions]model.PermissionFlags{
+	PermitPrint:     model.PermissionPrintRev2 | model.PermissionPrintRev3,
+	PermitCopy:      model.PermissionExtract | model.PermissionExtractRev3,
+	PermitModify:    model.PermissionModify,
+	PermitAnnotate:  model.PermissionModAnnFillForm,
+	PermitFillForms: model.PermissionFillRev3,
+	PermitAssemble:  model.PermissionAssembleRev3,
+}
+
+// Encryption describes how the output PDF is encrypted. It always uses AES.
+type Encryption struct {
+	// UserPassword is needed to open the PDF; empty lets anyone open it,
+	// subject to Permissions.
+	UserPassword string
+	// OwnerPassword grants every permission.
+	OwnerPassword string
+	// KeyLength is 128 or 256 bits; zero means 256.
+	KeyLength int
+	// Permissions lists what readers may do without the owner password.
+	// The zero value permits nothing.
+	Permissions Permissions
+}
+
+// Validate reports whether e can be applied.
+func (e Encryption) Validate() error {
+	switch e.KeyLength {
+	case 0, 128, 256:
+	default:
+		return fmt.Errorf("%w: AES key length %d, want 128 or 256", errs.ErrInvalidEncryption, e.KeyLength)
+	}
+	if e.Permissions&^PermitAll != 0 {
+		return fmt.Errorf("%w: unknown permissions %#x", errs.ErrInvalidEncryption, int(e.Permissions&^PermitAll))
+	}
+	if e.UserPassword == "" && e.OwnerPassword == "" {
+		return fmt.Errorf("%w: set a user or owner password", errs.ErrInvalidEncryption)
+	}
+	return nil
+}
+
+// apply configures conf to encrypt the PDF when it is written.
+func (e Encryption) apply(conf *model.Configuration) {
+	conf.Cmd = model.ENCRYPT
+	conf.UserPW = e.UserPassword
+	conf.OwnerPW = e.OwnerPassword
+	if conf.OwnerPW == "" {
+		conf.OwnerPW = conf.UserPW
+	}
+	conf.EncryptUsingAES = true
+	conf.EncryptKeyLength = e.KeyLength
+	if conf.EncryptKeyLength == 0 {
+		conf.EncryptKeyLength = 256
+	}
+	flags := model.PermissionsNone
+	for p, f := range permissionFlags {
+		if e.Permissions&p != 0 {
+			flags |= f
+		}
+	}
+	conf.Permissions = flags
+}
+
+// newConfiguration returns a pdfcpu configuration unlocking PDFs with pw.
+func newConfiguration(pw Passwords) *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = pw.User
+	conf.OwnerPW = pw.Owner
+	return conf
+}
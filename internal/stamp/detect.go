@@ -0,0 +1,234 @@
+package stamp
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// Kind is what a detected watermark draws.
+type Kind string
+
+// Kinds of detected watermarks.
+const (
+	KindText  Kind = "text"
+	KindImage Kind = "image"
+	KindPDF   Kind = "pdf"
+)
+
+// Detected describes a watermark found on a page. The style is read back
+// from the drawing, so FontSize is the size after scaling to the page
+// rather than the size asked for.
+type Detected struct {
+	Kind     Kind
+	Text     string // lines joined by newlines; empty if the font hides it
+	Font     string
+	FontSize float64
+	Color    spec.Color
+	Opacity  float64
+	Rotation float64
+	OnTop    bool
+}
+
+// pageContent returns the resources and the decoded content of page.
+// A page without content has nil content.
+func pageContent(ctx *model.Context, page int) (types.Dict, []byte, error) {
+	d, _, inherited, err := ctx.PageDict(page, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := inherited.Resources
+	if own, err := ctx.DereferenceDict(d["Resources"]); err == nil && own != nil {
+		res = own
+	}
+	content, err := ctx.PageContent(d, page)
+	if errors.Is(err, model.ErrNoContent) {
+		return res, nil, nil
+	}
+	return res, content, err
+}
+
+// artifact is a watermark drawn in a content stream: the form or image
+// XObject form drawn with transformation matrix and graphics state gs,
+// wrapped in content[start:end].
+type artifact struct {
+	start, end int
+	matrix     [6]float64
+	gs, form   string
+}
+
+// watermarkArtifacts finds the watermarks in content. Like pdfcpu's
+// detection it looks for the artifacts pdfcpu wraps watermarks in, but
+// anywhere in the content rather than only at its ends.
+func watermarkArtifacts(content []byte) []artifact {
+	var found []artifact
+	var a artifact
+	depth := 0
+	for _, op := range parseContent(content) {
+		if depth == 0 {
+			if op.op == "BDC" && op.name(0) == "Artifact" && len(op.args) > 1 &&
+				strings.Contains(op.args[1].dict, "/Watermark") {
+				depth = 1
+				a = artifact{start: op.start, matrix: [6]float64{1, 0, 0, 1, 0, 0}}
+			}
+			continue
+		}
+		switch op.op {
+		case "BDC", "BMC":
+			depth++
+		case "EMC":
+			depth--
+			if depth == 0 {
+				a.end = op.end
+				found = append(found, a)
+			}
+		case "cm":
+			for i := range a.matrix {
+				a.matrix[i] = op.number(i, a.matrix[i])
+			}
+		case "gs":
+			a.gs = op.name(0)
+		case "Do":
+			a.form = op.name(0)
+		}
+	}
+	return found
+}
+
+// detectWatermarks lists the watermarks drawn by content, a page's content
+// with resources res, in drawing order.
+func detectWatermarks(ctx *model.Context, res types.Dict, content []byte) []Detected {
+	var found []Detected
+	for _, a := range watermarkArtifacts(content) {
+		if a.form != "" {
+			found = append(found, describeWatermark(ctx, res, a.matrix, a.gs, a.form))
+		}
+	}
+	return found
+}
+
+// describeWatermark reads the style of the watermark drawn as XObject form
+// with the transformation matrix and graphics state gs.
+func describeWatermark(ctx *model.Context, res types.Dict, matrix [6]float64, gs, form string) Detected {
+	w := Detected{
+		Kind:     KindPDF,
+		Opacity:  1,
+		Rotation: math.Round(math.Atan2(matrix[1], matrix[0])*180/math.Pi*100) / 100,
+		OnTop:    true,
+	}
+	if states, _ := ctx.DereferenceDict(res["ExtGState"]); states != nil {
+		if state, _ := ctx.DereferenceDict(states[gs]); state != nil {
+			if ca, err := ctx.DereferenceNumber(state["ca"]); err == nil {
+				w.Opacity = ca
+			}
+		}
+	}
+	xobjects, _ := ctx.DereferenceDict(res["XObject"])
+	sd, _, err := ctx.DereferenceStreamDict(xobjects[form])
+	if err != nil || sd == nil {
+		return w
+	}
+	if s := sd.NameEntry("Subtype"); s != nil && *s == "Image" {
+		w.Kind = KindImage
+		return w
+	}
+	if ocg, _ := ctx.DereferenceDict(sd.Dict["OC"]); ocg != nil {
+		if name := ocg.StringEntry("Name"); name != nil && *name == "Background" {
+			w.OnTop = false
+		}
+	}
+	if tile := tileOf(ctx, sd); tile != nil {
+		// A tiled watermark draws the same tile all over the page.
+		sd = tile
+	}
+	if err := sd.Decode(); err != nil {
+		return w
+	}
+	formRes, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+
+	var lines []string
+	var decode func([]byte) string
+	for _, op := range parseContent(sd.Content) {
+		switch op.op {
+		case "Tf":
+			w.FontSize = op.number(1, 0)
+			w.Font, decode = fontOf(ctx, formRes, op.name(0))
+		case "rg":
+			w.Color = spec.Color{R: float32(op.number(0, 0)), G: float32(op.number(1, 0)), B: float32(op.number(2, 0))}
+		case "g":
+			g := float32(op.number(0, 0))
+			w.Color = spec.Color{R: g, G: g, B: g}
+		case "Tj", "'", `"`, "TJ":
+			w.Kind = KindText
+			var line []byte
+			for _, a := range op.args {
+				switch a.kind {
+				case opString:
+					line = append(line, a.str...)
+				case opArray:
+					for _, e := range a.array {
+						line = append(line, e.str...)
+					}
+				}
+			}
+			if decode != nil {
+				lines = append(lines, decode(line))
+			}
+		case "Do":
+			if w.Kind == KindText {
+				continue
+			}
+			inner, _ := ctx.DereferenceDict(formRes["XObject"])
+			if sd, _, err := ctx.DereferenceStreamDict(inner[op.name(0)]); err == nil && sd != nil {
+				if s := sd.NameEntry("Subtype"); s != nil && *s == "Image" {
+					w.Kind = KindImage
+				}
+			}
+		}
+	}
+	w.Text = strings.Join(lines, "\n")
+	return w
+}
+
+// tileOf returns the tile drawn by the grid form sd, or nil if sd does not
+// draw a tile grid.
+func tileOf(ctx *model.Context, sd *types.StreamDict) *types.StreamDict {
+	res, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+	xobjects, _ := ctx.DereferenceDict(res["XObject"])
+	tile, _, err := ctx.DereferenceStreamDict(xobjects[tileForm])
+	if err != nil {
+		return nil
+	}
+	return tile
+}
+
+// fontOf returns the base font of the font resource name and a decoder for
+// its strings, or nil if they cannot be decoded without the font program.
+func fontOf(ctx *model.Context, res types.Dict, name string) (string, func([]byte) string) {
+	fonts, _ := ctx.DereferenceDict(res["Font"])
+	font, _ := ctx.DereferenceDict(fonts[name])
+	if font == nil {
+		return "", nil
+	}
+	var base string
+	if b := font.NameEntry("BaseFont"); b != nil {
+		base = *b
+	}
+	if s := font.NameEntry("Subtype"); s != nil && *s == "Type0" {
+		// Composite fonts draw glyph IDs.
+		return base, nil
+	}
+	return base, func(b []byte) string {
+		s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+		if err != nil {
+			return string(b)
+		}
+		return string(s)
+	}
+}
@@ -0,0 +1,77 @@
+package stamp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// Document is a PDF that can be stamped several times, as for a mail
+// merge. ReadDocument reads, validates and optimizes it once and keeps the
+// result in memory as a PDF; each Apply parses that again without
+// validating, so each output carries only its own watermarks.
+type Document struct {
+	rs        io.ReadSeeker
+	pw        Passwords
+	optimized []byte
+}
+
+// ReadDocument reads the PDF behind rs, unlocking it with pw if it is
+// encrypted. rs must stay readable while the Document is used: pages
+// without instructions are passed through from it unchanged.
+func ReadDocument(rs io.ReadSeeker, pw Passwords) (*Document, error) {
+	ctx, err := readContext(rs, pw)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, fmt.Errorf("buffering PDF: %w", err)
+	}
+	return &Document{rs: rs, pw: pw, optimized: buf.Bytes()}, nil
+}
+
+// readContext reads, validates and optimizes the PDF behind rs for
+// stamping.
+func readContext(rs io.ReadSeeker, pw Passwords) (*model.Context, error) {
+	conf := newConfiguration(pw)
+	conf.Cmd = model.ADDWATERMARKS
+	ctx, err := api.ReadValidateAndOptimize(rs, conf)
+	if err != nil {
+		return nil, readError(rs, err)
+	}
+	return ctx, nil
+}
+
+// Apply is like the package-level Apply for the document. opts.Passwords
+// is ignored; the passwords given to ReadDocument apply. Apply must not
+// be called concurrently.
+func (d *Document) Apply(ctx context.Context, w io.Writer, instructions map[int][]spec.Instruction, opts Options) error {
+	if passthrough(instructions, opts) {
+		if _, err := d.rs.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("seeking PDF: %w", err)
+		}
+		_, err := io.Copy(w, d.rs)
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("reading PDF: %w", err)
+	}
+	// The buffered PDF was validated when it was read.
+	conf := newConfiguration(d.pw)
+	conf.Cmd = model.ADDWATERMARKS
+	pdfCtx, err := api.ReadContext(bytes.NewReader(d.optimized), conf)
+	if err == nil {
+		err = pdfCtx.EnsurePageCount()
+	}
+	if err != nil {
+		return fmt.Errorf("reading buffered PDF: %w", err)
+	}
+	return stampContext(ctx, pdfCtx, w, instructions, opts)
+}
@@ -0,0 +1,97 @@
+package stamp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+func TestDocument_Apply(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 3), "user", "owner")
+	doc, err := ReadDocument(bytes.NewReader(pdf), Passwords{User: "user", Owner: "owner"})
+	if err != nil {
+		t.Fatalf("ReadDocument: %v", err)
+	}
+	opts := Options{
+		Style:  spec.DefaultStyle(),
+		Images: map[string][]byte{"logo": createTestPNG(t, 8, 8)},
+	}
+
+	// Each copy carries only its own watermarks and keeps the encryption.
+	for _, text := range []string{"Copy for Jane", "Copy for Joe", "Copy for Ann"} {
+		instructions := map[int][]spec.Instruction{
+			1: {{Text: text}},
+			3: {{Image: "logo"}},
+		}
+		var buf bytes.Buffer
+		if err := doc.Apply(context.Background(), &buf, instructions, opts); err != nil {
+			t.Fatalf("Apply(%q): %v", text, err)
+		}
+		info, err := ReadInfo(bytes.NewReader(buf.Bytes()), Passwords{User: "user"}, nil)
+		if err != nil {
+			t.Fatalf("ReadInfo(%q): %v", text, err)
+		}
+		if !info.Encrypted {
+			t.Errorf("%q: output is not encrypted", text)
+		}
+		w := info.Pages[0].Watermarks
+		if len(w) != 1 || w[0].Text != text {
+			t.Errorf("%q: page 1 watermarks = %+v", text, w)
+		}
+		if w := info.Pages[1].Watermarks; len(w) != 0 {
+			t.Errorf("%q: page 2 watermarks = %+v, want none", text, w)
+		}
+		if w := info.Pages[2].Watermarks; len(w) != 1 || w[0].Kind != KindImage {
+			t.Errorf("%q: page 3 watermarks = %+v", text, w)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Apply(context.Background(), &buf, nil, opts); err != nil {
+		t.Fatalf("Apply without instructions: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), pdf) {
+		t.Error("Apply without instructions changed the PDF")
+	}
+}
+
+// countingReader counts the reads from an io.ReadSeeker.
+type countingReader struct {
+	io.ReadSeeker
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.ReadSeeker.Read(p)
+}
+
+func TestDocument_ApplyParsesOnce(t *testing.T) {
+	src := &countingReader{ReadSeeker: bytes.NewReader(createTestPDF(t, 3))}
+	doc, err := ReadDocument(src, Passwords{})
+	if err != nil {
+		t.Fatalf("ReadDocument: %v", err)
+	}
+	if src.reads == 0 {
+		t.Fatal("ReadDocument did not read the PDF")
+	}
+
+	// Copies are stamped from the buffered read, not by reading and
+	// validating the source again.
+	src.reads = 0
+	for _, text := range []string{"Copy for Jane", "Copy for Joe"} {
+		var buf bytes.Buffer
+		instructions := map[int][]spec.Instruction{1: {{Text: text}}}
+		if err := doc.Apply(context.Background(), &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
+			t.Fatalf("Apply(%q): %v", text, err)
+		}
+		assertValidPDF(t, buf.Bytes())
+	}
+	if src.reads != 0 {
+		t.Errorf("stamping two copies read the source %d times, want 0", src.reads)
+	}
+}
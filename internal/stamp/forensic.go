@@ -0,0 +1,301 @@
+package stamp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// A forensic mark is carried by a token that seals the ID with AES-256-GCM
+// under a key derived from the caller's mark key, so it can be neither read
+// nor forged without that key, and damaged tokens fail to open. The nonce
+// is derived from the ID, so every page of a copy carries the same token.
+// The token is hex-encoded and written in two places. It is drawn as
+// invisible text (rendering mode 3) at the end of every page's content, in
+// a literal string and a WinAnsi font so that text extracted from the page
+// reads the token. It is also the XMP instance ID, which survives pages
+// being flattened or re-rendered by tools that keep the metadata. Neither
+// place names pdfmark. What the mark does and does not defend against is
+// documented on pdfmark.WithForensicMark.
+
+// MinMarkKeySize is the shortest key accepted for sealing forensic marks.
+const MinMarkKeySize = 16
+
+// tokenOverhead is the length of a token beyond the ID: nonce and tag.
+const tokenOverhead = 12 + 16
+
+// tokenString matches PDF literal strings of hex digits long enough to
+// hold a token.
+var tokenString = regexp.MustCompile(fmt.Sprintf(`\(([0-9A-Fa-f]{%d,})\)`, 2*tokenOverhead))
+
+// instanceID matches the XMP instance ID in element or attribute form.
+var instanceID = regexp.MustCompile(`<xmpMM:InstanceID>[^<]*</xmpMM:InstanceID>|xmpMM:InstanceID="[^"]*"`)
+
+// instanceToken matches tokens stored as XMP instance IDs.
+var instanceToken = regexp.MustCompile(`xmp\.iid:([0-9A-Fa-f]+)`)
+
+// subkey derives the key for purpose from the mark key.
+func subkey(key []byte, purpose string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(purpose))
+	return m.Sum(nil)
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(subkey(key, "pdfmark forensic mark encryption"))
+	if err != nil {
+		panic(err) // a SHA-256 sum is a valid AES-256 key
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// seal returns the hex-encoded token for id under key.
+func seal(key []byte, id string) string {
+	aead := newAEAD(key)
+	m := hmac.New(sha256.New, subkey(key, "pdfmark forensic mark nonce"))
+	m.Write([]byte(id))
+	nonce := m.Sum(nil)[:aead.NonceSize()]
+	return hex.EncodeToString(aead.Seal(nonce, nonce, []byte(id), nil))
+}
+
+// open returns the ID sealed in the hex-encoded token t under key.
+func open(key []byte, t string) (string, bool) {
+	b, err := hex.DecodeString(t)
+	if err != nil || len(b) < tokenOverhead {
+		return "", false
+	}
+	aead := newAEAD(key)
+	id, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return "", false
+	}
+	return string(id), true
+}
+
+// lastToken returns the ID in the last token in page content b that opens
+// under key.
+func lastToken(key []byte, b []byte) (string, bool) {
+	return lastMatch(key, tokenString.FindAllSubmatch(b, -1))
+}
+
+// lastMatch returns the ID in the last token, the first submatch of
+// matches, that opens under key.
+func lastMatch(key []byte, matches [][][]byte) (string, bool) {
+	if len(key) == 0 {
+		return "", false
+	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		if id, ok := open(key, string(matches[i][1])); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// embedMark writes the forensic mark id, sealed under key, into every page
+// and the metadata of the PDF in ctx.
+func embedMark(ctx *model.Context, key []byte, id string) error {
+	if len(key) < MinMarkKeySize {
+		return errs.ErrInvalidForensicKey
+	}
+	t := seal(key, id)
+	font, err := ctx.IndRefForNewObject(types.Dict{
+		"Type":     types.Name("Font"),
+		"Subtype":  types.Name("Type1"),
+		"BaseFont": types.Name("Helvetica"),
+		"Encoding": types.Name("WinAnsiEncoding"),
+	})
+	if err != nil {
+		return err
+	}
+	// The font's resource name comes from the token, so it differs between
+	// copies like the token itself.
+	for page := 1; page <= ctx.PageCount; page++ {
+		if err := markPage(ctx, page, *font, "F"+t[:8], t); err != nil {
+			return fmt.Errorf("marking page %d: %w", page, err)
+		}
+	}
+	if err := markMetadata(ctx, t); err != nil {
+		return fmt.Errorf("marking metadata: %w", err)
+	}
+	return nil
+}
+
+// markPage appends a content stream drawing t as invisible text in the
+// lower left corner of the page, in font under resource name fontName.
+func markPage(ctx *model.Context, page int, font types.IndirectRef, fontName, t string) error {
+	d, _, inherited, err := ctx.PageDict(page, false)
+	if err != nil {
+		return err
+	}
+
+	// Resources inherited from the page tree are copied into the page
+	// before adding to them, so the font does not shadow them.
+	res, err := ctx.DereferenceDict(d["Resources"])
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		res = types.Dict{}
+		if inherited.Resources != nil {
+			res = inherited.Resources.Clone().(types.Dict)
+		}
+		d["Resources"] = res
+	}
+	fonts, err := ctx.DereferenceDict(res["Font"])
+	if err != nil {
+		return err
+	}
+	if fonts == nil {
+		fonts = types.Dict{}
+		res["Font"] = fonts
+	}
+	fonts[fontName] = font
+
+	var x, y float64
+	if box := inherited.MediaBox; box != nil {
+		x, y = box.LL.X, box.LL.Y
+	}
+	if box := inherited.CropBox; box != nil {
+		x, y = box.LL.X, box.LL.Y
+	}
+	content := fmt.Sprintf("q BT 3 Tr /%s 1 Tf 1 0 0 1 %.2f %.2f Tm (%s) Tj ET Q", fontName, x+1, y+1, t)
+	sd, err := ctx.NewStreamDictForBuf([]byte(content))
+	if err != nil {
+		return err
+	}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	ref, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+
+	// The page's own streams are left alone; they may be shared.
+	switch contents := d["Contents"].(type) {
+	case nil:
+		d["Contents"] = *ref
+	case types.Array:
+		d["Contents"] = append(contents, *ref)
+	default:
+		o, err := ctx.Dereference(contents)
+		if err != nil {
+			return err
+		}
+		if a, ok := o.(types.Array); ok {
+			d["Contents"] = append(a, *ref)
+		} else {
+			d["Contents"] = types.Array{contents, *ref}
+		}
+	}
+	return nil
+}
+
+// markMetadata sets t as the XMP instance ID of the document, replacing
+// an existing one. Metadata that is not XMP is replaced.
+func markMetadata(ctx *model.Context, t string) error {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	id := "xmp.iid:" + t
+	desc := fmt.Sprintf("<rdf:Description rdf:about=\"\" xmlns:xmpMM=\"http://ns.adobe.com/xap/1.0/mm/\"><xmpMM:InstanceID>%s</xmpMM:InstanceID></rdf:Description>\n", id)
+
+	var xmp []byte
+	if sd, _, err := ctx.DereferenceStreamDict(root["Metadata"]); err == nil && sd != nil {
+		if err := sd.Decode(); err == nil && bytes.Contains(sd.Content, []byte("</rdf:RDF>")) {
+			xmp = instanceID.ReplaceAllFunc(sd.Content, func(m []byte) []byte {
+				if m[0] == '<' {
+					return fmt.Appendf(nil, "<xmpMM:InstanceID>%s</xmpMM:InstanceID>", id)
+				}
+				return fmt.Appendf(nil, "xmpMM:InstanceID=%q", id)
+			})
+			if bytes.Equal(xmp, sd.Content) {
+				xmp = bytes.Replace(xmp, []byte("</rdf:RDF>"), []byte(desc+"</rdf:RDF>"), 1)
+			}
+		}
+	}
+	if xmp == nil {
+		xmp = []byte("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" +
+			"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n" +
+			"<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n" +
+			desc +
+			"</rdf:RDF>\n" +
+			"</x:xmpmeta>\n" +
+			"<?xpacket end=\"w\"?>")
+	}
+
+	// XMP is left uncompressed so tools that do not parse PDF can find it.
+	sd := types.StreamDict{
+		Dict: types.Dict{
+			"Type":    types.Name("Metadata"),
+			"Subtype": types.Name("XML"),
+		},
+		Content: xmp,
+	}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	ref, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+	root["Metadata"] = *ref
+	return nil
+}
+
+// Marks holds the forensic marks found in a PDF.
+type Marks struct {
+	// Pages maps page numbers to the ID marked on them.
+	Pages map[int]string
+	// Metadata is the ID in the XMP metadata, or "".
+	Metadata string
+}
+
+// ReadMarks reads the forensic marks sealed under key from the PDF behind
+// rs, unlocking it with pw if it is encrypted. Marks sealed under another
+// key are not found. It resets the reader position to the start when done.
+func ReadMarks(rs io.ReadSeeker, pw Passwords, key []byte) (*Marks, error) {
+	ctx, err := api.ReadAndValidate(rs, newConfiguration(pw))
+	if err != nil {
+		return nil, readError(rs, err)
+	}
+	marks := &Marks{Pages: make(map[int]string)}
+	for page := 1; page <= ctx.PageCount; page++ {
+		_, content, err := pageContent(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading page %d: %w", errs.ErrInvalidPDF, page, err)
+		}
+		if id, ok := lastToken(key, content); ok {
+			marks.Pages[page] = id
+		}
+	}
+	if root, err := ctx.Catalog(); err == nil {
+		if sd, _, err := ctx.DereferenceStreamDict(root["Metadata"]); err == nil && sd != nil {
+			if err := sd.Decode(); err == nil {
+				marks.Metadata, _ = lastMatch(key, instanceToken.FindAllSubmatch(sd.Content, -1))
+			}
+		}
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking PDF: %w", err)
+	}
+	return marks, nil
+}
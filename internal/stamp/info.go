@@ -0,0 +1,73 @@
+package stamp
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// PageSize is the width and height of a page in points.
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+// Info describes a PDF document.
+type Info struct {
+	// Version is the PDF version, such as "1.7".
+	Version string
+	// Encrypted reports whether the PDF is encrypted.
+	Encrypted bool
+	// Watermarked reports whether any page carries a watermark.
+	Watermarked bool
+	// Pages describes each page, in page order.
+	Pages []PageInfo
+}
+
+// PageInfo describes a page.
+type PageInfo struct {
+	PageSize
+	// Watermarks lists the watermarks found, in drawing order.
+	Watermarks []Detected
+	// Mark is the forensic mark on the page, or "" if there is none or it
+	// was sealed under another key.
+	Mark string
+}
+
+// ReadInfo describes the PDF behind rs, unlocking it with pw if it is
+// encrypted. Forensic marks are only reported if they were sealed under
+// key. It resets the reader position to the start when done.
+func ReadInfo(rs io.ReadSeeker, pw Passwords, key []byte) (*Info, error) {
+	ctx, err := api.ReadAndValidate(rs, newConfiguration(pw))
+	if err != nil {
+		return nil, readError(rs, err)
+	}
+	dims, err := ctx.PageDims()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading page sizes: %w", errs.ErrInvalidPDF, err)
+	}
+	info := &Info{
+		Version:   ctx.XRefTable.VersionString(),
+		Encrypted: ctx.Encrypt != nil,
+		Pages:     make([]PageInfo, len(dims)),
+	}
+	for i, d := range dims {
+		res, content, err := pageContent(ctx, i+1)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading page %d: %w", errs.ErrInvalidPDF, i+1, err)
+		}
+		info.Pages[i] = PageInfo{
+			PageSize:   PageSize{Width: d.Width, Height: d.Height},
+			Watermarks: detectWatermarks(ctx, res, content),
+		}
+		info.Pages[i].Mark, _ = lastToken(key, content)
+		info.Watermarked = info.Watermarked || len(info.Pages[i].Watermarks) > 0
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking PDF: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,122 @@
+package stamp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// spillThreshold is the size above which OpenInput copies a non-seekable
+// input to a temporary file instead of holding it in memory.
+var spillThreshold int64 = 32 << 20
+
+// OpenInput returns a seekable reader over the PDF read from r, for use with
+// PageCount and Apply. The caller must call cleanup when done.
+//
+// If r supports io.ReaderAt and io.Seeker, as *os.File and *bytes.Reader do,
+// it is read in place from its current offset without copying. Otherwise up
+// to 32 MiB is buffered in memory and larger inputs are spilled to a
+// temporary file in os.TempDir.
+//
+// If limit is positive, inputs larger than limit bytes are rejected with
+// ErrInputTooLarge.
+func OpenInput(r io.Reader, limit int64) (rs io.ReadSeeker, cleanup func(), err error) {
+	if ra, ok := r.(readSeekerAt); ok {
+		rs, err := section(ra, limit)
+		switch {
+		case err == nil:
+			return rs, func() {}, nil
+		case !errors.Is(err, errNotSeekable):
+			return nil, nil, err
+		}
+		// Pipes and terminals implement the interfaces but cannot seek.
+	}
+
+	src := r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	head, err := io.ReadAll(io.LimitReader(src, spillThreshold+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading PDF input: %w", err)
+	}
+	if len(head) == 0 {
+		return nil, nil, fmt.Errorf("%w: empty input", errs.ErrInvalidPDF)
+	}
+	if int64(len(head)) <= spillThreshold {
+		if err := checkLimit(int64(len(head)), limit); err != nil {
+			return nil, nil, err
+		}
+		return bytes.NewReader(head), func() {}, nil
+	}
+	return spill(head, src, limit)
+}
+
+type readSeekerAt interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+var errNotSeekable = errors.New("input is not seekable")
+
+// section returns a reader over the rest of ra from its current offset.
+func section(ra readSeekerAt, limit int64) (io.ReadSeeker, error) {
+	cur, err := ra.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errNotSeekable
+	}
+	end, err := ra.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errNotSeekable
+	}
+	if _, err := ra.Seek(cur, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking PDF input: %w", err)
+	}
+	size := end - cur
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: empty input", errs.ErrInvalidPDF)
+	}
+	if err := checkLimit(size, limit); err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(ra, cur, size), nil
+}
+
+// spill writes head followed by the rest of r to a temporary file.
+func spill(head []byte, r io.Reader, limit int64) (io.ReadSeeker, func(), error) {
+	f, err := os.CreateTemp("", "pdfmark-*.pdf")
+	if err != nil {
+		return nil, nil, fmt.Errorf("spilling PDF input: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	n, err := io.Copy(f, io.MultiReader(bytes.NewReader(head), r))
+	if err == nil {
+		err = checkLimit(n, limit)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		if errors.Is(err, errs.ErrInputTooLarge) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("spilling PDF input: %w", err)
+	}
+	return f, cleanup, nil
+}
+
+func checkLimit(size, limit int64) error {
+	if limit > 0 && size > limit {
+		return fmt.Errorf("%w: more than %d bytes", errs.ErrInputTooLarge, limit)
+	}
+	return nil
+}
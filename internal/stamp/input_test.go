@@ -0,0 +1,127 @@
+package stamp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// onlyReader hides every method but Read, like a network stream.
+type onlyReader struct{ io.Reader }
+
+func TestOpenInput_Empty(t *testing.T) {
+	for name, r := range map[string]io.Reader{
+		"seekable": bytes.NewReader(nil),
+		"stream":   onlyReader{bytes.NewReader(nil)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := OpenInput(r, 0)
+			if !errors.Is(err, errs.ErrInvalidPDF) {
+				t.Errorf("got error %v, want ErrInvalidPDF", err)
+			}
+		})
+	}
+}
+
+func TestOpenInput_Memory(t *testing.T) {
+	data := []byte("some pdf bytes")
+	rs, cleanup, err := OpenInput(onlyReader{bytes.NewReader(data)}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if _, ok := rs.(*bytes.Reader); !ok {
+		t.Errorf("got %T, want an in-memory reader", rs)
+	}
+	out, _ := io.ReadAll(rs)
+	if !bytes.Equal(out, data) {
+		t.Errorf("got %q, want %q", out, data)
+	}
+}
+
+func TestOpenInput_InPlace(t *testing.T) {
+	path := t.TempDir() + "/in.pdf"
+	if err := os.WriteFile(path, []byte("skip:payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, cleanup, err := OpenInput(f, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if _, ok := rs.(*io.SectionReader); !ok {
+		t.Errorf("got %T, want a section of the file", rs)
+	}
+	if out, _ := io.ReadAll(rs); string(out) != "payload" {
+		t.Errorf("got %q, want %q", out, "payload")
+	}
+}
+
+func TestOpenInput_Spill(t *testing.T) {
+	defer func(old int64) { spillThreshold = old }(spillThreshold)
+	spillThreshold = 8
+
+	data := []byte("larger than the threshold")
+	rs, cleanup, err := OpenInput(onlyReader{bytes.NewReader(data)}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, ok := rs.(*os.File)
+	if !ok {
+		t.Fatalf("got %T, want a temporary file", rs)
+	}
+	out, _ := io.ReadAll(rs)
+	if !bytes.Equal(out, data) {
+		t.Errorf("got %q, want %q", out, data)
+	}
+
+	cleanup()
+	if _, err := os.Stat(f.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file %s not removed: %v", f.Name(), err)
+	}
+}
+
+func TestOpenInput_Limit(t *testing.T) {
+	data := []byte("larger than the limit")
+	for name, r := range map[string]io.Reader{
+		"seekable": bytes.NewReader(data),
+		"stream":   onlyReader{bytes.NewReader(data)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := OpenInput(r, 10)
+			if !errors.Is(err, errs.ErrInputTooLarge) {
+				t.Errorf("got error %v, want ErrInputTooLarge", err)
+			}
+		})
+	}
+
+	t.Run("spilled", func(t *testing.T) {
+		defer func(old int64) { spillThreshold = old }(spillThreshold)
+		spillThreshold = 8
+		_, _, err := OpenInput(onlyReader{bytes.NewReader(data)}, 16)
+		if !errors.Is(err, errs.ErrInputTooLarge) {
+			t.Errorf("got error %v, want ErrInputTooLarge", err)
+		}
+	})
+
+	t.Run("at limit", func(t *testing.T) {
+		_, cleanup, err := OpenInput(onlyReader{bytes.NewReader(data)}, int64(len(data)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cleanup()
+	})
+}
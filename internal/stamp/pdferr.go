@@ -0,0 +1,82 @@
+package stamp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// tailSize is how far from the end readError looks for the %%EOF marker.
+const tailSize = 1024
+
+// headSize is how far from the start readError looks for the header.
+const headSize = 1024
+
+// readError classifies err, a pdfcpu failure to read the PDF behind rs, as
+// ErrEncryptedPDF, ErrUnsupportedPDFVersion or ErrTruncatedPDF where the
+// cause is known and as ErrInvalidPDF otherwise. err stays in the chain.
+func readError(rs io.ReadSeeker, err error) error {
+	return fmt.Errorf("%w: %w", readErrorKind(rs, err), err)
+}
+
+func readErrorKind(rs io.ReadSeeker, err error) error {
+	switch {
+	case errors.Is(err, pdfcpu.ErrWrongPassword):
+		return errs.ErrEncryptedPDF
+	case errors.Is(err, pdfcpu.ErrUnsupportedVersion), unknownVersion(rs):
+		return errs.ErrUnsupportedPDFVersion
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), truncated(rs):
+		return errs.ErrTruncatedPDF
+	}
+	return errs.ErrInvalidPDF
+}
+
+// unknownVersion reports whether rs has a %PDF- header whose version pdfcpu
+// does not know. pdfcpu fails on such headers without a sentinel error, so
+// the header is checked again here.
+func unknownVersion(rs io.ReadSeeker) bool {
+	defer rs.Seek(0, io.SeekStart)
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	head, err := io.ReadAll(io.LimitReader(rs, headSize))
+	if err != nil {
+		return false
+	}
+	i := bytes.Index(head, []byte("%PDF-"))
+	if i < 0 || len(head) < i+len("%PDF-x.y") {
+		return false
+	}
+	_, err = model.PDFVersion(string(head[i+len("%PDF-") : i+len("%PDF-x.y")]))
+	return err != nil
+}
+
+// truncated reports whether rs starts like a PDF but lacks the %%EOF
+// marker that ends every complete one.
+func truncated(rs io.ReadSeeker) bool {
+	defer rs.Seek(0, io.SeekStart)
+
+	head := make([]byte, len("%PDF-"))
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(rs, head); err != nil || string(head) != "%PDF-" {
+		return false
+	}
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false
+	}
+	if _, err := rs.Seek(max(size-tailSize, 0), io.SeekStart); err != nil {
+		return false
+	}
+	tail, err := io.ReadAll(rs)
+	return err == nil && !bytes.Contains(tail, []byte("%%EOF"))
+}
@@ -0,0 +1,172 @@
+package stamp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// Remove reads the PDF from rs, strips the watermarks from pages and
+// writes the result to w. Forensic marks are kept. The caller must have
+// validated that all page numbers are in range. If none of pages carries
+// a watermark, Remove fails with ErrNoWatermark and writes nothing.
+// opts.Passwords and opts.Encrypt apply as for Apply; the other options
+// are ignored.
+func Remove(rs io.ReadSeeker, w io.Writer, pages []int, opts Options) error {
+	conf := newConfiguration(opts.Passwords)
+	pdfCtx, err := api.ReadValidateAndOptimize(rs, conf)
+	if err != nil {
+		return readError(rs, err)
+	}
+	n, err := stripWatermarks(pdfCtx, pages)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errs.ErrNoWatermark
+	}
+	if opts.Encrypt != nil {
+		opts.Encrypt.apply(pdfCtx.Configuration)
+	}
+	return api.Write(pdfCtx, w, conf)
+}
+
+// stripWatermarks removes the watermarks from pages and returns how many
+// were removed.
+func stripWatermarks(ctx *model.Context, pages []int) (int, error) {
+	total := 0
+	for _, page := range pages {
+		n, err := stripPage(ctx, page)
+		if err != nil {
+			return 0, fmt.Errorf("removing watermarks from page %d: %w", page, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// stripPage cuts the watermarks out of every content stream of page, not
+// only its first and last as pdfcpu does, and drops the resources they
+// drew from the page's resources. Content streams shared with other pages
+// are stripped for those pages too.
+func stripPage(ctx *model.Context, page int) (int, error) {
+	d, _, inherited, err := ctx.PageDict(page, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var refs []types.IndirectRef
+	contents := d["Contents"]
+	if ref, ok := contents.(types.IndirectRef); ok {
+		refs = append(refs, ref)
+		o, err := ctx.Dereference(ref)
+		if err != nil {
+			return 0, err
+		}
+		if a, ok := o.(types.Array); ok {
+			refs, contents = nil, a
+		}
+	}
+	if a, ok := contents.(types.Array); ok {
+		for _, o := range a {
+			if ref, ok := o.(types.IndirectRef); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	var removed []artifact
+	for _, ref := range refs {
+		entry, ok := ctx.FindTableEntryForIndRef(&ref)
+		if !ok || entry == nil {
+			continue
+		}
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok {
+			continue
+		}
+		if err := sd.Decode(); errors.Is(err, filter.ErrUnsupportedFilter) {
+			// Content pdfcpu cannot decode cannot hold its watermarks.
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		found := watermarkArtifacts(sd.Content)
+		if len(found) == 0 {
+			continue
+		}
+		var b bytes.Buffer
+		last := 0
+		for _, a := range found {
+			b.Write(sd.Content[last:a.start])
+			last = a.end
+		}
+		b.Write(sd.Content[last:])
+		sd.Content = b.Bytes()
+		if err := sd.Encode(); err != nil {
+			return 0, err
+		}
+		entry.Object = sd
+		removed = append(removed, found...)
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+
+	// pdfcpu only makes resource names unique within a dict, and the page
+	// may share its dicts with other pages that still draw the names. The
+	// page gets its own copies of the dicts it drops names from.
+	res := d.DictEntry("Resources")
+	if res == nil {
+		shared := inherited.Resources
+		if own, err := ctx.DereferenceDict(d["Resources"]); err == nil && own != nil {
+			shared = own
+		}
+		if shared == nil {
+			return len(removed), nil
+		}
+		res = shared.Clone().(types.Dict)
+		d.Update("Resources", res)
+	}
+	states, err := ownDict(ctx, res, "ExtGState")
+	if err != nil {
+		return 0, err
+	}
+	xobjects, err := ownDict(ctx, res, "XObject")
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range removed {
+		if a.gs != "" && states != nil {
+			states.Delete(a.gs)
+		}
+		if a.form != "" && xobjects != nil {
+			xobjects.Delete(a.form)
+		}
+	}
+	return len(removed), nil
+}
+
+// ownDict returns the dict under key in res for editing, copying it into
+// res first if it is an indirect object another page may share. It
+// returns nil if res has no such dict.
+func ownDict(ctx *model.Context, res types.Dict, key string) (types.Dict, error) {
+	if d, ok := res[key].(types.Dict); ok {
+		return d, nil
+	}
+	shared, err := ctx.DereferenceDict(res[key])
+	if err != nil || shared == nil {
+		return nil, err
+	}
+	d := shared.Clone().(types.Dict)
+	res[key] = d
+	return d, nil
+}
@@ -0,0 +1,65 @@
+package stamp
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+func TestStripPage_SharedResources(t *testing.T) {
+	var buf bytes.Buffer
+	instructions := map[int][]spec.Instruction{1: {{Text: "ONE"}}, 2: {{Text: "TWO"}}}
+	if err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 2)), &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	ctx, err := api.ReadAndValidate(bytes.NewReader(buf.Bytes()), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both pages draw their watermark by the same names. Moving page 1's
+	// resources, XObjects included, into shared objects hands them to
+	// page 2 as well.
+	p1, _, _, err := ctx.PageDict(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _, _, err := ctx.PageDict(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ctx.DereferenceDict(p1["Resources"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	xobjects, err := ctx.IndRefForNewObject(res["XObject"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	res["XObject"] = *xobjects
+	shared, err := ctx.IndRefForNewObject(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1["Resources"], p2["Resources"] = *shared, *shared
+
+	if n, err := stripPage(ctx, 1); n != 1 || err != nil {
+		t.Fatalf("stripPage = %d, %v; want 1 watermark removed", n, err)
+	}
+	res2, content, err := pageContent(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := detectWatermarks(ctx, res2, content)
+	if len(w) != 1 || w[0].Text != "ONE" {
+		t.Errorf("page 2 watermarks = %+v, want the shared form still there", w)
+	}
+	if res1, _, _ := pageContent(ctx, 1); res1["XObject"] == nil {
+		t.Error("page 1 lost its XObjects, want its own copy without the watermark")
+	}
+}
@@ -1,66 +1,363 @@
-// Package stamp applies text watermarks to PDF pages using pdfcpu.
+// Package stamp applies text, image and PDF-page watermarks to PDF pages
+// using pdfcpu.
 package stamp
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
+	"slices"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/color"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/draw"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 
 	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
 )
 
-// NewTextWatermark builds a pdfcpu Watermark for the given text with the
-// library's fixed default style: centered, diagonal, semi-transparent gray.
-func NewTextWatermark(text string) *model.Watermark {
+// NewTextWatermark builds a pdfcpu Watermark for the given text drawn with
+// style s.
+func NewTextWatermark(text string, s spec.Style) *model.Watermark {
 	wm := model.DefaultWatermarkConfig()
 	wm.Mode = model.WMText
 	wm.TextString = text
 	wm.TextLines = []string{text}
-	wm.OnTop = true
-	wm.Pos = types.Center
-	wm.Diagonal = model.DiagonalLLToUR
+	applyStyle(wm, s)
+	return wm
+}
+
+// applyStyle copies the fields of s onto wm.
+func applyStyle(wm *model.Watermark, s spec.Style) {
+	wm.OnTop = s.OnTop
+	wm.Pos = anchors[s.Position]
+	wm.Dx = s.Dx
+	wm.Dy = s.Dy
 	wm.UserRotOrDiagonal = true
-	wm.Opacity = 0.3
-	wm.FontName = "Helvetica"
-	wm.FontSize = 48
-	wm.Scale = 1.0
-	wm.ScaleAbs = false
-	wm.Color = color.Gray
-	wm.FillColor = color.Gray
-	wm.StrokeColor = color.Gray
+	if s.Diagonal != spec.NoDiagonal {
+		wm.Diagonal = int(s.Diagonal)
+		wm.Rotation = 0
+	} else {
+		wm.Diagonal = model.NoDiagonal
+		wm.Rotation = s.Rotation
+	}
+	wm.Opacity = s.Opacity
+	wm.FontName = s.FontName
+	wm.FontSize = s.FontSize
+	wm.Scale = s.Scale
+	wm.ScaleAbs = s.ScaleMode == spec.ScaleAbsolute
+	c := color.SimpleColor{R: s.Color.R, G: s.Color.G, B: s.Color.B}
+	wm.Color = c
+	wm.FillColor = c
+	wm.StrokeColor = c
+	wm.RenderMode = draw.RenderMode(s.RenderMode)
+	if p, ok := presets[s.Position]; ok {
+		wm.Pos = p.anchor
+		wm.Diagonal = model.NoDiagonal
+		wm.Rotation = p.rotation
+		wm.Dx = s.Dx + p.dx*s.Margin
+		wm.Dy = s.Dy + p.dy*s.Margin
+		if wm.Mode == model.WMText {
+			// Relative scaling would stretch a header across the page.
+			wm.FontSize = fixedSize(s)
+			wm.Scale = 1
+			wm.ScaleAbs = true
+		}
+	}
+}
+
+// fixedSize returns the font size of text that is not scaled to the page:
+// the font size, scaled only by an absolute scale.
+func fixedSize(s spec.Style) int {
+	if s.ScaleMode != spec.ScaleAbsolute {
+		return s.FontSize
+	}
+	return max(int(math.Round(float64(s.FontSize)*s.Scale)), 1)
+}
+
+// anchors maps spec positions onto pdfcpu anchors.
+var anchors = map[spec.Position]types.Anchor{
+	spec.Center:       types.Center,
+	spec.TopLeft:      types.TopLeft,
+	spec.TopCenter:    types.TopCenter,
+	spec.TopRight:     types.TopRight,
+	spec.Left:         types.Left,
+	spec.Right:        types.Right,
+	spec.BottomLeft:   types.BottomLeft,
+	spec.BottomCenter: types.BottomCenter,
+	spec.BottomRight:  types.BottomRight,
+}
+
+// presets maps the header, footer and margin positions onto a pdfcpu
+// anchor, the rotation of the text and the direction in which the margin
+// moves it away from the page edge. pdfcpu places the anchor on the crop
+// box as the page is viewed, so presets follow page rotation.
+var presets = map[spec.Position]struct {
+	anchor   types.Anchor
+	rotation float64
+	dx, dy   float64
+}{
+	spec.Header:      {types.TopCenter, 0, 0, -1},
+	spec.HeaderLeft:  {types.TopLeft, 0, 1, -1},
+	spec.HeaderRight: {types.TopRight, 0, -1, -1},
+	spec.Footer:      {types.BottomCenter, 0, 0, 1},
+	spec.FooterLeft:  {types.BottomLeft, 0, 1, 1},
+	spec.FooterRight: {types.BottomRight, 0, -1, 1},
+	spec.LeftMargin:  {types.Left, 90, 1, 0},
+	spec.RightMargin: {types.Right, -90, -1, 0},
+}
+
+// Options configures Apply.
+type Options struct {
+	// Style is the base style; each instruction's override is applied on
+	// top of it.
+	Style spec.Style
+	// Images holds the contents of the image files referenced by
+	// instructions, keyed by reference.
+	Images map[string][]byte
+	// PDFs holds the contents of the stamp PDFs referenced by instructions,
+	// keyed by reference.
+	PDFs map[string][]byte
+	// Passwords unlock an encrypted input PDF.
+	Passwords Passwords
+	// Encrypt, if set, encrypts the output PDF. It replaces any encryption
+	// of the input.
+	Encrypt *Encryption
+	// Mark, if set, is sealed under MarkKey and written into every page and
+	// the metadata as an invisible forensic mark; ReadMarks recovers it.
+	Mark string
+	// MarkKey is the key sealing Mark, at least MinMarkKeySize bytes long.
+	MarkKey []byte
+	// Strip lists pages whose existing watermarks are removed before
+	// stamping, as Remove does.
+	Strip []int
+}
+
+// NewImageWatermark builds a pdfcpu Watermark stamping the image in data
+// with style s. Font settings in s are ignored.
+func NewImageWatermark(data []byte, s spec.Style) *model.Watermark {
+	wm := model.DefaultWatermarkConfig()
+	wm.Mode = model.WMImage
+	wm.Image = bytes.NewReader(data)
+	applyStyle(wm, s)
+	return wm
+}
+
+// NewPDFWatermark builds a pdfcpu Watermark stamping page (1-indexed) of the
+// PDF in data with style s. Font settings in s are ignored.
+func NewPDFWatermark(data []byte, page int, s spec.Style) *model.Watermark {
+	wm := model.DefaultWatermarkConfig()
+	wm.Mode = model.WMPDF
+	wm.PDF = bytes.NewReader(data)
+	wm.PdfPageNrSrc = page
+	applyStyle(wm, s)
 	return wm
 }
 
 // Apply reads the PDF from rs, stamps pages according to instructions
-// (page number -> watermark text), and writes the result to w.
-// The caller must have already validated that all page numbers are in range.
-func Apply(rs io.ReadSeeker, w io.Writer, instructions map[int]string) error {
-	if len(instructions) == 0 {
+// (page number -> instructions), and writes the result to w. Several
+// instructions for one page are stacked in slice order, the first one
+// lowest. The caller must have already validated that all page numbers are
+// in range. ctx is checked before reading the PDF, between watermarks and
+// before writing; once done, Apply returns an error wrapping ctx.Err().
+//
+// An encrypted input stays encrypted with its own passwords unless
+// opts.Encrypt replaces them.
+func Apply(ctx context.Context, rs io.ReadSeeker, w io.Writer, instructions map[int][]spec.Instruction, opts Options) error {
+	if passthrough(instructions, opts) {
 		_, err := io.Copy(w, rs)
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("reading PDF: %w", err)
+	}
+	pdfCtx, err := readContext(rs, opts.Passwords)
+	if err != nil {
+		return err
+	}
+	return stampContext(ctx, pdfCtx, w, instructions, opts)
+}
+
+// passthrough reports whether Apply leaves the PDF as it is.
+func passthrough(instructions map[int][]spec.Instruction, opts Options) bool {
+	return len(instructions) == 0 && opts.Encrypt == nil && opts.Mark == "" && len(opts.Strip) == 0
+}
+
+// stampContext stamps pdfCtx as Apply describes and writes it to w.
+func stampContext(ctx context.Context, pdfCtx *model.Context, w io.Writer, instructions map[int][]spec.Instruction, opts Options) error {
+	// Pages sharing the same watermark at the same stack position share
+	// one pdfcpu watermark, so its image or form is embedded once.
+	groups := make(map[stampKey]types.IntSet)
+	for page, list := range instructions {
+		for layer, ins := range list {
+			k := stampKey{
+				layer:   layer,
+				text:    ins.Text,
+				image:   ins.Image,
+				pdf:     ins.PDF,
+				pdfPage: ins.PDFPage,
+				style:   ins.Style.Apply(opts.Style),
+			}
+			if groups[k] == nil {
+				groups[k] = types.IntSet{}
+			}
+			groups[k][page] = true
+		}
+	}
+
+	if _, err := stripWatermarks(pdfCtx, opts.Strip); err != nil {
+		return err
+	}
+
+	// The mark goes first: pdfcpu only recognizes watermarks at the ends
+	// of a page's content, and appends them to its last stream.
+	if opts.Mark != "" {
+		if err := embedMark(pdfCtx, opts.MarkKey, opts.Mark); err != nil {
+			return fmt.Errorf("applying forensic mark: %w", err)
+		}
+	}
 
-	wmMap := make(map[int]*model.Watermark, len(instructions))
-	for page, text := range instructions {
-		wmMap[page] = NewTextWatermark(text)
+	keys := sortedKeys(groups)
+	for len(keys) > 0 {
+		n := 1
+		for n < len(keys) && keys[n].layer == keys[0].layer {
+			n++
+		}
+		if err := stampLayer(ctx, pdfCtx, keys[:n], groups, opts); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("writing PDF: %w", err)
+	}
+	if opts.Encrypt != nil {
+		opts.Encrypt.apply(pdfCtx.Configuration)
+	}
+	return api.Write(pdfCtx, w, pdfCtx.Configuration)
+}
+
+// stampLayer applies the watermarks of one stack position, keys, each on
+// the pages groups holds for it. Text watermarks sharing opacity and
+// layering go into a single pdfcpu call, which creates their font dicts and
+// graphics state once: templated text renders differently on every page and
+// would otherwise embed them once per page.
+func stampLayer(ctx context.Context, pdfCtx *model.Context, keys []stampKey, groups map[stampKey]types.IntSet, opts Options) error {
+	type batchKey struct {
+		onTop   bool
+		opacity float64
+	}
+	batches := make(map[batchKey]map[int][]*model.Watermark)
+	var order []batchKey
+	for _, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("applying watermarks: %w", err)
+		}
+		if k.style.Position == spec.Tiled {
+			if k.image != "" || k.pdf != "" {
+				return fmt.Errorf("%w: only text can be tiled", errs.ErrInvalidStyle)
+			}
+			if err := addTiles(pdfCtx, groups[k], k.text, k.style); err != nil {
+				return err
+			}
+			continue
+		}
+		wm, err := k.watermark(opts)
+		if err != nil {
+			return err
+		}
+		if wm.IsText() {
+			b := batchKey{wm.OnTop, wm.Opacity}
+			if batches[b] == nil {
+				batches[b] = make(map[int][]*model.Watermark)
+				order = append(order, b)
+			}
+			for page := range groups[k] {
+				batches[b][page] = []*model.Watermark{wm}
+			}
+			continue
+		}
+		if err := pdfcpu.AddWatermarks(pdfCtx, groups[k], wm); err != nil {
+			if errors.Is(err, pdfcpu.ErrUnsupportedVersion) {
+				return fmt.Errorf("stamp PDF %q: %w: %w", k.pdf, errs.ErrUnsupportedPDFVersion, err)
+			}
+			return fmt.Errorf("applying watermarks: %w", err)
+		}
+	}
+	for _, b := range order {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("applying watermarks: %w", err)
+		}
+		if err := pdfcpu.AddWatermarksSliceMap(pdfCtx, batches[b]); err != nil {
+			return fmt.Errorf("applying watermarks: %w", err)
+		}
+	}
+	return nil
+}
+
+// stampKey identifies one watermark look at one stack position.
+type stampKey struct {
+	layer   int
+	text    string
+	image   string
+	pdf     string
+	pdfPage int
+	style   spec.Style
+}
+
+func (k stampKey) watermark(opts Options) (*model.Watermark, error) {
+	switch {
+	case k.image != "":
+		data, ok := opts.Images[k.image]
+		if !ok {
+			return nil, fmt.Errorf("%w: image %q", errs.ErrAssetNotFound, k.image)
+		}
+		return NewImageWatermark(data, k.style), nil
+	case k.pdf != "":
+		data, ok := opts.PDFs[k.pdf]
+		if !ok {
+			return nil, fmt.Errorf("%w: PDF %q", errs.ErrAssetNotFound, k.pdf)
+		}
+		return NewPDFWatermark(data, max(k.pdfPage, 1), k.style), nil
 	}
+	return NewTextWatermark(k.text, k.style), nil
+}
 
-	conf := model.NewDefaultConfiguration()
-	return api.AddWatermarksMap(rs, w, wmMap, conf)
+// sortedKeys orders groups by stack position so stacks keep their input
+// order, then by lowest page.
+func sortedKeys(m map[stampKey]types.IntSet) []stampKey {
+	keys := make([]stampKey, 0, len(m))
+	first := make(map[stampKey]int, len(m))
+	for k, pages := range m {
+		keys = append(keys, k)
+		first[k] = slices.Min(slices.Collect(maps.Keys(pages)))
+	}
+	slices.SortFunc(keys, func(a, b stampKey) int {
+		if c := cmp.Compare(a.layer, b.layer); c != 0 {
+			return c
+		}
+		return cmp.Compare(first[a], first[b])
+	})
+	return keys
 }
 
-// PageCount returns the number of pages in the PDF behind rs.
-// It resets the reader position to the start after counting.
-func PageCount(rs io.ReadSeeker) (int, error) {
-	conf := model.NewDefaultConfiguration()
+// PageCount returns the number of pages in the PDF behind rs, unlocking it
+// with pw if it is encrypted. It resets the reader position to the start
+// after counting.
+func PageCount(rs io.ReadSeeker, pw Passwords) (int, error) {
+	conf := newConfiguration(pw)
 	n, err := api.PageCount(rs, conf)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %v", errs.ErrInvalidPDF, err)
+		return 0, readError(rs, err)
 	}
 	if _, err := rs.Seek(0, io.SeekStart); err != nil {
 		return 0, fmt.Errorf("seeking PDF: %w", err)
@@ -68,25 +365,38 @@ func PageCount(rs io.ReadSeeker) (int, error) {
 	return n, nil
 }
 
-// ValidatePages checks that every page referenced in instructions exists
-// within a PDF of totalPages pages.
-func ValidatePages(instructions map[int]string, totalPages int) error {
-	for page := range instructions {
-		if page > totalPages {
-			return fmt.Errorf("%w: page %d, PDF has %d pages", errs.ErrPageOutOfRange, page, totalPages)
-		}
+// StampPageCount returns the number of pages in data, a PDF to be used as
+// a watermark. pdfcpu cannot stamp pages of PDF 2.0 files, so those fail
+// with ErrUnsupportedPDFVersion.
+func StampPageCount(data []byte) (int, error) {
+	rs := bytes.NewReader(data)
+	ctx, err := api.ReadAndValidate(rs, model.NewDefaultConfiguration())
+	if err != nil {
+		return 0, readError(rs, err)
 	}
-	return nil
+	if ctx.XRefTable.Version() == model.V20 {
+		return 0, fmt.Errorf("%w: PDF 2.0 cannot be used as a stamp", errs.ErrUnsupportedPDFVersion)
+	}
+	return ctx.PageCount, nil
 }
 
-// BufferReader reads all of r into a bytes.Reader so pdfcpu can seek.
-func BufferReader(r io.Reader) (*bytes.Reader, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return nil, fmt.Errorf("reading PDF input: %w", err)
-	}
-	if len(data) == 0 {
-		return nil, fmt.Errorf("%w: empty input", errs.ErrInvalidPDF)
+// ValidatePages checks that every page referenced in instructions exists
+// within a PDF of totalPages pages. Every instruction selecting a missing
+// page is reported, joined into one error.
+func ValidatePages(instructions map[int][]spec.Instruction, totalPages int) error {
+	var problems []*errs.InstructionError
+	for _, page := range slices.Sorted(maps.Keys(instructions)) {
+		if page >= 1 && page <= totalPages {
+			continue
+		}
+		for _, ins := range instructions[page] {
+			problems = append(problems, &errs.InstructionError{
+				Err:    errs.ErrPageOutOfRange,
+				Line:   ins.Line,
+				Page:   page,
+				Detail: fmt.Sprintf("PDF has %d pages", totalPages),
+			})
+		}
 	}
-	return bytes.NewReader(data), nil
+	return errs.Join(problems)
 }
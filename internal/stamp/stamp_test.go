@@ -2,21 +2,34 @@ package stamp
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
 	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
 )
 
 func TestApply_SinglePage(t *testing.T) {
 	pdf := createTestPDF(t, 3)
 	rs := bytes.NewReader(pdf)
 
-	instructions := map[int]string{2: "CONFIDENTIAL"}
+	instructions := map[int][]spec.Instruction{2: {{Text: "CONFIDENTIAL"}}}
 
 	var buf bytes.Buffer
-	if err := Apply(rs, &buf, instructions); err != nil {
+	if err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
 		t.Fatalf("Apply: %v", err)
 	}
 
@@ -28,14 +41,14 @@ func TestApply_MultiplePages(t *testing.T) {
 	pdf := createTestPDF(t, 5)
 	rs := bytes.NewReader(pdf)
 
-	instructions := map[int]string{
-		1: "DRAFT",
-		3: "CONFIDENTIAL",
-		5: "INTERNAL",
+	instructions := map[int][]spec.Instruction{
+		1: {{Text: "DRAFT"}},
+		3: {{Text: "CONFIDENTIAL"}},
+		5: {{Text: "INTERNAL"}},
 	}
 
 	var buf bytes.Buffer
-	if err := Apply(rs, &buf, instructions); err != nil {
+	if err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
 		t.Fatalf("Apply: %v", err)
 	}
 
@@ -48,7 +61,54 @@ func TestApply_NoInstructions(t *testing.T) {
 	rs := bytes.NewReader(pdf)
 
 	var buf bytes.Buffer
-	if err := Apply(rs, &buf, map[int]string{}); err != nil {
+	if err := Apply(context.Background(), rs, &buf, map[int][]spec.Instruction{}, Options{Style: spec.DefaultStyle()}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertValidPDF(t, buf.Bytes())
+	assertPageCount(t, buf.Bytes(), 2)
+}
+
+func TestApply_CustomStyle(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	rs := bytes.NewReader(pdf)
+
+	s := spec.DefaultStyle()
+	s.FontName = "Courier"
+	s.FontSize = 24
+	s.Color = spec.Red
+	s.Opacity = 0.8
+	s.Diagonal = spec.NoDiagonal
+	s.Rotation = 90
+	s.Position = spec.BottomRight
+	s.Dx, s.Dy = -10, 10
+	s.OnTop = false
+	s.Scale = 2
+	s.ScaleMode = spec.ScaleAbsolute
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, map[int][]spec.Instruction{1: {{Text: "STYLED"}}}, Options{Style: s}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertValidPDF(t, buf.Bytes())
+	assertPageCount(t, buf.Bytes(), 2)
+}
+
+func TestApply_StyleOverride(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	rs := bytes.NewReader(pdf)
+
+	size := 12
+	pos := spec.BottomCenter
+	mode := spec.RenderStroke
+	instructions := map[int][]spec.Instruction{
+		1: {{Text: "DEFAULT"}},
+		2: {{Text: "FOOTER", Style: spec.StyleOverride{FontSize: &size, Position: &pos, RenderMode: &mode}}},
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
 		t.Fatalf("Apply: %v", err)
 	}
 
@@ -56,15 +116,38 @@ func TestApply_NoInstructions(t *testing.T) {
 	assertPageCount(t, buf.Bytes(), 2)
 }
 
+func TestNewTextWatermark_Style(t *testing.T) {
+	s := spec.DefaultStyle()
+	s.Diagonal = spec.NoDiagonal
+	s.Rotation = 30
+	s.Position = spec.TopLeft
+	s.OnTop = false
+	s.ScaleMode = spec.ScaleAbsolute
+
+	wm := NewTextWatermark("X", s)
+	if wm.Diagonal != model.NoDiagonal || wm.Rotation != 30 {
+		t.Errorf("diagonal/rotation = %d/%g, want none/30", wm.Diagonal, wm.Rotation)
+	}
+	if wm.Pos != types.TopLeft {
+		t.Errorf("pos = %v, want top left", wm.Pos)
+	}
+	if wm.OnTop {
+		t.Error("OnTop = true, want false")
+	}
+	if !wm.ScaleAbs {
+		t.Error("ScaleAbs = false, want true")
+	}
+}
+
 func TestValidatePages_InRange(t *testing.T) {
-	instructions := map[int]string{1: "A", 3: "B", 5: "C"}
+	instructions := map[int][]spec.Instruction{1: {{Text: "A"}}, 3: {{Text: "B"}}, 5: {{Text: "C"}}}
 	if err := ValidatePages(instructions, 5); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
 
 func TestValidatePages_OutOfRange(t *testing.T) {
-	instructions := map[int]string{1: "A", 6: "B"}
+	instructions := map[int][]spec.Instruction{1: {{Text: "A"}}, 6: {{Text: "B"}}}
 	err := ValidatePages(instructions, 5)
 	if !errors.Is(err, errs.ErrPageOutOfRange) {
 		t.Errorf("got error %v, want ErrPageOutOfRange", err)
@@ -75,7 +158,7 @@ func TestPageCount_Valid(t *testing.T) {
 	for _, n := range []int{1, 3, 5} {
 		pdf := createTestPDF(t, n)
 		rs := bytes.NewReader(pdf)
-		got, err := PageCount(rs)
+		got, err := PageCount(rs, Passwords{})
 		if err != nil {
 			t.Fatalf("PageCount(%d): %v", n, err)
 		}
@@ -87,27 +170,639 @@ func TestPageCount_Valid(t *testing.T) {
 
 func TestPageCount_InvalidPDF(t *testing.T) {
 	rs := bytes.NewReader([]byte("not a pdf"))
-	_, err := PageCount(rs)
+	_, err := PageCount(rs, Passwords{})
 	if !errors.Is(err, errs.ErrInvalidPDF) {
 		t.Errorf("got error %v, want ErrInvalidPDF", err)
 	}
 }
 
-func TestBufferReader_Empty(t *testing.T) {
-	_, err := BufferReader(bytes.NewReader(nil))
-	if !errors.Is(err, errs.ErrInvalidPDF) {
-		t.Errorf("got error %v, want ErrInvalidPDF", err)
+func TestValidatePages_BeforeFirst(t *testing.T) {
+	instructions := map[int][]spec.Instruction{-1: {{Text: "A"}}}
+	err := ValidatePages(instructions, 5)
+	if !errors.Is(err, errs.ErrPageOutOfRange) {
+		t.Errorf("got error %v, want ErrPageOutOfRange", err)
+	}
+}
+
+func TestApply_PerInstructionOpacity(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	rs := bytes.NewReader(pdf)
+
+	low, high := 0.2, 0.9
+	instructions := map[int][]spec.Instruction{
+		1: {{Text: "LOW", Style: spec.StyleOverride{Opacity: &low}}},
+		2: {{Text: "HIGH", Style: spec.StyleOverride{Opacity: &high}}},
+		3: {{Text: "DEFAULT"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertValidPDF(t, buf.Bytes())
+
+	got := stampOpacities(t, buf.Bytes())
+	for _, want := range []float64{0.2, 0.3, 0.9} {
+		if !slices.Contains(got, want) {
+			t.Errorf("opacities %v do not contain %g", got, want)
+		}
+	}
+}
+
+func TestApply_Layered(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	rs := bytes.NewReader(pdf)
+
+	size := 10
+	pos := spec.BottomCenter
+	instructions := map[int][]spec.Instruction{
+		1: {
+			{Text: "CONFIDENTIAL"},
+			{Text: "Copy for Jane Doe", Style: spec.StyleOverride{FontSize: &size, Position: &pos}},
+		},
+		2: {{Text: "CONFIDENTIAL"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertValidPDF(t, buf.Bytes())
+
+	if n := pageXObjects(t, buf.Bytes(), 1); n != 2 {
+		t.Errorf("page 1 has %d watermark forms, want 2", n)
+	}
+	if n := pageXObjects(t, buf.Bytes(), 2); n != 1 {
+		t.Errorf("page 2 has %d watermark forms, want 1", n)
+	}
+}
+
+func TestApply_TemplatedTextSharesResources(t *testing.T) {
+	pdf := createTestPDF(t, 6)
+
+	// Rendered page numbers give every page its own text.
+	instructions := make(map[int][]spec.Instruction)
+	for page := 1; page <= 6; page++ {
+		instructions[page] = []spec.Instruction{
+			{Text: "CONFIDENTIAL"},
+			{Text: fmt.Sprintf("Page %d of 6", page)},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), bytes.NewReader(pdf), &buf, instructions, Options{Style: spec.DefaultStyle()}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertValidPDF(t, buf.Bytes())
+
+	ctx, err := api.ReadAndValidate(bytes.NewReader(buf.Bytes()), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, entry := range ctx.XRefTable.Table {
+		switch o := entry.Object.(type) {
+		case types.Dict:
+			if o.Type() != nil {
+				counts[*o.Type()]++
+			}
+		case types.StreamDict:
+			if o.Subtype() != nil {
+				counts[*o.Subtype()]++
+			}
+		}
+	}
+	// One graphics state and font per layer, and one form for the shared
+	// text besides one per page number.
+	if counts["ExtGState"] != 2 || counts["Font"] != 2 || counts["Form"] != 7 {
+		t.Errorf("object counts = %v, want 2 ExtGState, 2 Font and 7 Form", counts)
+	}
+}
+
+func TestApply_Image(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	rs := bytes.NewReader(pdf)
+
+	scale := 0.25
+	pos := spec.TopRight
+	logo := spec.Instruction{
+		Image: "logo.png",
+		Style: spec.StyleOverride{Scale: &scale, Position: &pos},
+	}
+	instructions := map[int][]spec.Instruction{
+		1: {logo},
+		2: {{Text: "DRAFT"}},
+		3: {logo},
+	}
+	opts := Options{
+		Style:  spec.DefaultStyle(),
+		Images: map[string][]byte{"logo.png": createTestPNG(t, 40, 20)},
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, instructions, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertValidPDF(t, buf.Bytes())
+	assertPageCount(t, buf.Bytes(), 3)
+	if n := countImages(t, buf.Bytes()); n != 1 {
+		t.Errorf("PDF embeds %d images, want 1 shared image", n)
+	}
+}
+
+func TestApply_MissingImage(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	rs := bytes.NewReader(pdf)
+
+	instructions := map[int][]spec.Instruction{1: {{Image: "missing.png"}}}
+
+	var buf bytes.Buffer
+	err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()})
+	if !errors.Is(err, errs.ErrAssetNotFound) {
+		t.Errorf("got error %v, want ErrAssetNotFound", err)
+	}
+}
+
+func TestApply_PDFStamp(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	rs := bytes.NewReader(pdf)
+
+	instructions := map[int][]spec.Instruction{
+		1: {{PDF: "letterhead.pdf", PDFPage: 1}},
+		2: {{PDF: "letterhead.pdf", PDFPage: 2}},
+		3: {{Text: "DRAFT"}},
+	}
+	opts := Options{
+		Style: spec.DefaultStyle(),
+		PDFs:  map[string][]byte{"letterhead.pdf": createTestPDF(t, 2)},
+	}
+
+	var buf bytes.Buffer
+	if err := Apply(context.Background(), rs, &buf, instructions, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertValidPDF(t, buf.Bytes())
+	assertPageCount(t, buf.Bytes(), 3)
+	for page := 1; page <= 2; page++ {
+		if n := pageXObjects(t, buf.Bytes(), page); n != 1 {
+			t.Errorf("page %d has %d watermark forms, want 1", page, n)
+		}
+	}
+}
+
+func TestApply_MissingPDFStamp(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	rs := bytes.NewReader(pdf)
+
+	instructions := map[int][]spec.Instruction{1: {{PDF: "missing.pdf", PDFPage: 1}}}
+
+	var buf bytes.Buffer
+	err := Apply(context.Background(), rs, &buf, instructions, Options{Style: spec.DefaultStyle()})
+	if !errors.Is(err, errs.ErrAssetNotFound) {
+		t.Errorf("got error %v, want ErrAssetNotFound", err)
+	}
+}
+
+func TestApply_Canceled(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	err := Apply(ctx, bytes.NewReader(pdf), &buf, map[int][]spec.Instruction{1: {{Text: "DRAFT"}}}, Options{Style: spec.DefaultStyle()})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes after cancellation", buf.Len())
+	}
+}
+
+func TestPageCount_Encrypted(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 3), "user", "owner")
+	if _, err := PageCount(bytes.NewReader(pdf), Passwords{}); !errors.Is(err, errs.ErrEncryptedPDF) {
+		t.Errorf("without password: got error %v, want ErrEncryptedPDF", err)
+	}
+	got, err := PageCount(bytes.NewReader(pdf), Passwords{User: "user"})
+	if err != nil {
+		t.Fatalf("PageCount: %v", err)
+	}
+	if got != 3 {
+		t.Errorf("PageCount = %d, want 3", got)
+	}
+}
+
+func TestApply_EncryptedInput(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 2), "user", "owner")
+	instructions := map[int][]spec.Instruction{1: {{Text: "SECRET"}}}
+	var buf bytes.Buffer
+	err := Apply(context.Background(), bytes.NewReader(pdf), &buf, instructions, Options{
+		Style:     spec.DefaultStyle(),
+		Passwords: Passwords{Owner: "owner"},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, ok := testutil.PDFPermissions(t, buf.Bytes(), "user", "owner"); !ok {
+		t.Error("output is not encrypted, want the input's encryption kept")
+	}
+}
+
+func TestApply_Encrypt(t *testing.T) {
+	for _, keyLength := range []int{128, 256} {
+		instructions := map[int][]spec.Instruction{1: {{Text: "SECRET"}}}
+		var buf bytes.Buffer
+		err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 2)), &buf, instructions, Options{
+			Style: spec.DefaultStyle(),
+			Encrypt: &Encryption{
+				UserPassword:  "user",
+				OwnerPassword: "owner",
+				KeyLength:     keyLength,
+				Permissions:   PermitPrint,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Apply(AES-%d): %v", keyLength, err)
+		}
+		if _, err := PageCount(bytes.NewReader(buf.Bytes()), Passwords{}); !errors.Is(err, errs.ErrEncryptedPDF) {
+			t.Errorf("AES-%d: reading without password: got error %v, want ErrEncryptedPDF", keyLength, err)
+		}
+		perms, ok := testutil.PDFPermissions(t, buf.Bytes(), "user", "")
+		if !ok {
+			t.Fatalf("AES-%d: output is not encrypted", keyLength)
+		}
+		if perms&model.PermissionPrintRev3 == 0 {
+			t.Errorf("AES-%d: printing not permitted", keyLength)
+		}
+		if perms&(model.PermissionExtractRev3|model.PermissionModify) != 0 {
+			t.Errorf("AES-%d: permissions %#x allow copying or modifying", keyLength, perms)
+		}
 	}
 }
 
-func TestBufferReader_Valid(t *testing.T) {
-	data := []byte("some pdf bytes")
-	rs, err := BufferReader(bytes.NewReader(data))
+func TestApply_EncryptWithoutInstructions(t *testing.T) {
+	var buf bytes.Buffer
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 1)), &buf, nil, Options{
+		Style:   spec.DefaultStyle(),
+		Encrypt: &Encryption{UserPassword: "user"},
+	})
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, ok := testutil.PDFPermissions(t, buf.Bytes(), "user", ""); !ok {
+		t.Error("output is not encrypted")
 	}
-	out, _ := io.ReadAll(rs)
-	if !bytes.Equal(out, data) {
-		t.Errorf("got %q, want %q", out, data)
+}
+
+func TestEncryption_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Encryption
+		ok   bool
+	}{
+		{"defaults", Encryption{OwnerPassword: "o"}, true},
+		{"aes128", Encryption{UserPassword: "u", KeyLength: 128}, true},
+		{"bad key length", Encryption{UserPassword: "u", KeyLength: 40}, false},
+		{"no password", Encryption{Permissions: PermitAll}, false},
+		{"unknown permission", Encryption{UserPassword: "u", Permissions: 1 << 10}, false},
+	}
+	for _, tt := range tests {
+		err := tt.e.Validate()
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, errs.ErrInvalidEncryption) {
+			t.Errorf("%s: got error %v, want ErrInvalidEncryption", tt.name, err)
+		}
+	}
+}
+
+func TestPageCount_ErrorKinds(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"encrypted", testutil.EncryptPDF(t, pdf, "user", "owner"), errs.ErrEncryptedPDF},
+		{"version", bytes.Replace(pdf, []byte("%PDF-1."), []byte("%PDF-3."), 1), errs.ErrUnsupportedPDFVersion},
+		{"minor version", bytes.Replace(pdf, []byte("%PDF-1.7"), []byte("%PDF-1.9"), 1), errs.ErrUnsupportedPDFVersion},
+		{"version after junk", append([]byte("junk\n"), bytes.Replace(pdf, []byte("%PDF-1."), []byte("%PDF-3."), 1)...), errs.ErrUnsupportedPDFVersion},
+		{"truncated tail", pdf[:len(pdf)-10], errs.ErrTruncatedPDF},
+		{"truncated half", pdf[:len(pdf)/2], errs.ErrTruncatedPDF},
+		{"truncated header", pdf[:20], errs.ErrTruncatedPDF},
+		{"garbage", []byte("not a pdf"), errs.ErrInvalidPDF},
+	}
+	for _, tt := range tests {
+		_, err := PageCount(bytes.NewReader(tt.data), Passwords{})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+		if !errors.Is(err, errs.ErrInvalidPDF) {
+			t.Errorf("%s: error %v does not match ErrInvalidPDF", tt.name, err)
+		}
+	}
+}
+
+func TestPageCount_WrapsCause(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 1), "user", "owner")
+	_, err := PageCount(bytes.NewReader(pdf), Passwords{User: "wrong"})
+	if !errors.Is(err, pdfcpu.ErrWrongPassword) {
+		t.Errorf("got error %v, want it to wrap pdfcpu.ErrWrongPassword", err)
+	}
+}
+
+func TestStampPageCount_PDF20(t *testing.T) {
+	pdf := bytes.Replace(createTestPDF(t, 1), []byte("%PDF-1.7"), []byte("%PDF-2.0"), 1)
+	if _, err := StampPageCount(pdf); !errors.Is(err, errs.ErrUnsupportedPDFVersion) {
+		t.Errorf("got error %v, want ErrUnsupportedPDFVersion", err)
+	}
+	n, err := StampPageCount(createTestPDF(t, 2))
+	if err != nil || n != 2 {
+		t.Errorf("StampPageCount = %d, %v, want 2, nil", n, err)
+	}
+}
+
+// testMarkKey seals the forensic marks of the tests.
+var testMarkKey = []byte("0123456789abcdef")
+
+func TestApply_Mark(t *testing.T) {
+	var buf bytes.Buffer
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 3)), &buf, nil, Options{
+		Style:   spec.DefaultStyle(),
+		Mark:    "jane@example.com",
+		MarkKey: testMarkKey,
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertValidPDF(t, buf.Bytes())
+
+	marks, err := ReadMarks(bytes.NewReader(buf.Bytes()), Passwords{}, testMarkKey)
+	if err != nil {
+		t.Fatalf("ReadMarks: %v", err)
+	}
+	if len(marks.Pages) != 3 || marks.Pages[2] != "jane@example.com" || marks.Metadata != "jane@example.com" {
+		t.Errorf("marks = %+v", marks)
+	}
+
+	// Neither the ID nor the tool shows in the page content or metadata.
+	ctx, err := api.ReadAndValidate(bytes.NewReader(buf.Bytes()), newConfiguration(Passwords{}))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	_, content, err := pageContent(ctx, 1)
+	if err != nil {
+		t.Fatalf("reading page 1: %v", err)
+	}
+	for _, b := range [][]byte{content, buf.Bytes()} {
+		for _, s := range []string{"jane@example.com", hex.EncodeToString([]byte("jane@example.com")), "pdfmark", "Pdfmark"} {
+			if bytes.Contains(b, []byte(s)) {
+				t.Errorf("output contains %q", s)
+			}
+		}
+	}
+
+	// Text extracted from the page reads the token.
+	res, _, err := pageContent(ctx, 1)
+	if err != nil {
+		t.Fatalf("reading page 1: %v", err)
+	}
+	var text []string
+	var decode func([]byte) string
+	for _, op := range parseContent(content) {
+		switch op.op {
+		case "Tf":
+			_, decode = fontOf(ctx, res, op.name(0))
+		case "Tj":
+			if decode != nil && len(op.args) > 0 {
+				text = append(text, decode(op.args[0].str))
+			}
+		}
+	}
+	if len(text) == 0 {
+		t.Fatal("no text on page 1")
+	}
+	if id, ok := open(testMarkKey, text[len(text)-1]); !ok || id != "jane@example.com" {
+		t.Errorf("extracted text %q opens to %q, %v; want the token", text[len(text)-1], id, ok)
+	}
+
+	// Another key finds no mark.
+	marks, err = ReadMarks(bytes.NewReader(buf.Bytes()), Passwords{}, []byte("fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("ReadMarks: %v", err)
+	}
+	if len(marks.Pages) != 0 || marks.Metadata != "" {
+		t.Errorf("marks under another key = %+v, want none", marks)
+	}
+
+	// Marking again replaces the mark.
+	var again bytes.Buffer
+	err = Apply(context.Background(), bytes.NewReader(buf.Bytes()), &again, nil, Options{
+		Style:   spec.DefaultStyle(),
+		Mark:    "joe@example.com",
+		MarkKey: testMarkKey,
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	marks, err = ReadMarks(bytes.NewReader(again.Bytes()), Passwords{}, testMarkKey)
+	if err != nil {
+		t.Fatalf("ReadMarks: %v", err)
+	}
+	if marks.Pages[1] != "joe@example.com" || marks.Metadata != "joe@example.com" {
+		t.Errorf("after marking again: marks = %+v", marks)
+	}
+}
+
+func TestApply_MarkShortKey(t *testing.T) {
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 1)), io.Discard, nil, Options{
+		Style:   spec.DefaultStyle(),
+		Mark:    "jane@example.com",
+		MarkKey: []byte("short"),
+	})
+	if !errors.Is(err, errs.ErrInvalidForensicKey) {
+		t.Errorf("got error %v, want ErrInvalidForensicKey", err)
+	}
+}
+
+func TestLastToken(t *testing.T) {
+	good := seal(testMarkKey, "jane@example.com")
+	if id, ok := lastToken(testMarkKey, []byte("BT ("+good+") Tj ET")); !ok || id != "jane@example.com" {
+		t.Errorf("lastToken(%q) = %q, %v", good, id, ok)
+	}
+	last := "0"
+	if strings.HasSuffix(good, last) {
+		last = "1"
+	}
+	damaged := good[:len(good)-1] + last
+	if id, ok := lastToken(testMarkKey, []byte("("+damaged+")")); ok {
+		t.Errorf("lastToken(%q) = %q, want no mark", damaged, id)
+	}
+	if id, ok := lastToken(nil, []byte("("+good+")")); ok {
+		t.Errorf("lastToken without key = %q, want no mark", id)
+	}
+}
+
+func TestMarkMetadata_InstanceID(t *testing.T) {
+	ctx, err := api.ReadAndValidate(bytes.NewReader(createTestPDF(t, 1)), newConfiguration(Passwords{}))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/" xmpMM:InstanceID="uuid:1234"/>` +
+		`</rdf:RDF></x:xmpmeta>`
+	ref, err := ctx.IndRefForNewObject(types.StreamDict{Dict: types.Dict{"Type": types.Name("Metadata")}, Content: []byte(xmp)})
+	if err != nil {
+		t.Fatalf("adding metadata: %v", err)
+	}
+	root, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("reading catalog: %v", err)
+	}
+	root["Metadata"] = *ref
+
+	tok := seal(testMarkKey, "jane@example.com")
+	if err := markMetadata(ctx, tok); err != nil {
+		t.Fatalf("markMetadata: %v", err)
+	}
+	sd, _, err := ctx.DereferenceStreamDict(ctx.RootDict["Metadata"])
+	if err != nil || sd == nil {
+		t.Fatalf("reading metadata: %v", err)
+	}
+	if err := sd.Decode(); err != nil {
+		t.Fatalf("decoding metadata: %v", err)
+	}
+	want := `xmpMM:InstanceID="xmp.iid:` + tok + `"`
+	if got := string(sd.Content); strings.Count(got, "InstanceID") != 1 || !strings.Contains(got, want) {
+		t.Errorf("metadata = %s, want the instance ID replaced by %s", got, want)
+	}
+}
+
+func TestReadInfo_Watermarks(t *testing.T) {
+	style := spec.DefaultStyle()
+	style.Color = spec.Red
+	style.Opacity = 0.5
+	faint := 0.2
+	instructions := map[int][]spec.Instruction{
+		1: {{Text: "CONFIDENTIAL"}},
+		2: {{Text: "FAINT", Style: spec.StyleOverride{Opacity: &faint}}},
+		3: {{Image: "logo"}},
+	}
+	var buf bytes.Buffer
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 4)), &buf, instructions, Options{
+		Style:   style,
+		Images:  map[string][]byte{"logo": testutil.CreateTestPNG(t, 20, 20)},
+		Mark:    "jane@example.com",
+		MarkKey: testMarkKey,
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	info, err := ReadInfo(bytes.NewReader(buf.Bytes()), Passwords{}, testMarkKey)
+	if err != nil {
+		t.Fatalf("ReadInfo: %v", err)
+	}
+	if !info.Watermarked || len(info.Pages) != 4 {
+		t.Fatalf("info = %+v", info)
+	}
+	if w := info.Pages[0].Watermarks; len(w) != 1 || w[0].Kind != KindText || w[0].Text != "CONFIDENTIAL" ||
+		w[0].Font != "Helvetica" || w[0].Color != spec.Red || w[0].Opacity != 0.5 || !w[0].OnTop {
+		t.Errorf("page 1 watermarks = %+v", w)
+	}
+	if w := info.Pages[1].Watermarks; len(w) != 1 || w[0].Text != "FAINT" || w[0].Opacity != 0.2 {
+		t.Errorf("page 2 watermarks = %+v", w)
+	}
+	if w := info.Pages[2].Watermarks; len(w) != 1 || w[0].Kind != KindImage {
+		t.Errorf("page 3 watermarks = %+v", w)
+	}
+	if w := info.Pages[3].Watermarks; len(w) != 0 {
+		t.Errorf("page 4 watermarks = %+v, want none", w)
+	}
+	if info.Pages[3].Mark != "jane@example.com" {
+		t.Errorf("page 4 mark = %q", info.Pages[3].Mark)
+	}
+}
+
+func TestReadInfo_Underlay(t *testing.T) {
+	style := spec.DefaultStyle()
+	style.OnTop = false
+	style.Diagonal = spec.NoDiagonal
+	style.Rotation = 30
+	var buf bytes.Buffer
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 1)), &buf,
+		map[int][]spec.Instruction{1: {{Text: "UNDER"}}}, Options{Style: style})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	info, err := ReadInfo(bytes.NewReader(buf.Bytes()), Passwords{}, nil)
+	if err != nil {
+		t.Fatalf("ReadInfo: %v", err)
+	}
+	if w := info.Pages[0].Watermarks; len(w) != 1 || w[0].OnTop || w[0].Rotation != 30 {
+		t.Errorf("watermarks = %+v, want one underlay rotated by 30", w)
+	}
+}
+
+func TestApply_Presets(t *testing.T) {
+	pdf := mixedSizePDF(t)
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1 }
+	for _, pos := range []spec.Position{
+		spec.Header, spec.HeaderLeft, spec.HeaderRight,
+		spec.Footer, spec.FooterLeft, spec.FooterRight,
+		spec.LeftMargin, spec.RightMargin,
+	} {
+		t.Run(pos.String(), func(t *testing.T) {
+			style := spec.DefaultStyle()
+			style.Position = pos
+			style.Margin = 30
+			instructions := map[int][]spec.Instruction{}
+			for page := 1; page <= 4; page++ {
+				instructions[page] = []spec.Instruction{{Text: "Company Confidential"}}
+			}
+			var buf bytes.Buffer
+			if err := Apply(context.Background(), bytes.NewReader(pdf), &buf, instructions, Options{Style: style}); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+
+			viewports, boxes := tileBoxes(t, buf.Bytes())
+			for i, vp := range viewports {
+				if len(boxes[i]) != 1 {
+					t.Fatalf("page %d: %d watermarks, want 1", i+1, len(boxes[i]))
+				}
+				r := types.Rectangle{LL: boxes[i][0][0], UR: boxes[i][0][0]}
+				for _, p := range boxes[i][0] {
+					r.LL.X, r.LL.Y = min(r.LL.X, p.X), min(r.LL.Y, p.Y)
+					r.UR.X, r.UR.Y = max(r.UR.X, p.X), max(r.UR.Y, p.Y)
+				}
+				cx, cy := (r.LL.X+r.UR.X)/2, (r.LL.Y+r.UR.Y)/2
+				vx, vy := vp.LL.X+vp.Width()/2, vp.LL.Y+vp.Height()/2
+				var ok bool
+				switch pos {
+				case spec.Header:
+					ok = near(r.UR.Y, vp.UR.Y-30) && near(cx, vx)
+				case spec.HeaderLeft:
+					ok = near(r.UR.Y, vp.UR.Y-30) && near(r.LL.X, vp.LL.X+30)
+				case spec.HeaderRight:
+					ok = near(r.UR.Y, vp.UR.Y-30) && near(r.UR.X, vp.UR.X-30)
+				case spec.Footer:
+					ok = near(r.LL.Y, vp.LL.Y+30) && near(cx, vx)
+				case spec.FooterLeft:
+					ok = near(r.LL.Y, vp.LL.Y+30) && near(r.LL.X, vp.LL.X+30)
+				case spec.FooterRight:
+					ok = near(r.LL.Y, vp.LL.Y+30) && near(r.UR.X, vp.UR.X-30)
+				case spec.LeftMargin:
+					ok = near(r.LL.X, vp.LL.X+30) && near(cy, vy) && r.Height() > r.Width()
+				case spec.RightMargin:
+					ok = near(r.UR.X, vp.UR.X-30) && near(cy, vy) && r.Height() > r.Width()
+				}
+				if !ok {
+					t.Errorf("page %d: watermark at %v on %v", i+1, r, vp)
+				}
+			}
+		})
 	}
 }
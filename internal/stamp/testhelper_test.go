@@ -1,8 +1,13 @@
 package stamp
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
 	"github.com/anujkumar-df/pdfmark/internal/testutil"
 )
 
@@ -16,7 +21,74 @@ func assertValidPDF(t *testing.T, data []byte) {
 	testutil.AssertValidPDF(t, data)
 }
 
+func createTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	return testutil.CreateTestPNG(t, w, h)
+}
+
 func assertPageCount(t *testing.T, data []byte, expected int) {
 	t.Helper()
 	testutil.AssertPageCount(t, data, expected)
 }
+
+// stampOpacities returns the fill opacity of every ExtGState in the PDF.
+func stampOpacities(t *testing.T, data []byte) []float64 {
+	t.Helper()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	var opacities []float64
+	for _, entry := range ctx.XRefTable.Table {
+		d, ok := entry.Object.(types.Dict)
+		if !ok || d.Type() == nil || *d.Type() != "ExtGState" {
+			continue
+		}
+		if f, ok := d["ca"].(types.Float); ok {
+			opacities = append(opacities, f.Value())
+		}
+	}
+	return opacities
+}
+
+// pageXObjects returns the number of XObjects in the resources of page.
+func pageXObjects(t *testing.T, data []byte, page int) int {
+	t.Helper()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	_, _, inh, err := ctx.PageDict(page, false)
+	if err != nil {
+		t.Fatalf("page %d: %v", page, err)
+	}
+	if inh.Resources == nil {
+		return 0
+	}
+	o, ok := inh.Resources.Find("XObject")
+	if !ok {
+		return 0
+	}
+	d, err := ctx.DereferenceDict(o)
+	if err != nil {
+		t.Fatalf("page %d XObjects: %v", page, err)
+	}
+	return len(d)
+}
+
+// countImages returns the number of image XObjects in the PDF.
+func countImages(t *testing.T, data []byte) int {
+	t.Helper()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	n := 0
+	for _, entry := range ctx.XRefTable.Table {
+		sd, ok := entry.Object.(types.StreamDict)
+		if ok && sd.Subtype() != nil && *sd.Subtype() == "Image" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,223 @@
+package stamp
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// Tiles are stamped as one pdfcpu watermark per page, so they are
+// recognized, inspected and removed like any other watermark. pdfcpu draws
+// the first tile; its form is then moved into a form of its own, named
+// tileForm, which the watermark's form draws at every grid point computed
+// from the page's viewport. Pages of the same size share the forms.
+
+// maxTiles caps the tiles drawn on a page, so a tiny font with no gaps
+// cannot blow the grid up to millions of tiles.
+const maxTiles = 1000
+
+// tileForm names the form of a single tile in the resources of the form
+// that draws the grid.
+const tileForm = "Tile"
+
+// addTiles stamps text tiled with style s on pages.
+func addTiles(ctx *model.Context, pages types.IntSet, text string, s spec.Style) error {
+	// Relative scaling would make tiles as wide as the page.
+	size := fixedSize(s)
+	w := font.TextWidth(text, s.FontName, size)
+	h := font.LineHeight(s.FontName, size)
+
+	// Pages are grouped by viewport. Stamping the first tile turns the
+	// page rotation into a transformation of the content, which keeps the
+	// viewport computed here.
+	groups := make(map[types.Rectangle]types.IntSet)
+	for page := range pages {
+		_, _, inherited, err := ctx.PageDict(page, false)
+		if err != nil {
+			return fmt.Errorf("reading page %d: %w", page, err)
+		}
+		vp := viewport(inherited)
+		if groups[vp] == nil {
+			groups[vp] = types.IntSet{}
+		}
+		groups[vp][page] = true
+	}
+	viewports := make([]types.Rectangle, 0, len(groups))
+	for vp := range groups {
+		viewports = append(viewports, vp)
+	}
+	slices.SortFunc(viewports, func(a, b types.Rectangle) int {
+		return cmp.Compare(slices.Min(slices.Collect(maps.Keys(groups[a]))), slices.Min(slices.Collect(maps.Keys(groups[b]))))
+	})
+
+	for _, vp := range viewports {
+		rotation := tileRotation(vp, s)
+		tile := s
+		tile.Position = spec.Center
+		tile.Diagonal = spec.NoDiagonal
+		tile.Rotation = rotation
+		tile.FontSize = size
+		tile.Scale = 1
+		tile.ScaleMode = spec.ScaleAbsolute
+		centers, err := tileCenters(vp, w, h, rotation, s)
+		if err != nil {
+			return err
+		}
+		tile.Dx = centers[0].X - (vp.LL.X + vp.Width()/2)
+		tile.Dy = centers[0].Y - (vp.LL.Y + vp.Height()/2)
+		wm := NewTextWatermark(text, tile)
+		if err := pdfcpu.AddWatermarks(ctx, groups[vp], wm); err != nil {
+			return fmt.Errorf("applying watermarks: %w", err)
+		}
+		// Text forms are cached by bounding box, so the pages of a group
+		// share one.
+		for _, ir := range wm.FCache {
+			if err := tileGrid(ctx, *ir, centers, rotation); err != nil {
+				return fmt.Errorf("applying watermarks: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// tileGrid turns the form at ir, drawn centered on centers[0] turned by
+// rotation degrees, into one that draws it centered on each of centers.
+func tileGrid(ctx *model.Context, ir types.IndirectRef, centers []types.Point, rotation float64) error {
+	entry, ok := ctx.FindTableEntryForIndRef(&ir)
+	if !ok || entry == nil {
+		return fmt.Errorf("missing tile form %s", ir)
+	}
+	sd, ok := entry.Object.(types.StreamDict)
+	if !ok {
+		return fmt.Errorf("tile form %s is not a stream", ir)
+	}
+	bbox, err := ctx.RectForArray(sd.ArrayEntry("BBox"))
+	if err != nil {
+		return fmt.Errorf("tile form %s: %w", ir, err)
+	}
+	tile, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+
+	// The offsets between the centers are taken into the tile's
+	// coordinates, which the page turns by rotation.
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	var b bytes.Buffer
+	grid := *bbox
+	for _, p := range centers {
+		dx, dy := p.X-centers[0].X, p.Y-centers[0].Y
+		tx, ty := dx*cos+dy*sin, dy*cos-dx*sin
+		fmt.Fprintf(&b, "q 1 0 0 1 %.5f %.5f cm /%s Do Q\n", tx, ty, tileForm)
+		grid.LL.X = min(grid.LL.X, bbox.LL.X+tx)
+		grid.LL.Y = min(grid.LL.Y, bbox.LL.Y+ty)
+		grid.UR.X = max(grid.UR.X, bbox.UR.X+tx)
+		grid.UR.Y = max(grid.UR.Y, bbox.UR.Y+ty)
+	}
+
+	outer, err := ctx.NewStreamDictForBuf(b.Bytes())
+	if err != nil {
+		return err
+	}
+	outer.InsertName("Type", "XObject")
+	outer.InsertName("Subtype", "Form")
+	outer.Insert("BBox", grid.Array())
+	outer.Insert("Resources", types.Dict{"XObject": types.Dict{tileForm: *tile}})
+	if oc, ok := sd.Find("OC"); ok {
+		outer.Insert("OC", oc)
+	}
+	if err := outer.Encode(); err != nil {
+		return err
+	}
+	entry.Object = *outer
+	return nil
+}
+
+// viewport returns the visible area of a page as pdfcpu sees it when
+// stamping: the crop box, or media box if it has none, turned by the page
+// rotation.
+func viewport(a *model.InheritedPageAttrs) types.Rectangle {
+	var vp types.Rectangle
+	switch {
+	case a.CropBox != nil:
+		vp = *a.CropBox
+	case a.MediaBox != nil:
+		vp = *a.MediaBox
+	}
+	if a.Rotate%180 != 0 {
+		w := vp.Width()
+		vp.UR.X = vp.LL.X + vp.Height()
+		vp.UR.Y = vp.LL.Y + w
+	}
+	return vp
+}
+
+// tileRotation returns the angle tiles are drawn at on viewport vp.
+func tileRotation(vp types.Rectangle, s spec.Style) float64 {
+	switch s.Diagonal {
+	case spec.DiagonalLLToUR:
+		return math.Atan2(vp.Height(), vp.Width()) * 180 / math.Pi
+	case spec.DiagonalULToLR:
+		return -math.Atan2(vp.Height(), vp.Width()) * 180 / math.Pi
+	}
+	return s.Rotation
+}
+
+// tileCenters lays out the grid of tiles w by h points, turned by rotation
+// degrees, on viewport vp, and returns their centers row by row from the
+// top. The grid is centered within the margins and then moved by s.Dx and
+// s.Dy. At least one tile is placed, even if it does not fit. Tiles with
+// no room between them, or more than maxTiles of them, fail with
+// ErrInvalidStyle.
+func tileCenters(vp types.Rectangle, w, h, rotation float64, s spec.Style) ([]types.Point, error) {
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	ew := w*math.Abs(cos) + h*math.Abs(sin)
+	eh := w*math.Abs(sin) + h*math.Abs(cos)
+	px, py := ew+s.Tile.GapX, eh+s.Tile.GapY
+	if px <= 0 || py <= 0 {
+		return nil, fmt.Errorf("%w: tile pitch must be > 0, got %g and %g", errs.ErrInvalidStyle, px, py)
+	}
+
+	areaW := vp.Width() - 2*s.Tile.Margin
+	areaH := vp.Height() - 2*s.Tile.Margin
+	count := func(space, extent, pitch float64) float64 {
+		if space < extent {
+			return 1
+		}
+		// The epsilon keeps exact fits from being lost to rounding.
+		return math.Floor((space-extent)/pitch+1e-9) + 1
+	}
+	// The counts stay floats until capped, as a tiny pitch overflows int.
+	fcols, frows := count(areaW, ew, px), count(areaH, eh, py)
+	if fcols*frows > maxTiles {
+		return nil, fmt.Errorf("%w: %g by %g tiles on a %gx%g page, at most %d allowed", errs.ErrInvalidStyle, fcols, frows, vp.Width(), vp.Height(), maxTiles)
+	}
+	cols, rows := int(fcols), int(frows)
+
+	x0 := vp.LL.X + vp.Width()/2 - float64(cols-1)*px/2 + s.Dx
+	y0 := vp.LL.Y + vp.Height()/2 + float64(rows-1)*py/2 + s.Dy
+	var centers []types.Point
+	for row := range rows {
+		y := y0 - float64(row)*py
+		n, x := cols, x0
+		if s.Tile.Stagger && row%2 == 1 && cols > 1 {
+			// Shifted rows fit one tile less.
+			n, x = cols-1, x0+px/2
+		}
+		for col := range n {
+			centers = append(centers, types.Point{X: x + float64(col)*px, Y: y})
+		}
+	}
+	return centers, nil
+}
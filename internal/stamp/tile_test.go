@@ -0,0 +1,231 @@
+package stamp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+)
+
+// mixedSizePDF returns a PDF whose pages are A4 portrait, a landscape
+// letter page, an A4 page cropped to its upper left quarter, and an A4
+// page rotated by 90 degrees.
+func mixedSizePDF(t *testing.T) []byte {
+	t.Helper()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(createTestPDF(t, 4)), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := func(n int) types.Dict {
+		d, _, _, err := ctx.PageDict(n, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	page(2)["MediaBox"] = types.RectForDim(792, 612).Array()
+	page(3)["CropBox"] = types.NewRectangle(0, 421, 298, 842).Array()
+	page(4)["Rotate"] = types.Integer(90)
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tileBoxes returns the viewport of each page of data and the corners of
+// the watermarks drawn on it, one per tile of a tiled watermark.
+func tileBoxes(t *testing.T, data []byte) ([]types.Rectangle, [][][4]types.Point) {
+	t.Helper()
+	ctx, err := api.ReadAndValidate(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var viewports []types.Rectangle
+	var tiles [][][4]types.Point
+	for page := 1; page <= ctx.PageCount; page++ {
+		_, _, inherited, err := ctx.PageDict(page, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		viewports = append(viewports, viewport(inherited))
+		res, content, err := pageContent(ctx, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xobjects, _ := ctx.DereferenceDict(res["XObject"])
+		var boxes [][4]types.Point
+		for _, a := range watermarkArtifacts(content) {
+			sd, _, err := ctx.DereferenceStreamDict(xobjects[a.form])
+			if err != nil || sd == nil {
+				t.Fatalf("page %d: form %s: %v", page, a.form, err)
+			}
+			// A tile grid offsets its tile by a translation for each Do.
+			offsets := [][2]float64{{0, 0}}
+			if tile := tileOf(ctx, sd); tile != nil {
+				if err := sd.Decode(); err != nil {
+					t.Fatal(err)
+				}
+				offsets = nil
+				var tx, ty float64
+				for _, op := range parseContent(sd.Content) {
+					switch op.op {
+					case "cm":
+						tx, ty = op.number(4, 0), op.number(5, 0)
+					case "Do":
+						offsets = append(offsets, [2]float64{tx, ty})
+					}
+				}
+				sd = tile
+			}
+			bbox, err := ctx.RectForArray(sd.ArrayEntry("BBox"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := a.matrix
+			for _, d := range offsets {
+				var corners [4]types.Point
+				for i, p := range []types.Point{bbox.LL, {X: bbox.UR.X, Y: bbox.LL.Y}, bbox.UR, {X: bbox.LL.X, Y: bbox.UR.Y}} {
+					p.X, p.Y = p.X+d[0], p.Y+d[1]
+					corners[i] = types.Point{X: m[0]*p.X + m[2]*p.Y + m[4], Y: m[1]*p.X + m[3]*p.Y + m[5]}
+				}
+				boxes = append(boxes, corners)
+			}
+		}
+		tiles = append(tiles, boxes)
+	}
+	return viewports, tiles
+}
+
+func TestApply_Tiled(t *testing.T) {
+	style := spec.DefaultStyle()
+	style.Position = spec.Tiled
+	style.FontSize = 24
+	style.Diagonal = spec.NoDiagonal
+	style.Rotation = 30
+	style.Tile = spec.Tiling{GapX: 40, GapY: 30, Margin: 20}
+
+	var buf bytes.Buffer
+	instructions := map[int][]spec.Instruction{}
+	for page := 1; page <= 4; page++ {
+		instructions[page] = []spec.Instruction{{Text: "CONFIDENTIAL"}}
+	}
+	if err := Apply(context.Background(), bytes.NewReader(mixedSizePDF(t)), &buf, instructions, Options{Style: style}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertValidPDF(t, buf.Bytes())
+
+	viewports, tiles := tileBoxes(t, buf.Bytes())
+	for i, vp := range viewports {
+		if len(tiles[i]) < 2 {
+			t.Errorf("page %d: %d tiles, want several", i+1, len(tiles[i]))
+			continue
+		}
+		// The tiles stay within the margins and reach into each half of
+		// the page.
+		var left, right, bottom, top bool
+		for _, corners := range tiles[i] {
+			for _, p := range corners {
+				if p.X < vp.LL.X+style.Tile.Margin-1 || p.X > vp.UR.X-style.Tile.Margin+1 ||
+					p.Y < vp.LL.Y+style.Tile.Margin-1 || p.Y > vp.UR.Y-style.Tile.Margin+1 {
+					t.Errorf("page %d: tile corner %v outside %v less the margin", i+1, p, vp)
+				}
+				left = left || p.X < vp.LL.X+vp.Width()/2
+				right = right || p.X > vp.LL.X+vp.Width()/2
+				bottom = bottom || p.Y < vp.LL.Y+vp.Height()/2
+				top = top || p.Y > vp.LL.Y+vp.Height()/2
+			}
+		}
+		if !left || !right || !bottom || !top {
+			t.Errorf("page %d: tiles do not cover %v", i+1, vp)
+		}
+	}
+	if len(tiles[1]) == len(tiles[0]) {
+		t.Errorf("%d tiles on both A4 and letter landscape pages, want the grid to follow the page", len(tiles[0]))
+	}
+
+	info, err := ReadInfo(bytes.NewReader(buf.Bytes()), Passwords{}, nil)
+	if err != nil {
+		t.Fatalf("ReadInfo: %v", err)
+	}
+	for i, p := range info.Pages {
+		if len(p.Watermarks) != 1 {
+			t.Errorf("page %d: %d watermarks, want the tiles in one", i+1, len(p.Watermarks))
+		}
+	}
+	if w := info.Pages[0].Watermarks; w[0].Text != "CONFIDENTIAL" || w[0].FontSize != 24 || w[0].Rotation != 30 {
+		t.Errorf("tiles = %+v", w[0])
+	}
+}
+
+func TestApply_TiledImage(t *testing.T) {
+	style := spec.DefaultStyle()
+	style.Position = spec.Tiled
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 1)), &bytes.Buffer{},
+		map[int][]spec.Instruction{1: {{Image: "logo"}}},
+		Options{Style: style, Images: map[string][]byte{"logo": createTestPNG(t, 4, 4)}})
+	if err == nil {
+		t.Error("tiling an image succeeded, want ErrInvalidStyle")
+	}
+}
+
+func TestTileCenters(t *testing.T) {
+	vp := *types.RectForDim(300, 200)
+	s := spec.DefaultStyle()
+	s.Tile = spec.Tiling{GapX: 20, GapY: 20}
+
+	// 80x30 tiles at 100x50 pitch: 3 columns of 80 in 300, 4 rows of 30
+	// in 200.
+	got, err := tileCenters(vp, 80, 30, 0, s)
+	if err != nil || len(got) != 12 {
+		t.Fatalf("%d tiles, %v; want 12", len(got), err)
+	}
+	if p := got[0]; p.X != 50 || p.Y != 175 {
+		t.Errorf("first tile at %v, want (50, 175)", p)
+	}
+
+	s.Tile.Stagger = true
+	if got, _ := tileCenters(vp, 80, 30, 0, s); len(got) != 10 || got[3].X != 100 {
+		t.Errorf("staggered: %d tiles, second row from %v; want 10 from x=100", len(got), got[3])
+	}
+
+	// Turned by 90 degrees, the tiles are 30x80 at 50x100 pitch.
+	s.Tile.Stagger = false
+	if got, _ := tileCenters(vp, 80, 30, 90, s); len(got) != 12 {
+		t.Errorf("turned: %d tiles, want 6 columns of 2", len(got))
+	}
+
+	s.Tile.Margin = 120
+	if got, _ := tileCenters(vp, 80, 30, 0, s); len(got) != 1 || math.Abs(got[0].X-150) > 1e-9 || math.Abs(got[0].Y-100) > 1e-9 {
+		t.Errorf("no room: got %v, want one centered tile", got)
+	}
+
+	s.Tile = spec.Tiling{}
+	if _, err := tileCenters(vp, 0, 30, 0, s); !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("zero pitch: err = %v, want ErrInvalidStyle", err)
+	}
+	if _, err := tileCenters(*types.RectForDim(1e7, 1e7), 1, 1, 0, s); !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("1e14 tiles: err = %v, want ErrInvalidStyle", err)
+	}
+}
+
+func TestApply_TiledTooMany(t *testing.T) {
+	style := spec.DefaultStyle()
+	style.Position = spec.Tiled
+	style.FontSize = 1
+	style.Diagonal = spec.NoDiagonal
+	style.Tile = spec.Tiling{}
+	err := Apply(context.Background(), bytes.NewReader(createTestPDF(t, 1)), &bytes.Buffer{},
+		map[int][]spec.Instruction{1: {{Text: "x"}}}, Options{Style: style})
+	if !errors.Is(err, errs.ErrInvalidStyle) {
+		t.Errorf("err = %v, want ErrInvalidStyle", err)
+	}
+}
@@ -4,6 +4,10 @@ package testutil
 
 import (
 	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"io"
 	"testing"
 
@@ -72,6 +76,35 @@ func CreateTestPDF(t testing.TB, pages int) []byte {
 	return buf.Bytes()
 }
 
+// EncryptPDF encrypts the PDF in data with AES-256 and the given
+// passwords, allowing printing only.
+func EncryptPDF(t testing.TB, data []byte, userPW, ownerPW string) []byte {
+	t.Helper()
+	conf := model.NewAESConfiguration(userPW, ownerPW, 256)
+	var buf bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(data), &buf, conf); err != nil {
+		t.Fatalf("encrypting PDF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// PDFPermissions returns the permission flags of the PDF in data, unlocked
+// with the given passwords, and whether it is encrypted at all.
+func PDFPermissions(t testing.TB, data []byte, userPW, ownerPW string) (model.PermissionFlags, bool) {
+	t.Helper()
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = userPW
+	conf.OwnerPW = ownerPW
+	ctx, err := api.ReadContext(bytes.NewReader(data), conf)
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if ctx.E == nil {
+		return 0, false
+	}
+	return model.PermissionFlags(ctx.E.P), true
+}
+
 // AssertValidPDF fails the test if data is not a structurally valid PDF.
 func AssertValidPDF(t testing.TB, data []byte) {
 	t.Helper()
@@ -96,6 +129,19 @@ func AssertPageCount(t testing.TB, data []byte, expected int) {
 	}
 }
 
+// CreateTestPNG encodes a w x h PNG filled with a solid color.
+func CreateTestPNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{R: 200, A: 255}}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
 // CSVString joins lines with newlines and returns them as an io.Reader,
 // convenient for building CSV test inputs inline.
 func CSVString(lines ...string) io.Reader {
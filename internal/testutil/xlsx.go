@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Sheet is a worksheet for XLSX. A nil row leaves an empty row.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// XLSX builds a minimal XLSX workbook holding sheets. Numeric cells are
+// stored as numbers, other cells as shared strings.
+func XLSX(t testing.TB, sheets ...Sheet) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(xml.Header + content)); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	var shared []string
+	index := make(map[string]int)
+	var wb, rels strings.Builder
+	for i, sh := range sheets {
+		fmt.Fprintf(&wb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sh.Name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+
+		var data strings.Builder
+		for r, row := range sh.Rows {
+			if row == nil {
+				continue
+			}
+			fmt.Fprintf(&data, `<row r="%d">`, r+1)
+			for c, v := range row {
+				if v == "" {
+					continue
+				}
+				ref := fmt.Sprintf("%c%d", 'A'+c, r+1)
+				if _, err := strconv.ParseFloat(v, 64); err == nil {
+					fmt.Fprintf(&data, `<c r="%s"><v>%s</v></c>`, ref, v)
+					continue
+				}
+				n, ok := index[v]
+				if !ok {
+					n = len(shared)
+					index[v] = n
+					shared = append(shared, v)
+				}
+				fmt.Fprintf(&data, `<c r="%s" t="s"><v>%d</v></c>`, ref, n)
+			}
+			data.WriteString(`</row>`)
+		}
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1),
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+data.String()+`</sheetData></worksheet>`)
+	}
+
+	var sst strings.Builder
+	for _, s := range shared {
+		fmt.Fprintf(&sst, `<si><t>%s</t></si>`, escape(s))
+	}
+	write("[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`)
+	write("xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+wb.String()+`</sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+	write("xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+sst.String()+`</sst>`)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing XLSX: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,191 @@
+// Package tmpl renders the placeholders in watermark text, such as
+// {{page}} and {{total_pages}}, using text/template.
+package tmpl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// DateLayout is the layout {{date}} uses when none is given.
+const DateLayout = "2006-01-02"
+
+// MaxLength is the longest text, in bytes, a template may render to.
+const MaxLength = 4 << 10
+
+// Data holds the values available to a template when it is rendered for one
+// page.
+type Data struct {
+	Page       int
+	TotalPages int
+	Date       time.Time
+	Filename   string
+	// Vars holds caller-supplied variables, each rendered by {{name}}.
+	Vars map[string]string
+}
+
+// builtins are the placeholder functions provided for every template, in
+// addition to the caller's variables.
+var builtins = []string{"page", "total_pages", "date", "filename", "upper", "lower"}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Template is a parsed watermark text.
+type Template struct {
+	t *template.Template
+}
+
+// IsTemplate reports whether text contains placeholders. Text without them
+// is used literally and need not be parsed.
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// ValidateName checks that name can be used as a caller variable: it must be
+// an identifier and must not shadow a built-in placeholder.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return errs.Detailf(errs.ErrInvalidTemplate, "variable name %q is not an identifier", name)
+	}
+	for _, b := range builtins {
+		if name == b {
+			return errs.Detailf(errs.ErrInvalidTemplate, "variable name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// Parse parses text, which may use the built-in placeholders and the
+// variables named in vars. Each action names one placeholder, optionally
+// followed by quoted strings or other placeholders as arguments, as in
+// {{date "02 Jan 2006"}} or {{upper recipient}}. Any other placeholder,
+// and the text/template pipelines, control structures and functions, are
+// errors wrapping ErrInvalidTemplate, so templates from untrusted clients
+// cannot loop or call arbitrary functions.
+func Parse(text string, vars []string) (*Template, error) {
+	for _, name := range vars {
+		if err := ValidateName(name); err != nil {
+			return nil, err
+		}
+	}
+	fm := funcs(Data{}, vars)
+	t, err := template.New("watermark").Funcs(fm).Parse(text)
+	if err != nil {
+		return nil, errs.Detailf(errs.ErrInvalidTemplate, "%v", err)
+	}
+	if len(t.Templates()) > 1 {
+		return nil, errs.Detailf(errs.ErrInvalidTemplate, "template definitions are not supported")
+	}
+	if t.Tree != nil {
+		if err := checkNodes(t.Tree.Root, fm); err != nil {
+			return nil, errs.Detailf(errs.ErrInvalidTemplate, "%v", err)
+		}
+	}
+	return &Template{t: t}, nil
+}
+
+// checkNodes reports the first node of list that is not text, a comment or
+// a plain placeholder action.
+func checkNodes(list *parse.ListNode, fm template.FuncMap) error {
+	for _, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.TextNode, *parse.CommentNode:
+		case *parse.ActionNode:
+			if err := checkAction(n, fm); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%q is not a placeholder", n)
+		}
+	}
+	return nil
+}
+
+// checkAction checks that a names a single placeholder of fm with only
+// strings and placeholders as arguments.
+func checkAction(a *parse.ActionNode, fm template.FuncMap) error {
+	if len(a.Pipe.Decl) > 0 || len(a.Pipe.Cmds) != 1 {
+		return fmt.Errorf("%q: pipelines and variables are not supported", a)
+	}
+	for i, arg := range a.Pipe.Cmds[0].Args {
+		switch arg := arg.(type) {
+		case *parse.IdentifierNode:
+			if _, ok := fm[arg.Ident]; !ok {
+				return fmt.Errorf("%q: function %q is not supported", a, arg.Ident)
+			}
+		case *parse.StringNode:
+			if i == 0 {
+				return fmt.Errorf("%q does not name a placeholder", a)
+			}
+		default:
+			return fmt.Errorf("%q: argument %q is not supported", a, arg)
+		}
+	}
+	return nil
+}
+
+// Execute renders the template for the page described by d.
+func (t *Template) Execute(d Data) (string, error) {
+	c, err := t.t.Clone()
+	if err != nil {
+		return "", errs.Detailf(errs.ErrInvalidTemplate, "%v", err)
+	}
+	names := make([]string, 0, len(d.Vars))
+	for name := range d.Vars {
+		names = append(names, name)
+	}
+	b := &limitedBuilder{max: MaxLength}
+	if err := c.Funcs(funcs(d, names)).Execute(b, nil); err != nil {
+		return "", errs.Detailf(errs.ErrInvalidTemplate, "%v", err)
+	}
+	return b.String(), nil
+}
+
+// limitedBuilder is a strings.Builder that fails writes growing it past max
+// bytes.
+type limitedBuilder struct {
+	strings.Builder
+	max int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("rendered text exceeds %d bytes", b.max)
+	}
+	return b.Builder.Write(p)
+}
+
+// funcs returns the template functions for d. Each name in vars renders the
+// matching entry of d.Vars, or "" when there is none.
+func funcs(d Data, vars []string) template.FuncMap {
+	m := template.FuncMap{
+		"page":        func() int { return d.Page },
+		"total_pages": func() int { return d.TotalPages },
+		"date": func(layout ...string) (string, error) {
+			switch len(layout) {
+			case 0:
+				return d.Date.Format(DateLayout), nil
+			case 1:
+				return d.Date.Format(layout[0]), nil
+			}
+			return "", fmt.Errorf("date takes at most one layout, got %d", len(layout))
+		},
+		"filename": func() string { return d.Filename },
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+	}
+	for _, name := range vars {
+		if _, reserved := m[name]; reserved || !namePattern.MatchString(name) {
+			continue
+		}
+		value := d.Vars[name]
+		m[name] = func() string { return value }
+	}
+	return m
+}
@@ -0,0 +1,121 @@
+package tmpl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+func TestExecute(t *testing.T) {
+	d := Data{
+		Page:       2,
+		TotalPages: 7,
+		Date:       time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		Filename:   "report.pdf",
+		Vars:       map[string]string{"recipient": "Jane Doe", "ticket": "T-42"},
+	}
+	tests := []struct {
+		text, want string
+	}{
+		{"CONFIDENTIAL", "CONFIDENTIAL"},
+		{"Copy for {{recipient}} – page {{page}}/{{total_pages}}", "Copy for Jane Doe – page 2/7"},
+		{"{{filename}} {{date}}", "report.pdf 2024-03-09"},
+		{`{{date "02 Jan 2006"}}`, "09 Mar 2024"},
+		{"{{upper recipient}} {{lower ticket}}", "JANE DOE t-42"},
+		{"{{/* note */}}{{- page -}} of {{total_pages}}", "2of 7"},
+	}
+	for _, tt := range tests {
+		tpl, err := Parse(tt.text, []string{"recipient", "ticket"})
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.text, err)
+		}
+		got, err := tpl.Execute(d)
+		if err != nil {
+			t.Fatalf("Execute(%q): %v", tt.text, err)
+		}
+		if got != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExecute_MissingVar(t *testing.T) {
+	tpl, err := Parse("for {{recipient}}", []string{"recipient"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got, err := tpl.Execute(Data{})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got != "for " {
+		t.Errorf("got %q, want %q", got, "for ")
+	}
+}
+
+func TestExecute_Reuse(t *testing.T) {
+	tpl, err := Parse("{{page}}", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for page, want := range map[int]string{1: "1", 3: "3"} {
+		if got, _ := tpl.Execute(Data{Page: page}); got != want {
+			t.Errorf("page %d rendered %q, want %q", page, got, want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		text string
+		vars []string
+	}{
+		"unknown placeholder": {"{{recipient}}", nil},
+		"syntax":              {"{{page", nil},
+		"bad var name":        {"x", []string{"first name"}},
+		"reserved var name":   {"x", []string{"page"}},
+		"range":               {"{{range 1000000000}}x{{end}}", nil},
+		"if":                  {"{{if page}}x{{end}}", nil},
+		"printf":              {`{{printf "%d" page}}`, nil},
+		"len":                 {`{{len "abc"}}`, nil},
+		"pipeline":            {"{{page | printf}}", nil},
+		"builtin pipeline":    {"{{recipient | upper}}", []string{"recipient"}},
+		"variable":            {"{{$x := page}}{{$x}}", nil},
+		"dot":                 {"{{.}}", nil},
+		"nested":              {"{{upper (lower filename)}}", nil},
+		"define":              {`{{define "x"}}a{{end}}`, nil},
+		"template":            {`{{template "watermark"}}`, nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(tt.text, tt.vars)
+			if !errors.Is(err, errs.ErrInvalidTemplate) {
+				t.Errorf("got error %v, want ErrInvalidTemplate", err)
+			}
+		})
+	}
+}
+
+func TestExecute_TooLong(t *testing.T) {
+	tpl, err := Parse("{{recipient}}{{recipient}}", []string{"recipient"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	long := strings.Repeat("x", MaxLength/2+1)
+	if _, err := tpl.Execute(Data{Vars: map[string]string{"recipient": long}}); !errors.Is(err, errs.ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
+
+func TestExecute_BadDateArgs(t *testing.T) {
+	tpl, err := Parse(`{{date "a" "b"}}`, nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := tpl.Execute(Data{}); !errors.Is(err, errs.ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Package xlsx reads the cell values of a worksheet in an Office Open XML
+// spreadsheet (.xlsx). It supports what watermark plans need: shared,
+// inline and formula strings, numbers and booleans. Styles, dates and
+// formulas without a cached value are not interpreted.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+)
+
+// Row is one non-empty row of a worksheet.
+type Row struct {
+	// Line is the 1-based row number shown by spreadsheet applications.
+	Line int
+	// Cells holds the cell values by column, "" for empty cells.
+	Cells []string
+}
+
+// ReadSheet returns the rows of the worksheet named sheet in the size bytes
+// of r, or of the first worksheet if sheet is empty. Empty rows are
+// omitted. Problems with the file are reported as ErrMalformedCSV.
+func ReadSheet(r io.ReaderAt, size int64, sheet string) ([]Row, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not an XLSX file: %v", errs.ErrMalformedCSV, err)
+	}
+	name, err := sheetPath(zr, sheet)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := sharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R      string `xml:"r,attr"`
+				T      string `xml:"t,attr"`
+				V      string `xml:"v"`
+				Inline text   `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeXML(zr, name, &ws); err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	prev := 0
+	for _, xr := range ws.Rows {
+		line := xr.R
+		if line == 0 {
+			line = prev + 1
+		}
+		prev = line
+
+		var cells []string
+		for _, c := range xr.Cells {
+			col := len(cells)
+			if c.R != "" {
+				if col, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			var v string
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(strings.TrimSpace(c.V))
+				if err != nil || i < 0 || i >= len(shared) {
+					return nil, fmt.Errorf("%w: cell %s: bad shared string index %q", errs.ErrMalformedCSV, c.R, c.V)
+				}
+				v = shared[i]
+			case "inlineStr":
+				v = c.Inline.String()
+			case "b":
+				v = "false"
+				if strings.TrimSpace(c.V) == "1" {
+					v = "true"
+				}
+			default:
+				// Numbers, formula strings ("str") and errors ("e") carry
+				// their value as text.
+				v = c.V
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = v
+		}
+		if !blank(cells) {
+			rows = append(rows, Row{Line: line, Cells: cells})
+		}
+	}
+	return rows, nil
+}
+
+// IsXLSX reports whether head, the start of a file, looks like a ZIP
+// archive, as XLSX files are.
+func IsXLSX(head []byte) bool {
+	return strings.HasPrefix(string(head), "PK\x03\x04")
+}
+
+// text is a string item: plain text, or rich text runs. Phonetic hints are
+// ignored.
+type text struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t text) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// sheetPath finds the archive path of the worksheet named sheet, or of the
+// first worksheet if sheet is empty.
+func sheetPath(zr *zip.Reader, sheet string) (string, error) {
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXML(zr, "xl/workbook.xml", &wb); err != nil {
+		return "", err
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXML(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, s := range wb.Sheets {
+		names = append(names, s.Name)
+		if sheet != "" && !strings.EqualFold(s.Name, sheet) {
+			continue
+		}
+		for _, rel := range rels.Rels {
+			if rel.ID != s.ID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+		return "", fmt.Errorf("%w: sheet %q has no worksheet part", errs.ErrMalformedCSV, s.Name)
+	}
+	if sheet == "" {
+		return "", fmt.Errorf("%w: workbook has no sheets", errs.ErrMalformedCSV)
+	}
+	return "", fmt.Errorf("%w: no sheet %q, have %s", errs.ErrMalformedCSV, sheet, strings.Join(names, ", "))
+}
+
+// sharedStrings reads the shared string table, which is optional.
+func sharedStrings(zr *zip.Reader) ([]string, error) {
+	var sst struct {
+		Items []text `xml:"si"`
+	}
+	err := decodeXML(zr, "xl/sharedStrings.xml", &sst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.String()
+	}
+	return out, nil
+}
+
+// maxMemberSize bounds the uncompressed size of an archive member, so a
+// small file cannot inflate into gigabytes of XML.
+const maxMemberSize = 64 << 20
+
+// decodeXML unmarshals the archive member name into v. A missing member is
+// reported as fs.ErrNotExist as well as ErrMalformedCSV, and one larger
+// than maxMemberSize as ErrMalformedCSV.
+func decodeXML(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errs.ErrMalformedCSV, err)
+	}
+	defer f.Close()
+	tooLarge := func() error {
+		return fmt.Errorf("%w: %s: larger than %d MiB uncompressed", errs.ErrMalformedCSV, name, maxMemberSize>>20)
+	}
+	if fi, err := f.Stat(); err == nil {
+		if h, ok := fi.Sys().(*zip.FileHeader); ok && h.UncompressedSize64 > maxMemberSize {
+			return tooLarge()
+		}
+	}
+	// The declared size may lie, so the data is bounded as well.
+	lr := &io.LimitedReader{R: f, N: maxMemberSize + 1}
+	if err := xml.NewDecoder(lr).Decode(v); err != nil {
+		if lr.N == 0 {
+			return tooLarge()
+		}
+		return fmt.Errorf("%w: %s: %v", errs.ErrMalformedCSV, name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "C7".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	if n == 0 || n > 3 {
+		return 0, fmt.Errorf("%w: bad cell reference %q", errs.ErrMalformedCSV, ref)
+	}
+	return col - 1, nil
+}
+
+func blank(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
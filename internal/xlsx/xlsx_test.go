@@ -0,0 +1,111 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+)
+
+func read(t *testing.T, data []byte, sheet string) ([]Row, error) {
+	t.Helper()
+	return ReadSheet(bytes.NewReader(data), int64(len(data)), sheet)
+}
+
+func TestReadSheet(t *testing.T) {
+	data := testutil.XLSX(t,
+		testutil.Sheet{Name: "Notes", Rows: [][]string{{"ignore me"}}},
+		testutil.Sheet{Name: "Plan", Rows: [][]string{
+			{"page", "watermark_text"},
+			nil,
+			{"1", "CONFIDENTIAL", "", "0.5"},
+			{"", "", ""},
+		}},
+	)
+	rows, err := read(t, data, "plan")
+	if err != nil {
+		t.Fatalf("ReadSheet: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2: %+v", len(rows), rows)
+	}
+	if rows[1].Line != 3 || !slices.Equal(rows[1].Cells, []string{"1", "CONFIDENTIAL", "", "0.5"}) {
+		t.Errorf("row 2 = %+v, want line 3 with a gap in column C", rows[1])
+	}
+
+	rows, err = read(t, data, "")
+	if err != nil || len(rows) != 1 || rows[0].Cells[0] != "ignore me" {
+		t.Errorf("first sheet = %+v, %v, want the Notes sheet", rows, err)
+	}
+}
+
+func TestReadSheet_CellTypes(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="S" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="/xl/worksheets/s.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><r><t>CONF</t></r><r><t>IDENTIAL</t></r></si></sst>`,
+		"xl/worksheets/s.xml": `<worksheet><sheetData><row>` +
+			`<c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>inline</t></is></c>` +
+			`<c r="C1" t="b"><v>1</v></c><c r="D1" t="str"><f>A1</f><v>formula</v></c></row></sheetData></worksheet>`,
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	rows, err := read(t, buf.Bytes(), "")
+	if err != nil {
+		t.Fatalf("ReadSheet: %v", err)
+	}
+	want := []string{"CONFIDENTIAL", "inline", "true", "formula"}
+	if len(rows) != 1 || rows[0].Line != 1 || !slices.Equal(rows[0].Cells, want) {
+		t.Errorf("rows = %+v, want one row %q", rows, want)
+	}
+}
+
+func TestReadSheet_Errors(t *testing.T) {
+	data := testutil.XLSX(t, testutil.Sheet{Name: "Plan", Rows: [][]string{{"page"}}})
+
+	// A workbook part that claims to inflate to a terabyte.
+	var bomb bytes.Buffer
+	zw := zip.NewWriter(&bomb)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: "xl/workbook.xml", Method: zip.Store, UncompressedSize64: 1 << 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("<workbook/>"))
+	zw.Close()
+
+	tests := map[string]struct {
+		data  []byte
+		sheet string
+	}{
+		"missing sheet": {data, "Other"},
+		"not a zip":     {[]byte("page,watermark_text\n"), ""},
+		"zip bomb":      {bomb.Bytes(), ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := read(t, tt.data, tt.sheet); !errors.Is(err, errs.ErrMalformedCSV) {
+				t.Errorf("got error %v, want ErrMalformedCSV", err)
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27} {
+		if got, err := columnIndex(ref); err != nil || got != want {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d", ref, got, err, want)
+		}
+	}
+	if _, err := columnIndex("12"); err == nil {
+		t.Error("columnIndex(\"12\") succeeded, want an error")
+	}
+}
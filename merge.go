@@ -0,0 +1,183 @@
+package pdfmark
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/csvparse"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
+)
+
+// Recipient is one row of a mail-merge recipients list. Key names the
+// recipient and, by default, the output file; Vars are the template
+// variables for that recipient's copy.
+type Recipient = csvparse.Recipient
+
+// ParseRecipients reads a recipients CSV whose header names the template
+// variables. Every row must have a unique, non-empty value in keyColumn:
+//
+//	email,recipient
+//	jane@example.com,Jane Doe
+//	joe@example.com,Joe Bloggs
+func ParseRecipients(r io.Reader, keyColumn string) ([]Recipient, error) {
+	return csvparse.ParseRecipients(r, keyColumn)
+}
+
+// MergeOutput receives the PDFs written by Merge, one per recipient.
+type MergeOutput interface {
+	// Create returns a writer for the file called name. Merge closes it
+	// after writing.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirOutput returns a MergeOutput writing files into dir, which is created
+// if needed. Existing files are overwritten.
+func DirOutput(dir string) MergeOutput {
+	return dirOutput(dir)
+}
+
+type dirOutput string
+
+func (d dirOutput) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(string(d), name))
+}
+
+// ZipOutput is a MergeOutput writing every file into a zip archive. Close
+// must be called after Merge to finish the archive.
+type ZipOutput struct {
+	zw *zip.Writer
+}
+
+// NewZipOutput returns a ZipOutput writing the archive to w.
+func NewZipOutput(w io.Writer) *ZipOutput {
+	return &ZipOutput{zw: zip.NewWriter(w)}
+}
+
+// Create adds a file called name to the archive.
+func (z *ZipOutput) Create(name string) (io.WriteCloser, error) {
+	w, err := z.zw.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{w}, nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (z *ZipOutput) Close() error {
+	return z.zw.Close()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// Merge stamps the PDF from src once per recipient and writes each copy to
+// out. The instructions in csvData and the PDF are read and validated
+// once, before the first copy; each copy is stamped on its own parse of the
+// validated PDF kept in memory, rendering the watermark text with the
+// recipient's Vars on top of those from WithTemplateVars. Copies are named by WithOutputName, or
+// "<key>.pdf" by default.
+//
+// ctx is checked between stages and before each copy. If an error occurs,
+// copies already written are left in out.
+func Merge(ctx context.Context, out MergeOutput, src io.Reader, csvData io.Reader, recipients []Recipient, opts ...Option) error {
+	cfg := newConfig(opts)
+	names := make(map[string]bool)
+	for name := range cfg.vars {
+		names[name] = true
+	}
+	for _, r := range recipients {
+		for name := range r.Vars {
+			names[name] = true
+		}
+	}
+	varNames := slices.Sorted(maps.Keys(names))
+
+	var naming *tmpl.Template
+	if cfg.outputName != "" {
+		t, err := tmpl.Parse(cfg.outputName, varNames)
+		if err != nil {
+			return fmt.Errorf("output name: %w", err)
+		}
+		naming = t
+	}
+
+	j, err := prepare(ctx, src, csvData, cfg, varNames)
+	if err != nil {
+		return err
+	}
+	defer j.close()
+	if err := j.parse(ctx); err != nil {
+		return err
+	}
+
+	written := make(map[string]string)
+	for _, r := range recipients {
+		if err := checkContext(ctx, "merging "+r.Key); err != nil {
+			return err
+		}
+
+		vars := make(map[string]string, len(cfg.vars)+len(r.Vars))
+		maps.Copy(vars, cfg.vars)
+		maps.Copy(vars, r.Vars)
+
+		name, err := outputName(naming, r.Key, tmpl.Data{
+			TotalPages: j.totalPages,
+			Date:       j.date,
+			Filename:   cfg.filename,
+			Vars:       vars,
+		})
+		if err != nil {
+			return fmt.Errorf("recipient %q: %w", r.Key, err)
+		}
+		if prev, dup := written[name]; dup {
+			return fmt.Errorf("%w: %q for recipients %q and %q", errs.ErrInvalidOutputName, name, prev, r.Key)
+		}
+		written[name] = r.Key
+
+		if err := writeCopy(ctx, out, name, j, vars); err != nil {
+			return fmt.Errorf("recipient %q: %w", r.Key, err)
+		}
+	}
+	return nil
+}
+
+// outputName renders the file name for one copy. Names must be plain file
+// names without directories.
+func outputName(naming *tmpl.Template, key string, d tmpl.Data) (string, error) {
+	name := key + ".pdf"
+	if naming != nil {
+		var err error
+		if name, err = naming.Execute(d); err != nil {
+			return "", err
+		}
+	}
+	if !fs.ValidPath(name) || name == "." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: %q", errs.ErrInvalidOutputName, name)
+	}
+	return name, nil
+}
+
+func writeCopy(ctx context.Context, out MergeOutput, name string, j *job, vars map[string]string) error {
+	w, err := out.Create(name)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", name, err)
+	}
+	if err := j.write(ctx, w, vars); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
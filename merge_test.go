@@ -0,0 +1,146 @@
+package pdfmark
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func testRecipients(t *testing.T) []Recipient {
+	t.Helper()
+	recipients, err := ParseRecipients(csvString(
+		"email,recipient,ticket",
+		"jane@example.com,Jane Doe,T-1",
+		"joe@example.com,Joe Bloggs,T-2",
+	), "email")
+	if err != nil {
+		t.Fatalf("ParseRecipients: %v", err)
+	}
+	return recipients
+}
+
+func mergeCSV() io.Reader {
+	return csvString(
+		"page,watermark_text",
+		`all,"Copy for {{recipient}} – page {{page}}/{{total_pages}}"`,
+	)
+}
+
+func TestMerge_Dir(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	dir := filepath.Join(t.TempDir(), "out")
+
+	err := Merge(context.Background(), DirOutput(dir), bytes.NewReader(pdf), mergeCSV(), testRecipients(t))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"jane@example.com.pdf", "joe@example.com.pdf"}; !slices.Equal(names, want) {
+		t.Errorf("directory holds %v, want %v", names, want)
+	}
+
+	// Each copy is stamped for its own recipient.
+	for name, recipient := range map[string]string{"jane@example.com.pdf": "Jane Doe", "joe@example.com.pdf": "Joe Bloggs"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		assertValidPDF(t, data)
+		assertPageCount(t, data, 2)
+		for i, texts := range watermarkTexts(t, data) {
+			want := fmt.Sprintf("Copy for %s – page %d/2", recipient, i+1)
+			if len(texts) != 1 || texts[0] != want {
+				t.Errorf("%s page %d watermarks = %q, want %q", name, i+1, texts, want)
+			}
+		}
+	}
+}
+
+func TestMerge_Zip(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+
+	var buf bytes.Buffer
+	zo := NewZipOutput(&buf)
+	err := Merge(context.Background(), zo, bytes.NewReader(pdf), mergeCSV(), testRecipients(t),
+		WithOutputName("review-{{ticket}}.pdf"))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if err := zo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		assertValidPDF(t, data)
+	}
+	if want := []string{"review-T-1.pdf", "review-T-2.pdf"}; !slices.Equal(names, want) {
+		t.Errorf("zip holds %v, want %v", names, want)
+	}
+}
+
+func TestMerge_OutputNameErrors(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	for name, pattern := range map[string]string{
+		"duplicate": "copy.pdf",
+		"directory": "../{{ticket}}.pdf",
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := Merge(context.Background(), DirOutput(t.TempDir()), bytes.NewReader(pdf), mergeCSV(), testRecipients(t),
+				WithOutputName(pattern))
+			if !errors.Is(err, ErrInvalidOutputName) {
+				t.Errorf("got error %v, want ErrInvalidOutputName", err)
+			}
+		})
+	}
+}
+
+func TestMerge_UnknownVar(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	csv := csvString("page,watermark_text", "1,{{department}}")
+	err := Merge(context.Background(), DirOutput(t.TempDir()), bytes.NewReader(pdf), csv, testRecipients(t))
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
+
+func TestMerge_Cancelled(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dir := t.TempDir()
+	err := Merge(ctx, DirOutput(dir), bytes.NewReader(pdf), mergeCSV(), testRecipients(t))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("wrote %d files after cancellation", len(entries))
+	}
+}
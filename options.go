@@ -0,0 +1,319 @@
+package pdfmark
+
+import (
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+)
+
+// Option configures a call to WatermarkWithOptions or Merge.
+type Option func(*config)
+
+// config holds the settings assembled from Options.
+type config struct {
+	style         spec.Style
+	strictColumns bool
+	layered       bool
+	pagePolicy    spec.PagePolicy
+	warn          func(error)
+	images        map[string][]byte
+	imageFS       fs.FS
+	pdfs          map[string][]byte
+	pdfFS         fs.FS
+	vars          map[string]string
+	filename      string
+	date          time.Time
+	outputName    string
+	maxInputSize  int64
+	passwords     stamp.Passwords
+	encrypt       *stamp.Encryption
+	forensicMark  string
+	forensicKey   []byte
+	replace       bool
+	format        Format
+	decoder       Decoder
+	dialect       CSVDialect
+}
+
+func newConfig(opts []Option) config {
+	c := config{style: spec.DefaultStyle()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithStyle sets the style applied to every watermark.
+func WithStyle(s Style) Option {
+	return func(c *config) {
+		c.style = s
+	}
+}
+
+// WithStrictColumns makes unknown CSV header columns an error wrapping both
+// ErrMalformedCSV and ErrUnknownColumn. By default they are ignored and
+// reported to the warning handler.
+func WithStrictColumns() Option {
+	return func(c *config) {
+		c.strictColumns = true
+	}
+}
+
+// WithWarningHandler registers fn to receive non-fatal problems found in the
+// instructions, such as unknown CSV columns. Warnings are discarded when no
+// handler is set.
+func WithWarningHandler(fn func(error)) Option {
+	return func(c *config) {
+		c.warn = fn
+	}
+}
+
+// WithReplace strips the watermarks already on each page the instructions
+// select before stamping, as Remove does, so a DRAFT copy can be stamped
+// FINAL without keeping the original. Pages not selected keep their
+// watermarks, and forensic marks are kept.
+func WithReplace() Option {
+	return func(c *config) {
+		c.replace = true
+	}
+}
+
+// WithLayering allows several instructions to target the same page. They are
+// stacked in input order, the first one lowest, instead of failing with
+// ErrDuplicatePage.
+func WithLayering() Option {
+	return func(c *config) {
+		c.layered = true
+	}
+}
+
+// PagePolicy decides what happens to instructions selecting pages outside
+// the PDF, or pages already selected by an earlier instruction.
+type PagePolicy = spec.PagePolicy
+
+// Supported page policies.
+const (
+	// Strict fails with ErrPageOutOfRange or ErrDuplicatePage. It is the
+	// default.
+	Strict = spec.Strict
+	// SkipInvalid drops the offending pages and stamps the rest. Of several
+	// instructions selecting a page, the first is kept.
+	SkipInvalid = spec.SkipInvalid
+	// ClampToLastPage stamps pages past the end on the last page instead,
+	// and otherwise behaves like SkipInvalid.
+	ClampToLastPage = spec.ClampToLastPage
+)
+
+// ParsePagePolicy parses "strict", "skip" or "clamp".
+func ParsePagePolicy(s string) (PagePolicy, error) {
+	return spec.ParsePagePolicy(s)
+}
+
+// WithPagePolicy sets how out-of-range and duplicate pages are handled,
+// for example so that one CSV listing pages up to 20 can stamp documents of
+// any length. Under a lenient policy every duplicate page dropped, and every
+// run of pages outside the document dropped or moved, is passed to the
+// warning handler as an *InstructionError matching ErrDuplicatePage or
+// ErrPageOutOfRange, and listed in StampPlan.Skipped.
+func WithPagePolicy(p PagePolicy) Option {
+	return func(c *config) {
+		c.pagePolicy = p
+	}
+}
+
+// WithImages supplies image data for the image column, keyed by the value
+// used in the CSV. Keys are looked up before the file system set with
+// WithImageFS.
+func WithImages(images map[string][]byte) Option {
+	return func(c *config) {
+		c.images = images
+	}
+}
+
+// WithImageFS resolves image references in the CSV as paths within fsys,
+// for example os.DirFS("assets").
+func WithImageFS(fsys fs.FS) Option {
+	return func(c *config) {
+		c.imageFS = fsys
+	}
+}
+
+// WithPDFStamps supplies stamp PDFs for the pdf column, keyed by the value
+// used in the CSV. Keys are looked up before the file system set with
+// WithPDFStampFS.
+func WithPDFStamps(pdfs map[string][]byte) Option {
+	return func(c *config) {
+		c.pdfs = pdfs
+	}
+}
+
+// WithPDFStampFS resolves stamp PDF references in the CSV as paths within
+// fsys.
+func WithPDFStampFS(fsys fs.FS) Option {
+	return func(c *config) {
+		c.pdfFS = fsys
+	}
+}
+
+// WithTemplateVars supplies caller variables for placeholders in the
+// watermark text; {{recipient}} renders vars["recipient"]. Names must be
+// identifiers and must not shadow a built-in placeholder.
+func WithTemplateVars(vars map[string]string) Option {
+	return func(c *config) {
+		c.vars = vars
+	}
+}
+
+// WithFilename sets the value of the {{filename}} placeholder, usually the
+// base name of the input PDF. It is empty by default.
+func WithFilename(name string) Option {
+	return func(c *config) {
+		c.filename = name
+	}
+}
+
+// WithDate sets the date rendered by the {{date}} placeholder. It defaults
+// to the time of the call.
+func WithDate(t time.Time) Option {
+	return func(c *config) {
+		c.date = t
+	}
+}
+
+// WithOutputName sets the file name pattern for copies written by Merge. It
+// is a template like the watermark text, so "review-{{ticket}}.pdf" names
+// each copy after the recipient's ticket column. Names must not contain
+// directories.
+func WithOutputName(pattern string) Option {
+	return func(c *config) {
+		c.outputName = pattern
+	}
+}
+
+// WithMaxInputSize rejects input PDFs larger than n bytes with
+// ErrInputTooLarge before they are parsed. There is no limit by default.
+func WithMaxInputSize(n int64) Option {
+	return func(c *config) {
+		c.maxInputSize = n
+	}
+}
+
+// WithFormat selects the format of the instructions: FormatCSV, FormatJSON
+// or FormatYAML. By default the format is detected from the content.
+func WithFormat(f Format) Option {
+	return func(c *config) {
+		c.format = f
+	}
+}
+
+// WithDecoder reads the instructions with d instead of a built-in decoder,
+// overriding WithFormat.
+func WithDecoder(d Decoder) Option {
+	return func(c *config) {
+		c.decoder = d
+	}
+}
+
+// WithCSVDialect sets how CSV instructions are read, for example
+// semicolon-separated Windows-1252 exports with German column names:
+//
+//	pdfmark.WithCSVDialect(pdfmark.CSVDialect{
+//		Delimiter: ';',
+//		Headers:   map[string]string{"Seite": "page", "Wasserzeichen": "watermark_text"},
+//		Encoding:  charmap.Windows1252,
+//	})
+//
+// It applies when the instructions are CSV, whether detected or forced
+// with WithFormat, and is ignored with WithDecoder.
+func WithCSVDialect(d CSVDialect) Option {
+	return func(c *config) {
+		c.dialect = d
+	}
+}
+
+// WithPasswords unlocks an encrypted input PDF. Stamping a PDF whose
+// permissions forbid changes needs the owner password; the user password
+// is enough otherwise. Without them, or with wrong ones, encrypted inputs
+// fail with ErrEncryptedPDF, which wraps ErrInvalidPDF. The output keeps
+// the input's encryption unless WithEncryption replaces it.
+func WithPasswords(user, owner string) Option {
+	return func(c *config) {
+		c.passwords = stamp.Passwords{User: user, Owner: owner}
+	}
+}
+
+// Encryption describes how the output PDF is encrypted with AES, for
+// example to lock down a confidential copy for printing only:
+//
+//	pdfmark.WithEncryption(pdfmark.Encryption{
+//		UserPassword:  "open-me",
+//		OwnerPassword: "admin",
+//		Permissions:   pdfmark.PermitPrint,
+//	})
+type Encryption = stamp.Encryption
+
+// Permissions is a set of actions granted to readers of an encrypted PDF
+// who do not have the owner password.
+type Permissions = stamp.Permissions
+
+// Permissions that may be granted by Encryption.
+const (
+	PermitPrint     = stamp.PermitPrint
+	PermitCopy      = stamp.PermitCopy
+	PermitModify    = stamp.PermitModify
+	PermitAnnotate  = stamp.PermitAnnotate
+	PermitFillForms = stamp.PermitFillForms
+	PermitAssemble  = stamp.PermitAssemble
+	PermitAll       = stamp.PermitAll
+)
+
+// WithEncryption encrypts the output PDF with e in the same pass that
+// stamps it, replacing any encryption of the input. Invalid settings fail
+// with ErrInvalidEncryption.
+func WithEncryption(e Encryption) Option {
+	return func(c *config) {
+		c.encrypt = &e
+	}
+}
+
+// WithForensicMark hides id in the output as an invisible forensic mark, so
+// Trace can tell whose copy a leaked PDF is even after visible watermarks
+// are cropped out. id is a template like the watermark text, so Merge can
+// mark each copy with "{{email}}"; page placeholders render as 0. The mark
+// is written as invisible text on every page and as the XMP instance ID.
+//
+// The mark is encrypted and authenticated under the key set with
+// WithForensicKey, which is required: without the key it can be neither
+// read nor forged, and it does not name pdfmark. It catches copies passed
+// on as they are or with their visible watermarks cropped out. It does not
+// survive printing, rasterizing or a recipient who strips invisible text
+// and metadata, and its length reveals the length of id.
+func WithForensicMark(id string) Option {
+	return func(c *config) {
+		c.forensicMark = id
+	}
+}
+
+// WithForensicKey sets the secret key that seals forensic marks for
+// WithForensicMark and opens them for Trace and Inspect. It must be at
+// least 16 bytes long, and should be random, such as 32 bytes from
+// crypto/rand; a missing or shorter key fails with ErrInvalidForensicKey.
+// Keep it to trace leaks later: marks sealed under another key are not
+// found.
+func WithForensicKey(key []byte) Option {
+	return func(c *config) {
+		c.forensicKey = slices.Clone(key)
+	}
+}
+
+// checkForensicKey fails with ErrInvalidForensicKey if no usable key is set.
+func (c *config) checkForensicKey() error {
+	if len(c.forensicKey) < stamp.MinMarkKeySize {
+		return errs.ErrInvalidForensicKey
+	}
+	return nil
+}
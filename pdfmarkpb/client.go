@@ -0,0 +1,127 @@
+// Package pdfmarkpb holds the gRPC API of the pdfmark watermarking service,
+// generated from pdfmark.proto, and helpers for calling it. "pdfmark serve
+// -grpc-addr" runs the service.
+//
+// The helpers send a PDF in chunks after the options and reassemble
+// streamed output:
+//
+//	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+//	client := pdfmarkpb.NewWatermarkServiceClient(conn)
+//	err = pdfmarkpb.Watermark(ctx, client, dst, pdfFile, &pdfmarkpb.WatermarkOptions{
+//		Instructions: csvData,
+//	})
+package pdfmarkpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pdfmark.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkSize is the size of the PDF chunks sent by the helpers and by the
+// service.
+const ChunkSize = 64 << 10
+
+// Watermark sends the PDF read from src to c for stamping as opts describe
+// and writes the watermarked PDF to dst. A failure of the service is
+// returned as a gRPC status error.
+func Watermark(ctx context.Context, c WatermarkServiceClient, dst io.Writer, src io.Reader, opts *WatermarkOptions) error {
+	stream, err := c.Watermark(ctx)
+	if err != nil {
+		return err
+	}
+	err = send(src, func() error {
+		return stream.Send(&WatermarkRequest{Payload: &WatermarkRequest_Options{Options: opts}})
+	}, func(b []byte) error {
+		return stream.Send(&WatermarkRequest{Payload: &WatermarkRequest_Pdf{Pdf: b}})
+	})
+	if err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(resp.GetPdf()); err != nil {
+			return fmt.Errorf("writing PDF: %w", err)
+		}
+	}
+}
+
+// Plan sends the PDF read from src to c and returns what Watermark would
+// stamp with opts.
+func Plan(ctx context.Context, c WatermarkServiceClient, src io.Reader, opts *WatermarkOptions) (*PlanResponse, error) {
+	stream, err := c.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = send(src, func() error {
+		return stream.Send(&PlanRequest{Payload: &PlanRequest_Options{Options: opts}})
+	}, func(b []byte) error {
+		return stream.Send(&PlanRequest{Payload: &PlanRequest_Pdf{Pdf: b}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stream.CloseAndRecv()
+}
+
+// Inspect sends the PDF read from src to c and returns its description.
+func Inspect(ctx context.Context, c WatermarkServiceClient, src io.Reader, opts *InspectOptions) (*InspectResponse, error) {
+	stream, err := c.Inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = send(src, func() error {
+		return stream.Send(&InspectRequest{Payload: &InspectRequest_Options{Options: opts}})
+	}, func(b []byte) error {
+		return stream.Send(&InspectRequest{Payload: &InspectRequest_Pdf{Pdf: b}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stream.CloseAndRecv()
+}
+
+// send sends the options message first, then the PDF from src in chunks.
+// If the service ends the call early, send stops quietly so the caller
+// receives the status instead.
+func send(src io.Reader, options func() error, chunk func([]byte) error) error {
+	if err := options(); err != nil {
+		return ignoreEOF(err)
+	}
+	for {
+		// A sent message must not be modified, so each chunk gets its own
+		// buffer.
+		buf := make([]byte, ChunkSize)
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := chunk(buf[:n]); err != nil {
+				return ignoreEOF(err)
+			}
+		}
+		switch {
+		case err == io.EOF, err == io.ErrUnexpectedEOF:
+			return nil
+		case err != nil:
+			return fmt.Errorf("reading PDF: %w", err)
+		}
+	}
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package pdfmark
+
+import (
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
+)
+
+// StampPlan describes what a watermarking run would stamp on each page of
+// a PDF. It is returned by Plan.
+type StampPlan struct {
+	// TotalPages is the page count of the PDF.
+	TotalPages int
+	// Pages lists the pages that receive at least one watermark, in page
+	// order. Pages passed through unchanged are omitted.
+	Pages []PagePlan
+	// Skipped lists the pages dropped or moved under a lenient page policy,
+	// one entry per duplicate page and one per run of pages outside the
+	// document, such as "pages 13-900 skipped", with the reason in Detail.
+	Skipped []*InstructionError
+	// Warnings lists non-fatal problems such as unknown columns.
+	Warnings []error
+}
+
+// PagePlan lists the watermarks stamped on one page.
+type PagePlan struct {
+	Page int
+	// Stamps lists the watermarks in the order they are applied.
+	Stamps []PlannedStamp
+}
+
+// PlannedStamp is one watermark as it would be stamped. Exactly one of
+// Text, Image and PDF is set.
+type PlannedStamp struct {
+	Line    int    // source line of the instruction
+	Text    string // watermark text with placeholders rendered
+	Image   string // image reference
+	PDF     string // stamp PDF reference
+	PDFPage int    // page of the stamp PDF
+	Style   Style  // effective style, with the instruction's overrides applied
+}
+
+// Plan checks the instructions in csvData against the PDF in src the way
+// WatermarkWithOptions does and reports what would be stamped on which
+// page, without writing any output. It suits validating inputs in CI before
+// the real run.
+//
+// Every invalid instruction is reported: if any are found, Plan returns a
+// nil plan and a *ValidationReport as the error. Other errors, such as
+// invalid options, an unreadable PDF or a done ctx, are returned as
+// WatermarkWithOptions would. If the PDF cannot be read, the problems
+// already found in the instructions are joined to its error, so errors.As
+// still finds the *ValidationReport. Warnings and skipped pages are
+// returned in the plan rather than passed to the warning handler.
+func Plan(ctx context.Context, src io.Reader, csvData io.Reader, opts ...Option) (*StampPlan, error) {
+	cfg := newConfig(opts)
+	c, err := checkInstructions(ctx, csvData, cfg, slices.Collect(maps.Keys(cfg.vars)))
+	if err != nil {
+		return nil, err
+	}
+	report := c.report
+	if !c.complete {
+		return nil, report
+	}
+
+	if err := checkContext(ctx, "reading PDF"); err != nil {
+		return nil, err
+	}
+	rs, cleanup, err := stamp.OpenInput(src, cfg.maxInputSize)
+	if err != nil {
+		return nil, report.join(err)
+	}
+	defer cleanup()
+	if err := checkContext(ctx, "counting pages"); err != nil {
+		return nil, err
+	}
+	totalPages, err := stamp.PageCount(rs, cfg.passwords)
+	if err != nil {
+		return nil, report.join(err)
+	}
+
+	pages, skipped, err := spec.Expand(c.instructions, totalPages, cfg.layered, cfg.pagePolicy)
+	report.add(err)
+	report.add(stamp.ValidatePages(pages, totalPages))
+	if !report.OK() {
+		report.sort()
+		return nil, report
+	}
+
+	date := cfg.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	if err := renderTemplates(pages, c.templates, tmpl.Data{
+		TotalPages: totalPages,
+		Date:       date,
+		Filename:   cfg.filename,
+		Vars:       cfg.vars,
+	}); err != nil {
+		report.add(err)
+		return nil, report
+	}
+
+	plan := &StampPlan{TotalPages: totalPages, Skipped: skipped, Warnings: report.Warnings}
+	for _, page := range slices.Sorted(maps.Keys(pages)) {
+		pp := PagePlan{Page: page}
+		for _, ins := range pages[page] {
+			pp.Stamps = append(pp.Stamps, PlannedStamp{
+				Line:    ins.Line,
+				Text:    ins.Text,
+				Image:   ins.Image,
+				PDF:     ins.PDF,
+				PDFPage: ins.PDFPage,
+				Style:   ins.Style.Apply(cfg.style),
+			})
+		}
+		plan.Pages = append(plan.Pages, pp)
+	}
+	return plan, nil
+}
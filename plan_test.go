@@ -0,0 +1,121 @@
+package pdfmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	pdf := createTestPDF(t, 4)
+	in := "page,watermark_text,color\nodd,{{page}}/{{total_pages}},red\n2,DRAFT,\n"
+	plan, err := Plan(context.Background(), bytes.NewReader(pdf), strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.TotalPages != 4 {
+		t.Errorf("TotalPages = %d, want 4", plan.TotalPages)
+	}
+	want := map[int]string{1: "1/4", 2: "DRAFT", 3: "3/4"}
+	if len(plan.Pages) != len(want) {
+		t.Fatalf("got %d pages, want %d: %+v", len(plan.Pages), len(want), plan.Pages)
+	}
+	for _, pp := range plan.Pages {
+		if len(pp.Stamps) != 1 || pp.Stamps[0].Text != want[pp.Page] {
+			t.Errorf("page %d = %+v, want text %q", pp.Page, pp.Stamps, want[pp.Page])
+		}
+	}
+	if got := plan.Pages[0].Stamps[0]; got.Line != 2 || got.Style.Color != (Color{R: 1}) {
+		t.Errorf("page 1 stamp = %+v, want line 2 in red", got)
+	}
+	if got := plan.Pages[1].Stamps[0].Style; got != DefaultStyle() {
+		t.Errorf("page 2 style = %+v, want the default", got)
+	}
+}
+
+func TestPlan_Problems(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	in := "page,watermark_text,opacity\n1,A,\n3,B,\n1,C,\n2,D,2\n"
+	plan, err := Plan(context.Background(), bytes.NewReader(pdf), strings.NewReader(in))
+	if plan != nil {
+		t.Errorf("got plan %+v, want nil", plan)
+	}
+	var report *ValidationReport
+	if !errors.As(err, &report) {
+		t.Fatalf("got error %v, want *ValidationReport", err)
+	}
+	want := []struct {
+		line     int
+		sentinel error
+	}{
+		{3, ErrPageOutOfRange},
+		{4, ErrDuplicatePage},
+		{5, ErrMalformedCSV},
+	}
+	if len(report.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(report.Problems), len(want), report)
+	}
+	for i, w := range want {
+		if p := report.Problems[i]; p.Line != w.line || !errors.Is(p, w.sentinel) {
+			t.Errorf("problem %d = %v, want line %d matching %v", i, p, w.line, w.sentinel)
+		}
+	}
+}
+
+func TestPlan_InvalidPDF(t *testing.T) {
+	_, err := Plan(context.Background(), strings.NewReader("not a pdf"), strings.NewReader("page,watermark_text\n1,A\n"))
+	if !errors.Is(err, ErrInvalidPDF) {
+		t.Errorf("got error %v, want ErrInvalidPDF", err)
+	}
+}
+
+func TestPlan_InvalidPDFWithProblems(t *testing.T) {
+	in := "page,watermark_text,opacity\n1,A,\n2,B,2\n"
+	_, err := Plan(context.Background(), strings.NewReader("not a pdf"), strings.NewReader(in))
+	if !errors.Is(err, ErrInvalidPDF) {
+		t.Errorf("got error %v, want ErrInvalidPDF", err)
+	}
+	var report *ValidationReport
+	if !errors.As(err, &report) {
+		t.Fatalf("got error %v, want it to carry the *ValidationReport", err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Line != 3 || !errors.Is(report.Problems[0], ErrMalformedCSV) {
+		t.Errorf("problems = %v, want line 3 malformed", report.Problems)
+	}
+}
+
+func TestPlan_Options(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	tests := []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{"encryption", []Option{WithEncryption(Encryption{Permissions: PermitPrint})}, ErrInvalidEncryption},
+		{"forensic mark template", []Option{WithForensicMark("{{nobody}}"), WithForensicKey(testForensicKey)}, ErrInvalidTemplate},
+		{"forensic key", []Option{WithForensicMark("jane@example.com")}, ErrInvalidForensicKey},
+	}
+	for _, tt := range tests {
+		_, err := Plan(context.Background(), bytes.NewReader(pdf), strings.NewReader("page,watermark_text\n1,A\n"), tt.opts...)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPlan_ClampToLastPage(t *testing.T) {
+	pdf := createTestPDF(t, 12)
+	in := "page,watermark_text\n5,MID\n20,END\n"
+	plan, err := Plan(context.Background(), bytes.NewReader(pdf), strings.NewReader(in), WithPagePolicy(ClampToLastPage))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Pages) != 2 || plan.Pages[1].Page != 12 || plan.Pages[1].Stamps[0].Text != "END" {
+		t.Errorf("pages = %+v, want END moved to page 12", plan.Pages)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].Line != 3 || plan.Skipped[0].Page != 20 {
+		t.Errorf("skipped = %v, want line 3 page 20", plan.Skipped)
+	}
+}
@@ -0,0 +1,65 @@
+package pdfmark
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+)
+
+// Remove reads a PDF from src, strips the watermarks added by pdfmark or
+// pdfcpu from the pages selected by pages, and writes the result to dst.
+// The zero Pages selects every page. Selecting a page past the end fails
+// with ErrPageOutOfRange, and a selection without any watermark fails with
+// ErrNoWatermark. Forensic marks are kept; use WithReplace to strip and
+// stamp in one pass instead.
+//
+// WithPasswords, WithEncryption and WithMaxInputSize apply; other options
+// are ignored. An encrypted input stays encrypted with its own passwords
+// unless WithEncryption replaces them. The caller is responsible for
+// closing dst.
+func Remove(dst io.WriteCloser, src io.Reader, pages Pages, opts ...Option) error {
+	cfg := newConfig(opts)
+	if cfg.encrypt != nil {
+		if err := cfg.encrypt.Validate(); err != nil {
+			return err
+		}
+	}
+	rs, cleanup, err := stamp.OpenInput(src, cfg.maxInputSize)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	totalPages, err := stamp.PageCount(rs, cfg.passwords)
+	if err != nil {
+		return err
+	}
+
+	selected := make([]int, totalPages)
+	for i := range selected {
+		selected[i] = i + 1
+	}
+	if !pages.IsZero() {
+		selected = pages.Resolve(totalPages)
+		var problems []*errs.InstructionError
+		for _, page := range selected {
+			if page < 1 || page > totalPages {
+				problems = append(problems, &errs.InstructionError{
+					Err:    errs.ErrPageOutOfRange,
+					Page:   page,
+					Value:  pages.String(),
+					Detail: fmt.Sprintf("PDF has %d pages", totalPages),
+				})
+			}
+		}
+		if err := errs.Join(problems); err != nil {
+			return err
+		}
+	}
+
+	return stamp.Remove(rs, dst, selected, stamp.Options{
+		Passwords: cfg.passwords,
+		Encrypt:   cfg.encrypt,
+	})
+}
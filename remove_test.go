@@ -0,0 +1,134 @@
+package pdfmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// stampDraft stamps DRAFT on every page of a fresh PDF, plus an underlaid
+// logo on page 1 and a forensic mark.
+func stampDraft(t *testing.T, pages int) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, pages)),
+		csvString("page,watermark_text,image", "all,DRAFT,", "1,,logo"),
+		WithLayering(), WithForensicMark("jane@example.com"), WithForensicKey(testForensicKey),
+		WithImages(map[string][]byte{"logo": createTestPNG(t, 64, 64)}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	return out.Bytes()
+}
+
+// watermarkTexts returns the text of the watermarks on each page of pdf,
+// "<image>" standing for images.
+func watermarkTexts(t *testing.T, pdf []byte) [][]string {
+	t.Helper()
+	r, err := Inspect(bytes.NewReader(pdf))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	texts := make([][]string, len(r.Pages))
+	for i, p := range r.Pages {
+		for _, w := range p.Watermarks {
+			if w.Kind == ImageWatermark {
+				texts[i] = append(texts[i], "<image>")
+			} else {
+				texts[i] = append(texts[i], w.Text)
+			}
+		}
+	}
+	return texts
+}
+
+func TestRemove(t *testing.T) {
+	draft := stampDraft(t, 3)
+	pages, err := ParsePages("1-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Remove(nopWriteCloser{&out}, bytes.NewReader(draft), pages); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+	got := watermarkTexts(t, out.Bytes())
+	if len(got[0]) != 0 || len(got[1]) != 0 || len(got[2]) != 1 || got[2][0] != "DRAFT" {
+		t.Errorf("watermarks after removal = %q, want only DRAFT on page 3", got)
+	}
+	if out.Len() >= len(draft) {
+		t.Errorf("output is %d bytes, input %d; want the logo dropped", out.Len(), len(draft))
+	}
+
+	r, err := Trace(bytes.NewReader(out.Bytes()), WithForensicKey(testForensicKey))
+	if err != nil || r.ID != "jane@example.com" || len(r.Pages) != 3 {
+		t.Errorf("Trace = %+v, %v; want the forensic mark kept", r, err)
+	}
+}
+
+func TestRemove_AllPages(t *testing.T) {
+	var out bytes.Buffer
+	if err := Remove(nopWriteCloser{&out}, bytes.NewReader(stampDraft(t, 2)), Pages{}); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	for i, texts := range watermarkTexts(t, out.Bytes()) {
+		if len(texts) != 0 {
+			t.Errorf("page %d watermarks = %q, want none", i+1, texts)
+		}
+	}
+}
+
+func TestRemove_Errors(t *testing.T) {
+	last, _ := ParsePages("last")
+	tooFar, _ := ParsePages("2,5")
+
+	if err := Remove(nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(createTestPDF(t, 2)), last); !errors.Is(err, ErrNoWatermark) {
+		t.Errorf("unwatermarked PDF: got error %v, want ErrNoWatermark", err)
+	}
+	err := Remove(nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(stampDraft(t, 2)), tooFar)
+	if !errors.Is(err, ErrPageOutOfRange) {
+		t.Fatalf("page 5 of 2: got error %v, want ErrPageOutOfRange", err)
+	}
+	if p := Problems(err); len(p) != 1 || p[0].Page != 5 {
+		t.Errorf("problems = %v", p)
+	}
+
+	huge, _ := ParsePages("1-50000000")
+	err = Remove(nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(stampDraft(t, 2)), huge)
+	if p := Problems(err); len(p) != 1 || !errors.Is(p[0], ErrPageOutOfRange) || p[0].Page != 3 {
+		t.Errorf("pages 1-50000000 of 2: got error %v, want page 3 out of range", err)
+	}
+}
+
+func TestWithReplace(t *testing.T) {
+	draft := stampDraft(t, 2)
+	final := csvString("page,watermark_text", "1,FINAL")
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(draft), final, WithReplace())
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	got := watermarkTexts(t, out.Bytes())
+	if len(got[0]) != 1 || got[0][0] != "FINAL" {
+		t.Errorf("page 1 watermarks = %q, want FINAL only", got[0])
+	}
+	if len(got[1]) != 1 || got[1][0] != "DRAFT" {
+		t.Errorf("page 2 watermarks = %q, want DRAFT kept", got[1])
+	}
+
+	// Replacing on an unwatermarked PDF just stamps.
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text", "1,FINAL"), WithReplace())
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	if got := watermarkTexts(t, out.Bytes()); len(got[0]) != 1 {
+		t.Errorf("watermarks = %q, want FINAL", got)
+	}
+}
@@ -0,0 +1,101 @@
+package pdfmark
+
+import "github.com/anujkumar-df/pdfmark/internal/spec"
+
+// Style describes how a watermark looks and where it is placed on the page.
+// Start from DefaultStyle and override the fields you need.
+type Style = spec.Style
+
+// Position anchors a watermark on the page.
+type Position = spec.Position
+
+// Tiling lays out the grid of a Tiled watermark: the gaps between tiles,
+// whether rows are staggered, and the margin kept free along the page
+// edges, all in points. Style.Tile holds it. A grid of more than 1000
+// tiles on a page fails with ErrInvalidStyle.
+type Tiling = spec.Tiling
+
+// Diagonal selects a page diagonal to paint along.
+type Diagonal = spec.Diagonal
+
+// ScaleMode controls how Style.Scale is interpreted.
+type ScaleMode = spec.ScaleMode
+
+// RenderMode selects how glyph outlines are painted.
+type RenderMode = spec.RenderMode
+
+// Color is an RGB color with components in [0, 1].
+type Color = spec.Color
+
+// Anchor positions.
+const (
+	Center       = spec.Center
+	TopLeft      = spec.TopLeft
+	TopCenter    = spec.TopCenter
+	TopRight     = spec.TopRight
+	Left         = spec.Left
+	Right        = spec.Right
+	BottomLeft   = spec.BottomLeft
+	BottomCenter = spec.BottomCenter
+	BottomRight  = spec.BottomRight
+	// Tiled repeats a text watermark in a grid across each page, fitted
+	// to the page's crop box, so no part of the page is left unmarked.
+	// Tiles are drawn at Style.FontSize, times Style.Scale with
+	// ScaleAbsolute; relative scaling does not apply. Rotation or the
+	// diagonal sets the angle of every tile, and Dx and Dy shift the grid.
+	Tiled = spec.Tiled
+	// Header and footer presets draw a horizontal line of text, such as
+	// "Company Confidential – Page {{page}} of {{total_pages}}", anchored
+	// to the top or bottom edge of the page's crop box and Style.Margin
+	// inside it. LeftMargin and RightMargin run the text up the left or
+	// down the right edge. Presets follow page rotation, ignore Rotation
+	// and the diagonal, draw text at its font size like Tiled, and are
+	// shifted by Dx and Dy.
+	Header      = spec.Header
+	HeaderLeft  = spec.HeaderLeft
+	HeaderRight = spec.HeaderRight
+	Footer      = spec.Footer
+	FooterLeft  = spec.FooterLeft
+	FooterRight = spec.FooterRight
+	LeftMargin  = spec.LeftMargin
+	RightMargin = spec.RightMargin
+)
+
+// Diagonals.
+const (
+	NoDiagonal     = spec.NoDiagonal
+	DiagonalLLToUR = spec.DiagonalLLToUR
+	DiagonalULToLR = spec.DiagonalULToLR
+)
+
+// Scale modes.
+const (
+	ScaleRelative = spec.ScaleRelative
+	ScaleAbsolute = spec.ScaleAbsolute
+)
+
+// Render modes.
+const (
+	RenderFill       = spec.RenderFill
+	RenderStroke     = spec.RenderStroke
+	RenderFillStroke = spec.RenderFillStroke
+)
+
+// DefaultStyle returns the style used by Watermark: Helvetica 48pt, gray,
+// 0.3 opacity, stamped on top along the lower-left to upper-right diagonal,
+// centered on the page.
+func DefaultStyle() Style {
+	return spec.DefaultStyle()
+}
+
+// ParseColor parses a color name (black, gray, red, ...), a hex code
+// (#RRGGBB) or three space-separated intensities ("0.5 0.5 0.5").
+func ParseColor(s string) (Color, error) {
+	return spec.ParseColor(s)
+}
+
+// ParsePosition parses a position name such as "center", "top-left" or
+// "tiled".
+func ParsePosition(s string) (Position, error) {
+	return spec.ParsePosition(s)
+}
@@ -0,0 +1,69 @@
+package pdfmark
+
+import (
+	"github.com/anujkumar-df/pdfmark/internal/decode"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
+)
+
+// parseTemplates parses every distinct watermark text that contains
+// placeholders, keyed by the raw text. names lists the caller variables.
+// Every text that fails to parse is reported.
+func parseTemplates(instructions []spec.Instruction, names []string) (map[string]*tmpl.Template, error) {
+	templates := make(map[string]*tmpl.Template)
+	var problems []*errs.InstructionError
+	for _, ins := range instructions {
+		if !tmpl.IsTemplate(ins.Text) {
+			continue
+		}
+		if _, done := templates[ins.Text]; done {
+			continue
+		}
+		t, err := tmpl.Parse(ins.Text, names)
+		if err != nil {
+			p := errs.Wrap(err)
+			p.Line, p.Column = ins.Line, decode.FieldText
+			problems = append(problems, p)
+			continue
+		}
+		templates[ins.Text] = t
+	}
+	return templates, errs.Join(problems)
+}
+
+// renderTemplates replaces the text of every templated instruction with its
+// rendering for the page it is stamped on. base supplies everything but the
+// page number.
+func renderTemplates(pages map[int][]spec.Instruction, templates map[string]*tmpl.Template, base tmpl.Data) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	for page, list := range pages {
+		d := base
+		d.Page = page
+		for i, ins := range list {
+			t, ok := templates[ins.Text]
+			if !ok {
+				continue
+			}
+			text, err := t.Execute(d)
+			if err != nil {
+				p := errs.Wrap(err)
+				p.Line, p.Column, p.Page = ins.Line, decode.FieldText, page
+				return p
+			}
+			if text == "" {
+				return &errs.InstructionError{
+					Err:    errs.ErrInvalidTemplate,
+					Line:   ins.Line,
+					Column: decode.FieldText,
+					Page:   page,
+					Detail: "text renders empty",
+				}
+			}
+			list[i].Text = text
+		}
+	}
+	return nil
+}
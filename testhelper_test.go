@@ -31,3 +31,8 @@ type nopWriteCloser struct {
 }
 
 func (nopWriteCloser) Close() error { return nil }
+
+func createTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	return testutil.CreateTestPNG(t, w, h)
+}
@@ -0,0 +1,77 @@
+package pdfmark
+
+import (
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/stamp"
+)
+
+// TraceReport describes the forensic mark recovered by Trace.
+type TraceReport struct {
+	// ID is the recovered mark, as given to WithForensicMark.
+	ID string
+	// Pages lists the pages marked with ID, in page order.
+	Pages []int
+	// Metadata reports whether the XMP metadata is marked with ID.
+	Metadata bool
+	// Others lists further IDs found, for example when pages of several
+	// copies were combined, in order of their first page.
+	Others []string
+}
+
+// Trace recovers the forensic mark written by WithForensicMark from the PDF
+// in src, to tell whose copy leaked. The ID marked on the most pages wins,
+// the metadata breaking ties; the metadata alone is used if no page is
+// marked, as happens when pages were re-rendered. WithForensicKey is
+// required and must be the key the mark was sealed under; WithPasswords and
+// WithMaxInputSize apply too, other options are ignored. A PDF without a
+// mark under the key fails with ErrNoForensicMark.
+func Trace(src io.Reader, opts ...Option) (*TraceReport, error) {
+	cfg := newConfig(opts)
+	if err := cfg.checkForensicKey(); err != nil {
+		return nil, err
+	}
+	rs, cleanup, err := stamp.OpenInput(src, cfg.maxInputSize)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	marks, err := stamp.ReadMarks(rs, cfg.passwords, cfg.forensicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string][]int)
+	var ids []string
+	for _, page := range slices.Sorted(maps.Keys(marks.Pages)) {
+		id := marks.Pages[page]
+		if pages[id] == nil {
+			ids = append(ids, id)
+		}
+		pages[id] = append(pages[id], page)
+	}
+	if marks.Metadata != "" && pages[marks.Metadata] == nil {
+		ids = append(ids, marks.Metadata)
+	}
+	if len(ids) == 0 {
+		return nil, errs.ErrNoForensicMark
+	}
+
+	best := ids[0]
+	for _, id := range ids[1:] {
+		n, m := len(pages[id]), len(pages[best])
+		if n > m || n == m && id == marks.Metadata {
+			best = id
+		}
+	}
+	r := &TraceReport{ID: best, Pages: pages[best], Metadata: best == marks.Metadata}
+	for _, id := range ids {
+		if id != best {
+			r.Others = append(r.Others, id)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,130 @@
+package pdfmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testForensicKey seals the forensic marks of the tests.
+var testForensicKey = []byte("0123456789abcdef")
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 3)),
+		csvString("page,watermark_text", "1,CONFIDENTIAL"), WithForensicMark("jane@example.com"), WithForensicKey(testForensicKey))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+
+	r, err := Trace(bytes.NewReader(out.Bytes()), WithForensicKey(testForensicKey))
+	if err != nil {
+		t.Fatalf("Trace: %v", err)
+	}
+	if r.ID != "jane@example.com" || !slices.Equal(r.Pages, []int{1, 2, 3}) || !r.Metadata || len(r.Others) > 0 {
+		t.Errorf("Trace = %+v", r)
+	}
+}
+
+func TestTrace_Key(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text", "1,X"), WithForensicMark("jane@example.com"))
+	if !errors.Is(err, ErrInvalidForensicKey) {
+		t.Errorf("marking without key: got error %v, want ErrInvalidForensicKey", err)
+	}
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text", "1,X"), WithForensicMark("jane@example.com"), WithForensicKey([]byte("short")))
+	if !errors.Is(err, ErrInvalidForensicKey) {
+		t.Errorf("marking with short key: got error %v, want ErrInvalidForensicKey", err)
+	}
+
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text", "1,X"), WithForensicMark("jane@example.com"), WithForensicKey(testForensicKey))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	if bytes.Contains(out.Bytes(), []byte("jane@example.com")) {
+		t.Error("output contains the ID in clear")
+	}
+	if _, err := Trace(bytes.NewReader(out.Bytes())); !errors.Is(err, ErrInvalidForensicKey) {
+		t.Errorf("tracing without key: got error %v, want ErrInvalidForensicKey", err)
+	}
+	if _, err := Trace(bytes.NewReader(out.Bytes()), WithForensicKey([]byte("fedcba9876543210"))); !errors.Is(err, ErrNoForensicMark) {
+		t.Errorf("tracing with another key: got error %v, want ErrNoForensicMark", err)
+	}
+	report, err := Inspect(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if m := report.Pages[0].ForensicMark; m != "" {
+		t.Errorf("Inspect without key found mark %q", m)
+	}
+}
+
+func TestTrace_NoMark(t *testing.T) {
+	_, err := Trace(bytes.NewReader(createTestPDF(t, 1)), WithForensicKey(testForensicKey))
+	if !errors.Is(err, ErrNoForensicMark) {
+		t.Errorf("got error %v, want ErrNoForensicMark", err)
+	}
+}
+
+func TestTrace_Encrypted(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 2)),
+		csvString("page,watermark_text", "1,CONFIDENTIAL"),
+		WithForensicMark("jane@example.com"), WithForensicKey(testForensicKey), WithEncryption(Encryption{UserPassword: "user"}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+
+	if _, err := Trace(bytes.NewReader(out.Bytes()), WithForensicKey(testForensicKey)); !errors.Is(err, ErrEncryptedPDF) {
+		t.Errorf("without password: got error %v, want ErrEncryptedPDF", err)
+	}
+	r, err := Trace(bytes.NewReader(out.Bytes()), WithPasswords("user", ""), WithForensicKey(testForensicKey))
+	if err != nil {
+		t.Fatalf("Trace: %v", err)
+	}
+	if r.ID != "jane@example.com" {
+		t.Errorf("ID = %q, want jane@example.com", r.ID)
+	}
+}
+
+func TestTrace_Merge(t *testing.T) {
+	dir := t.TempDir()
+	err := Merge(context.Background(), DirOutput(dir), bytes.NewReader(createTestPDF(t, 2)), mergeCSV(), testRecipients(t),
+		WithForensicMark("{{email}}"), WithForensicKey(testForensicKey))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	for _, email := range []string{"jane@example.com", "joe@example.com"} {
+		f, err := os.Open(filepath.Join(dir, email+".pdf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, err := Trace(f, WithForensicKey(testForensicKey))
+		if err != nil {
+			t.Fatalf("Trace(%s): %v", email, err)
+		}
+		if r.ID != email {
+			t.Errorf("copy for %s traced to %q", email, r.ID)
+		}
+	}
+}
+
+func TestWithForensicMark_InvalidTemplate(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text", "1,X"), WithForensicMark("{{nobody}}"), WithForensicKey(testForensicKey))
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
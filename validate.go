@@ -0,0 +1,161 @@
+package pdfmark
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
+)
+
+// ValidationReport lists every problem found in a set of instructions.
+type ValidationReport struct {
+	// Problems lists the invalid instructions, ordered by line.
+	Problems []*InstructionError
+	// Warnings lists non-fatal problems such as unknown columns.
+	Warnings []error
+}
+
+// OK reports whether no problems were found.
+func (r *ValidationReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Error summarises the problems, one per line.
+func (r *ValidationReport) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pdfmark: %d invalid instruction(s)", len(r.Problems))
+	for _, p := range r.Problems {
+		b.WriteString("\n\t")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the problems, so that errors.Is and errors.As look at
+// each of them.
+func (r *ValidationReport) Unwrap() []error {
+	list := make([]error, len(r.Problems))
+	for i, p := range r.Problems {
+		list[i] = p
+	}
+	return list
+}
+
+// Err returns r as an error, or nil if no problems were found.
+func (r *ValidationReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return r
+}
+
+// ValidateInstructions reads instructions from r and checks them the way
+// WatermarkWithOptions does, without reading a PDF: field values, styles,
+// templates and referenced images and stamp PDFs. Unlike
+// WatermarkWithOptions it does not stop at the first invalid instruction
+// but reports all of them in the returned ValidationReport. Checks that
+// need the page count, such as pages out of range, are not made; use Plan
+// for those.
+//
+// The error is non-nil only if the input could not be checked at all, for
+// example because it is empty, it is not valid JSON, an option such as
+// WithEncryption is invalid or ctx is done.
+func ValidateInstructions(ctx context.Context, r io.Reader, opts ...Option) (*ValidationReport, error) {
+	cfg := newConfig(opts)
+	c, err := checkInstructions(ctx, r, cfg, slices.Collect(maps.Keys(cfg.vars)))
+	if err != nil {
+		return nil, err
+	}
+	c.report.sort()
+	return c.report, nil
+}
+
+// checked holds instructions decoded and checked by checkInstructions.
+type checked struct {
+	instructions []spec.Instruction
+	templates    map[string]*tmpl.Template
+	report       *ValidationReport
+	// complete is false if the input could not be read to the end.
+	complete bool
+}
+
+// checkInstructions checks the options and decodes the instructions in r,
+// running the checks of prepare that do not need the PDF and recording
+// every problem in the report. It returns an error only if the options are
+// invalid or the input cannot be checked at all.
+func checkInstructions(ctx context.Context, r io.Reader, cfg config, varNames []string) (*checked, error) {
+	if _, err := checkOptions(cfg, varNames); err != nil {
+		return nil, err
+	}
+	if err := checkContext(ctx, "parsing instructions"); err != nil {
+		return nil, err
+	}
+
+	dec, err := cfg.instructionDecoder()
+	if err != nil {
+		return nil, err
+	}
+	c := &checked{report: &ValidationReport{}}
+	parsed, err := dec.Decode(ctx, r, DecodeOptions{
+		StrictColumns: cfg.strictColumns,
+		Layered:       cfg.layered,
+		Policy:        cfg.pagePolicy,
+		CollectAll:    true,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("pdfmark: parsing instructions: %w", err)
+		}
+		problems := Problems(err)
+		if len(problems) == 0 {
+			return nil, err
+		}
+		c.report.Problems = problems
+	}
+	if parsed == nil {
+		// The input could not be read past a syntax error.
+		return c, nil
+	}
+	c.complete = true
+	c.instructions = parsed.Instructions
+	c.report.Warnings = parsed.Warnings
+
+	c.report.add(validateStyles(parsed.Instructions, cfg.style))
+	c.templates, err = parseTemplates(parsed.Instructions, varNames)
+	c.report.add(err)
+	if err := checkContext(ctx, "loading assets"); err != nil {
+		return nil, err
+	}
+	_, err = loadAssets(parsed.Instructions, cfg)
+	c.report.add(err)
+	return c, nil
+}
+
+// add records the problems in err.
+func (r *ValidationReport) add(err error) {
+	r.Problems = append(r.Problems, Problems(err)...)
+}
+
+// join returns err joined with r if r has problems, and err otherwise.
+func (r *ValidationReport) join(err error) error {
+	if r.OK() {
+		return err
+	}
+	r.sort()
+	return errors.Join(err, r)
+}
+
+// sort orders the problems by line, keeping the order of problems found on
+// the same line.
+func (r *ValidationReport) sort() {
+	slices.SortStableFunc(r.Problems, func(a, b *InstructionError) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+}
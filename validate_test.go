@@ -0,0 +1,84 @@
+package pdfmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateInstructions(t *testing.T) {
+	in := strings.Join([]string{
+		"page,watermark_text,image,opacity",
+		"1,OK,,",
+		"0,BAD PAGE,,",
+		"2,,missing.png,",
+		"3,FADED,,1.5",
+		"4,{{nope}},,",
+	}, "\n")
+	report, err := ValidateInstructions(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ValidateInstructions: %v", err)
+	}
+	want := []struct {
+		line     int
+		sentinel error
+	}{
+		{3, ErrInvalidPage},
+		{4, ErrAssetNotFound},
+		{5, ErrMalformedCSV},
+		{6, ErrInvalidTemplate},
+	}
+	if len(report.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(report.Problems), len(want), report)
+	}
+	for i, w := range want {
+		if p := report.Problems[i]; p.Line != w.line || !errors.Is(p, w.sentinel) {
+			t.Errorf("problem %d = %v, want line %d matching %v", i, p, w.line, w.sentinel)
+		}
+	}
+	if p := report.Problems[1]; p.Column != "image" || p.Value != "missing.png" {
+		t.Errorf("asset problem = %+v, want column image value missing.png", p)
+	}
+	if !errors.Is(report.Err(), ErrInvalidPage) || !errors.Is(report.Err(), ErrInvalidTemplate) {
+		t.Errorf("report error %v does not match every problem", report.Err())
+	}
+}
+
+func TestValidateInstructions_OK(t *testing.T) {
+	report, err := ValidateInstructions(context.Background(), strings.NewReader("page,watermark_text,colour\n1,OK,red\n"))
+	if err != nil {
+		t.Fatalf("ValidateInstructions: %v", err)
+	}
+	if !report.OK() || report.Err() != nil {
+		t.Errorf("got problems %v, want none", report.Problems)
+	}
+	if len(report.Warnings) != 1 || !errors.Is(report.Warnings[0], ErrUnknownColumn) {
+		t.Errorf("warnings = %v, want one ErrUnknownColumn", report.Warnings)
+	}
+}
+
+func TestValidateInstructions_Empty(t *testing.T) {
+	_, err := ValidateInstructions(context.Background(), strings.NewReader(""))
+	if !errors.Is(err, ErrEmptyCSV) {
+		t.Errorf("got error %v, want ErrEmptyCSV", err)
+	}
+}
+
+func TestWatermark_InstructionError(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), strings.NewReader("page,watermark_text\n1,A\n5,B\n9,C\n"))
+	if !errors.Is(err, ErrPageOutOfRange) {
+		t.Fatalf("got error %v, want ErrPageOutOfRange", err)
+	}
+	problems := Problems(err)
+	if len(problems) != 2 {
+		t.Fatalf("got %d problems, want 2: %v", len(problems), err)
+	}
+	var ie *InstructionError
+	if !errors.As(err, &ie) || ie.Line != 3 || ie.Page != 5 {
+		t.Errorf("got %+v, want line 3 page 5", ie)
+	}
+}
@@ -1,40 +1,318 @@
 package pdfmark
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"time"
 
-	"github.com/anujkumar-df/pdfmark/internal/csvparse"
+	"github.com/anujkumar-df/pdfmark/internal/errs"
+	"github.com/anujkumar-df/pdfmark/internal/spec"
 	"github.com/anujkumar-df/pdfmark/internal/stamp"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
 )
 
-// Watermark reads a PDF from src, applies text watermarks according to the CSV
+// Watermark reads a PDF from src, applies watermarks according to the CSV
 // data from csvData, and writes the resulting PDF to dst.
 //
-// The CSV must have a header row and at least two columns: page (1-indexed) and
-// watermark_text. Pages not listed in the CSV are passed through unchanged.
+// The CSV must have a header row and at least two columns: page
+// (1-indexed) and watermark_text. The page column may also hold a page
+// selector such as "1-5", "odd", "even", "last", "-1" or "all"; a page may
+// be selected by at most one row unless WithLayering is used. Optional
+// columns font, font_size, color, opacity, rotation, position, dx, dy,
+// margin, scale and render_mode override the style for a single row. A row
+// may set the image column instead of watermark_text to stamp a PNG or JPEG
+// supplied through WithImages or WithImageFS, or the pdf column to stamp a
+// page of another PDF (chosen by pdf_page, default 1) supplied through
+// WithPDFStamps or WithPDFStampFS. The watermark text may contain
+// placeholders such as {{page}} and {{total_pages}}, rendered for each
+// page; see the package documentation. The instructions may also be given
+// as JSON or YAML using the column names as keys; the format is detected
+// from the content unless WithFormat or WithDecoder is used. Pages not
+// listed in the instructions are passed through unchanged. Watermarks are
+// drawn with DefaultStyle; use WatermarkWithOptions to change the look.
 //
 // The caller is responsible for closing dst; this function only writes to it.
+// Sources that implement io.ReaderAt and io.Seeker, such as *os.File, are
+// read in place; other readers are buffered in memory, or spilled to a
+// temporary file when large. pdfcpu still holds the parsed document in
+// memory, so use WithMaxInputSize to bound the input accepted.
 //
 // Watermark is safe for concurrent use from multiple goroutines.
 func Watermark(dst io.WriteCloser, src io.Reader, csvData io.Reader) error {
-	instructions, err := csvparse.Parse(csvData)
+	return WatermarkWithOptions(context.Background(), dst, src, csvData)
+}
+
+// WatermarkWithOptions is like Watermark but accepts a context and options
+// controlling the watermark style. ctx is checked between the parse, count,
+// validate and stamp stages; once it is done, WatermarkWithOptions returns
+// an error wrapping ctx.Err(), so errors.Is(err, context.Canceled) or
+// errors.Is(err, context.DeadlineExceeded) reports why.
+func WatermarkWithOptions(ctx context.Context, dst io.WriteCloser, src io.Reader, csvData io.Reader, opts ...Option) error {
+	cfg := newConfig(opts)
+	j, err := prepare(ctx, src, csvData, cfg, slices.Collect(maps.Keys(cfg.vars)))
 	if err != nil {
 		return err
 	}
+	defer j.close()
+	return j.write(ctx, dst, cfg.vars)
+}
+
+// WatermarkReaderAt is like WatermarkWithOptions but reads the size bytes of
+// the PDF from src in place, avoiding the copy made for a plain io.Reader.
+// It suits large files opened with os.Open.
+func WatermarkReaderAt(ctx context.Context, dst io.WriteCloser, src io.ReaderAt, size int64, csvData io.Reader, opts ...Option) error {
+	return WatermarkWithOptions(ctx, dst, io.NewSectionReader(src, 0, size), csvData, opts...)
+}
 
-	rs, err := stamp.BufferReader(src)
+// job is a watermarking run prepared up to the point of stamping, so the
+// same PDF and instructions can be stamped several times. close releases the
+// input and must be called when done.
+type job struct {
+	cfg          config
+	rs           io.ReadSeeker
+	doc          *stamp.Document // set by parse; nil to read rs on every write
+	close        func()
+	totalPages   int
+	instructions map[int][]spec.Instruction
+	templates    map[string]*tmpl.Template
+	mark         *tmpl.Template
+	assets       assets
+	date         time.Time
+}
+
+// prepare parses and validates the instructions, reads the PDF and resolves
+// the page selectors. varNames lists the template variables that may be
+// used in the watermark text.
+func prepare(ctx context.Context, src io.Reader, csvData io.Reader, cfg config, varNames []string) (_ *job, err error) {
+	if err := checkContext(ctx, "parsing instructions"); err != nil {
+		return nil, err
+	}
+	mark, err := checkOptions(cfg, varNames)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	totalPages, err := stamp.PageCount(rs)
+	dec, err := cfg.instructionDecoder()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	parsed, err := dec.Decode(ctx, csvData, DecodeOptions{
+		StrictColumns: cfg.strictColumns,
+		Layered:       cfg.layered,
+		Policy:        cfg.pagePolicy,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("pdfmark: parsing instructions: %w", err)
+		}
+		return nil, err
+	}
+	if cfg.warn != nil {
+		for _, w := range parsed.Warnings {
+			cfg.warn(w)
+		}
+	}
+	if err := validateStyles(parsed.Instructions, cfg.style); err != nil {
+		return nil, err
+	}
+
+	templates, err := parseTemplates(parsed.Instructions, varNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkContext(ctx, "loading assets"); err != nil {
+		return nil, err
+	}
+	assets, err := loadAssets(parsed.Instructions, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkContext(ctx, "reading PDF"); err != nil {
+		return nil, err
+	}
+	rs, cleanup, err := stamp.OpenInput(src, cfg.maxInputSize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	if err := checkContext(ctx, "counting pages"); err != nil {
+		return nil, err
+	}
+	totalPages, err := stamp.PageCount(rs, cfg.passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkContext(ctx, "validating pages"); err != nil {
+		return nil, err
+	}
+	instructions, skipped, err := spec.Expand(parsed.Instructions, totalPages, cfg.layered, cfg.pagePolicy)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.warn != nil {
+		for _, s := range skipped {
+			cfg.warn(s)
+		}
 	}
 
 	if err := stamp.ValidatePages(instructions, totalPages); err != nil {
+		return nil, err
+	}
+
+	date := cfg.date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	return &job{
+		cfg:          cfg,
+		rs:           rs,
+		close:        cleanup,
+		totalPages:   totalPages,
+		instructions: instructions,
+		templates:    templates,
+		mark:         mark,
+		assets:       assets,
+		date:         date,
+	}, nil
+}
+
+// parse reads and validates the PDF once for repeated writes, which then
+// stamp a cheap parse of the validated PDF instead of reading it again.
+func (j *job) parse(ctx context.Context) error {
+	if err := checkContext(ctx, "reading PDF"); err != nil {
+		return err
+	}
+	doc, err := stamp.ReadDocument(j.rs, j.cfg.passwords)
+	if err != nil {
 		return err
 	}
+	j.doc = doc
+	return nil
+}
+
+// write renders the watermark text with vars and writes the stamped PDF to
+// dst. It may be called repeatedly but not concurrently.
+func (j *job) write(ctx context.Context, dst io.Writer, vars map[string]string) error {
+	data := tmpl.Data{
+		TotalPages: j.totalPages,
+		Date:       j.date,
+		Filename:   j.cfg.filename,
+		Vars:       vars,
+	}
+	instructions := j.instructions
+	if len(j.templates) > 0 {
+		// Rendering replaces the text, so work on a copy.
+		instructions = make(map[int][]spec.Instruction, len(j.instructions))
+		for page, list := range j.instructions {
+			instructions[page] = slices.Clone(list)
+		}
+		if err := renderTemplates(instructions, j.templates, data); err != nil {
+			return err
+		}
+	}
+	var mark string
+	if j.mark != nil {
+		var err error
+		if mark, err = j.mark.Execute(data); err != nil {
+			return fmt.Errorf("forensic mark: %w", err)
+		}
+		if mark == "" {
+			return fmt.Errorf("%w: forensic mark renders empty", errs.ErrInvalidTemplate)
+		}
+	}
+
+	var strip []int
+	if j.cfg.replace {
+		strip = slices.Sorted(maps.Keys(j.instructions))
+	}
 
-	return stamp.Apply(rs, dst, instructions)
+	opts := stamp.Options{
+		Style:     j.cfg.style,
+		Images:    j.assets.images,
+		PDFs:      j.assets.pdfs,
+		Passwords: j.cfg.passwords,
+		Encrypt:   j.cfg.encrypt,
+		Mark:      mark,
+		MarkKey:   j.cfg.forensicKey,
+		Strip:     strip,
+	}
+	var err error
+	if j.doc != nil {
+		err = j.doc.Apply(ctx, dst, instructions, opts)
+	} else {
+		if _, err := j.rs.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("seeking PDF: %w", err)
+		}
+		err = stamp.Apply(ctx, j.rs, dst, instructions, opts)
+	}
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("pdfmark: stamping: %w", err)
+	}
+	return err
+}
+
+// checkOptions checks the settings in cfg that do not depend on the
+// instructions: the base style, the output encryption and the forensic
+// mark, which it returns parsed, or nil if none is set.
+func checkOptions(cfg config, varNames []string) (*tmpl.Template, error) {
+	if err := cfg.style.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.encrypt != nil {
+		if err := cfg.encrypt.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.forensicMark == "" {
+		return nil, nil
+	}
+	if err := cfg.checkForensicKey(); err != nil {
+		return nil, err
+	}
+	mark, err := tmpl.Parse(cfg.forensicMark, varNames)
+	if err != nil {
+		return nil, fmt.Errorf("%w: forensic mark: %w", errs.ErrInvalidTemplate, err)
+	}
+	return mark, nil
+}
+
+// checkContext returns an error wrapping ctx.Err() if ctx is done, naming
+// the stage that was about to start.
+func checkContext(ctx context.Context, stage string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("pdfmark: %s: %w", stage, err)
+	}
+	return nil
+}
+
+// validateStyles checks the effective style of every instruction and
+// reports each instruction whose style is invalid.
+func validateStyles(instructions []spec.Instruction, base spec.Style) error {
+	var problems []*errs.InstructionError
+	for _, ins := range instructions {
+		style := ins.Style.Apply(base)
+		if err := style.Validate(); err != nil {
+			p := errs.Wrap(err)
+			p.Line = ins.Line
+			problems = append(problems, p)
+		} else if style.Position == spec.Tiled && ins.Text == "" {
+			problems = append(problems, &errs.InstructionError{
+				Err:    errs.ErrInvalidStyle,
+				Line:   ins.Line,
+				Detail: "only watermark text can be tiled",
+			})
+		}
+	}
+	return errs.Join(problems)
 }
@@ -2,10 +2,21 @@ package pdfmark
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/anujkumar-df/pdfmark/internal/spec"
+	"github.com/anujkumar-df/pdfmark/internal/testutil"
+	"github.com/anujkumar-df/pdfmark/internal/tmpl"
 )
 
 func TestWatermark_EndToEnd(t *testing.T) {
@@ -80,6 +91,47 @@ func TestWatermark_PageOutOfRange(t *testing.T) {
 	}
 }
 
+func TestWatermark_HugeRange(t *testing.T) {
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 5)),
+		csvString("page,watermark_text", "1-50000000,BAD"))
+	if p := Problems(err); len(p) != 1 || !errors.Is(p[0], ErrPageOutOfRange) || p[0].Page != 6 {
+		t.Errorf("got error %v, want page 6 out of range", err)
+	}
+}
+
+func TestWatermarkWithOptions_PagePolicy(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	lines := []string{"page,watermark_text", "1,OK", "1,AGAIN", "2-20,TAIL"}
+
+	var skipped []*InstructionError
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csvString(lines...),
+		WithPagePolicy(SkipInvalid),
+		WithWarningHandler(func(err error) {
+			var ie *InstructionError
+			if errors.As(err, &ie) {
+				skipped = append(skipped, ie)
+			}
+		}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+	// Line 3 repeats page 1 and line 4 runs 17 pages past the end.
+	if len(skipped) != 2 || !errors.Is(skipped[0], ErrDuplicatePage) || !errors.Is(skipped[1], ErrPageOutOfRange) ||
+		skipped[1].Detail != "pages 4-20 skipped, PDF has 3 pages" {
+		t.Errorf("skipped = %v, want a duplicate and pages 4-20", skipped)
+	}
+
+	// The default policy still rejects the CSV.
+	err = Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), csvString(lines...))
+	if !errors.Is(err, ErrDuplicatePage) {
+		t.Errorf("got error %v, want ErrDuplicatePage", err)
+	}
+}
+
 func TestWatermark_InvalidPDF(t *testing.T) {
 	csv := csvString(
 		"page,watermark_text",
@@ -106,6 +158,20 @@ func TestWatermark_EmptyPDF(t *testing.T) {
 	}
 }
 
+func TestWatermark_TruncatedPDF(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	csv := csvString("page,watermark_text", "1,TEST")
+
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf[:len(pdf)/2]), csv)
+	if !errors.Is(err, ErrTruncatedPDF) {
+		t.Errorf("got error %v, want ErrTruncatedPDF", err)
+	}
+	if !errors.Is(err, ErrInvalidPDF) {
+		t.Errorf("error %v does not match ErrInvalidPDF", err)
+	}
+}
+
 func TestWatermark_BadCSV(t *testing.T) {
 	pdf := createTestPDF(t, 3)
 	csv := csvString(
@@ -188,3 +254,594 @@ func TestWatermark_Concurrent(t *testing.T) {
 		t.Errorf("concurrent error: %v", err)
 	}
 }
+
+func TestWatermarkWithOptions_Style(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text",
+		"1,CONFIDENTIAL",
+		"2,DRAFT",
+	)
+
+	s := DefaultStyle()
+	s.FontName = "Times-Roman"
+	s.FontSize = 36
+	s.Color = Color{R: 1}
+	s.Opacity = 0.5
+	s.Diagonal = NoDiagonal
+	s.Rotation = 45
+	s.Position = TopCenter
+	s.Dy = -20
+	s.OnTop = false
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv, WithStyle(s))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+}
+
+func TestWatermarkWithOptions_InvalidStyle(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	csv := csvString(
+		"page,watermark_text",
+		"1,TEST",
+	)
+
+	s := DefaultStyle()
+	s.Opacity = 0
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv, WithStyle(s))
+	if !errors.Is(err, ErrInvalidStyle) {
+		t.Errorf("got error %v, want ErrInvalidStyle", err)
+	}
+}
+
+func TestWatermarkWithOptions_CanceledContext(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	csv := csvString(
+		"page,watermark_text",
+		"1,TEST",
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(ctx, nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
+
+func TestWatermarkWithOptions_CanceledBetweenStages(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	csv := csvString(
+		"page,watermark_text,unknown",
+		"1,DRAFT,x",
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The warning handler runs after parsing, so cancelling there stops
+	// the run before the PDF is read.
+	var out bytes.Buffer
+	err := WatermarkWithOptions(ctx, nopWriteCloser{&out}, bytes.NewReader(pdf), csv,
+		WithWarningHandler(func(error) { cancel() }))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("wrote %d bytes after cancellation", out.Len())
+	}
+}
+
+func TestWatermarkWithOptions_DeadlineExceeded(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(ctx, nopWriteCloser{&out}, bytes.NewReader(pdf), csvString("page,watermark_text", "1,DRAFT"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWatermark_StyleColumns(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text,font,font_size,color,opacity,rotation,position,dx,dy,render_mode",
+		"1,CONFIDENTIAL,,,,,,,,,",
+		"2,Recipient: Jane Doe,Courier,10,#000000,1,0,bottom-center,0,20,fill",
+		"3,OUTLINE,Times-Roman,,red,,ul-lr,,,,stroke",
+	)
+
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if err != nil {
+		t.Fatalf("Watermark: %v", err)
+	}
+
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+}
+
+func TestWatermarkWithOptions_UnknownColumn(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	newCSV := func() io.Reader {
+		return csvString(
+			"page,watermark_text,notes",
+			"1,TEST,reviewed by legal",
+		)
+	}
+
+	var warnings []error
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+		WithWarningHandler(func(err error) { warnings = append(warnings, err) }))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrUnknownColumn) {
+		t.Errorf("warnings = %v, want one ErrUnknownColumn", warnings)
+	}
+
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(), WithStrictColumns())
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("got error %v, want ErrUnknownColumn", err)
+	}
+}
+
+func TestWatermark_PageSelectors(t *testing.T) {
+	pdf := createTestPDF(t, 6)
+	csv := csvString(
+		"page,watermark_text",
+		"1-2,DRAFT",
+		`"4,5",REVIEW`,
+		"last,END",
+	)
+
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if err != nil {
+		t.Fatalf("Watermark: %v", err)
+	}
+
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 6)
+}
+
+func TestWatermark_RangeOutOfRange(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text",
+		"2-5,DRAFT",
+	)
+
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("got error %v, want ErrPageOutOfRange", err)
+	}
+}
+
+func TestWatermark_OverlappingSelectors(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text",
+		"all,DRAFT",
+		"last,FINAL",
+	)
+
+	var out bytes.Buffer
+	err := Watermark(nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if !errors.Is(err, ErrDuplicatePage) {
+		t.Errorf("got error %v, want ErrDuplicatePage", err)
+	}
+}
+
+func TestWatermarkWithOptions_Layering(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text,font_size,position,rotation",
+		"all,CONFIDENTIAL,,,",
+		"all,Copy for Jane Doe,10,bottom-center,0",
+		"2,SECOND PAGE,,,",
+	)
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv, WithLayering())
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+}
+
+func TestWatermarkWithOptions_Images(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	logo := createTestPNG(t, 60, 30)
+	newCSV := func() io.Reader {
+		return csvString(
+			"page,watermark_text,image,scale,opacity,position,rotation",
+			"1,CONFIDENTIAL,,,,,",
+			"2-3,,brand/logo.png,0.2,0.8,top-right,0",
+		)
+	}
+
+	t.Run("map", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+			WithImages(map[string][]byte{"brand/logo.png": logo}))
+		if err != nil {
+			t.Fatalf("WatermarkWithOptions: %v", err)
+		}
+		assertValidPDF(t, out.Bytes())
+		assertPageCount(t, out.Bytes(), 3)
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		fsys := fstest.MapFS{"brand/logo.png": {Data: logo}}
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+			WithImageFS(fsys))
+		if err != nil {
+			t.Fatalf("WatermarkWithOptions: %v", err)
+		}
+		assertValidPDF(t, out.Bytes())
+		assertPageCount(t, out.Bytes(), 3)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+			WithImageFS(fstest.MapFS{}))
+		if !errors.Is(err, ErrAssetNotFound) {
+			t.Errorf("got error %v, want ErrAssetNotFound", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+			WithImages(map[string][]byte{"brand/logo.png": []byte("not an image")}))
+		if !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("got error %v, want ErrInvalidImage", err)
+		}
+	})
+}
+
+func TestWatermarkWithOptions_PDFStamps(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	letterhead := createTestPDF(t, 2)
+	newCSV := func(stampPage string) io.Reader {
+		return csvString(
+			"page,watermark_text,pdf,pdf_page,opacity",
+			"1,CONFIDENTIAL,,,",
+			"2-3,,forms/letterhead.pdf,"+stampPage+",1",
+		)
+	}
+
+	t.Run("map", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV("2"),
+			WithPDFStamps(map[string][]byte{"forms/letterhead.pdf": letterhead}))
+		if err != nil {
+			t.Fatalf("WatermarkWithOptions: %v", err)
+		}
+		assertValidPDF(t, out.Bytes())
+		assertPageCount(t, out.Bytes(), 3)
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		fsys := fstest.MapFS{"forms/letterhead.pdf": {Data: letterhead}}
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(""),
+			WithPDFStampFS(fsys))
+		if err != nil {
+			t.Fatalf("WatermarkWithOptions: %v", err)
+		}
+		assertValidPDF(t, out.Bytes())
+		assertPageCount(t, out.Bytes(), 3)
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		// The letterhead example of the package documentation.
+		csv := csvString(
+			"page,watermark_text,pdf,pdf_page,opacity,rotation",
+			"1,,letterhead.pdf,1,1,0",
+			"2-last,,letterhead.pdf,2,1,0",
+		)
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv,
+			WithPDFStamps(map[string][]byte{"letterhead.pdf": letterhead}))
+		if err != nil {
+			t.Fatalf("WatermarkWithOptions: %v", err)
+		}
+		r, err := Inspect(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatalf("Inspect: %v", err)
+		}
+		for _, p := range r.Pages {
+			if w := p.Watermarks; len(w) != 1 || w[0].Kind != PDFWatermark || w[0].Rotation != 0 {
+				t.Errorf("page %d watermarks = %+v, want one unrotated PDF stamp", p.Page, w)
+			}
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV("1"))
+		if !errors.Is(err, ErrAssetNotFound) {
+			t.Errorf("got error %v, want ErrAssetNotFound", err)
+		}
+	})
+
+	t.Run("page out of range", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV("3"),
+			WithPDFStamps(map[string][]byte{"forms/letterhead.pdf": letterhead}))
+		if !errors.Is(err, ErrPageOutOfRange) {
+			t.Errorf("got error %v, want ErrPageOutOfRange", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var out bytes.Buffer
+		err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV("1"),
+			WithPDFStamps(map[string][]byte{"forms/letterhead.pdf": []byte("not a pdf")}))
+		if !errors.Is(err, ErrInvalidPDF) {
+			t.Errorf("got error %v, want ErrInvalidPDF", err)
+		}
+	})
+}
+
+func TestWatermarkWithOptions_TemplateVars(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	csv := csvString(
+		"page,watermark_text",
+		`all,"Copy for {{recipient}} – page {{page}}/{{total_pages}}"`,
+	)
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv,
+		WithTemplateVars(map[string]string{"recipient": "Jane Doe"}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+}
+
+func TestWatermarkWithOptions_UnknownTemplateVar(t *testing.T) {
+	pdf := createTestPDF(t, 1)
+	csv := csvString(
+		"page,watermark_text",
+		"1,Copy for {{recipient}}",
+	)
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), csv)
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	parsed := []spec.Instruction{
+		{Pages: mustPages(t, "all"), Text: "{{filename}} {{page}}/{{total_pages}} for {{recipient}}", Line: 2},
+		{Pages: mustPages(t, "1"), Text: "DRAFT", Line: 3},
+	}
+	vars := map[string]string{"recipient": "Jane"}
+	templates, err := parseTemplates(parsed, []string{"recipient"})
+	if err != nil {
+		t.Fatalf("parseTemplates: %v", err)
+	}
+	pages, _, err := spec.Expand(parsed, 2, true, spec.Strict)
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if err := renderTemplates(pages, templates, tmpl.Data{TotalPages: 2, Filename: "a.pdf", Vars: vars}); err != nil {
+		t.Fatalf("renderTemplates: %v", err)
+	}
+
+	if got := pages[1][0].Text; got != "a.pdf 1/2 for Jane" {
+		t.Errorf("page 1 text = %q", got)
+	}
+	if got := pages[1][1].Text; got != "DRAFT" {
+		t.Errorf("page 1 literal text = %q", got)
+	}
+	if got := pages[2][0].Text; got != "a.pdf 2/2 for Jane" {
+		t.Errorf("page 2 text = %q", got)
+	}
+}
+
+func TestRenderTemplates_Empty(t *testing.T) {
+	parsed := []spec.Instruction{{Pages: mustPages(t, "1"), Text: "{{recipient}}", Line: 2}}
+	templates, err := parseTemplates(parsed, []string{"recipient"})
+	if err != nil {
+		t.Fatalf("parseTemplates: %v", err)
+	}
+	pages, _, _ := spec.Expand(parsed, 1, false, spec.Strict)
+	err = renderTemplates(pages, templates, tmpl.Data{TotalPages: 1, Vars: map[string]string{"recipient": ""}})
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("got error %v, want ErrInvalidTemplate", err)
+	}
+}
+
+func mustPages(t *testing.T, s string) spec.Pages {
+	t.Helper()
+	p, err := spec.ParsePages(s)
+	if err != nil {
+		t.Fatalf("ParsePages(%q): %v", s, err)
+	}
+	return p
+}
+
+func TestWatermarkReaderAt(t *testing.T) {
+	pdf := createTestPDF(t, 3)
+	path := filepath.Join(t.TempDir(), "in.pdf")
+	if err := os.WriteFile(path, pdf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	err = WatermarkReaderAt(context.Background(), nopWriteCloser{&out}, f, int64(len(pdf)), csvString(
+		"page,watermark_text",
+		"2,DRAFT",
+	))
+	if err != nil {
+		t.Fatalf("WatermarkReaderAt: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+	assertPageCount(t, out.Bytes(), 3)
+}
+
+func TestWatermarkWithOptions_MaxInputSize(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	newCSV := func() io.Reader { return csvString("page,watermark_text", "1,DRAFT") }
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+		WithMaxInputSize(int64(len(pdf)-1)))
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("got error %v, want ErrInputTooLarge", err)
+	}
+
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, io.MultiReader(bytes.NewReader(pdf)), newCSV(),
+		WithMaxInputSize(int64(len(pdf))))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions at the limit: %v", err)
+	}
+	assertValidPDF(t, out.Bytes())
+}
+
+func TestWatermarkWithOptions_EncryptedInput(t *testing.T) {
+	pdf := testutil.EncryptPDF(t, createTestPDF(t, 2), "user", "owner")
+	newCSV := func() io.Reader { return csvString("page,watermark_text", "1,DRAFT") }
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV())
+	if !errors.Is(err, ErrEncryptedPDF) {
+		t.Errorf("without password: got error %v, want ErrEncryptedPDF", err)
+	}
+
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+		WithPasswords("user", "owner"))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	if _, ok := testutil.PDFPermissions(t, out.Bytes(), "user", "owner"); !ok {
+		t.Error("output is not encrypted, want the input's encryption kept")
+	}
+}
+
+func TestWatermarkWithOptions_Encryption(t *testing.T) {
+	pdf := createTestPDF(t, 2)
+	newCSV := func() io.Reader { return csvString("page,watermark_text", "1,CONFIDENTIAL") }
+
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+		WithEncryption(Encryption{UserPassword: "reader", KeyLength: 64}))
+	if !errors.Is(err, ErrInvalidEncryption) {
+		t.Errorf("got error %v, want ErrInvalidEncryption", err)
+	}
+
+	out.Reset()
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(pdf), newCSV(),
+		WithEncryption(Encryption{UserPassword: "reader", OwnerPassword: "admin", KeyLength: 128, Permissions: PermitPrint}))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	perms, ok := testutil.PDFPermissions(t, out.Bytes(), "reader", "")
+	if !ok {
+		t.Fatal("output is not encrypted")
+	}
+	if perms&model.PermissionPrintRev3 == 0 || perms&model.PermissionExtractRev3 != 0 {
+		t.Errorf("permissions %#x, want printing only", perms)
+	}
+	if _, err := Plan(context.Background(), bytes.NewReader(out.Bytes()), newCSV()); !errors.Is(err, ErrEncryptedPDF) {
+		t.Errorf("planning without password: got error %v, want ErrEncryptedPDF", err)
+	}
+	plan, err := Plan(context.Background(), bytes.NewReader(out.Bytes()), newCSV(), WithPasswords("reader", ""))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.TotalPages != 2 {
+		t.Errorf("TotalPages = %d, want 2", plan.TotalPages)
+	}
+}
+
+func TestWatermarkWithOptions_Tiled(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 2)),
+		csvString("page,watermark_text,position,font_size", "1,DRAFT,tiled,20", "2,DRAFT,,"))
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	got := watermarkTexts(t, out.Bytes())
+	if len(got[0]) != 1 || got[0][0] != "DRAFT" || len(got[1]) != 1 {
+		t.Errorf("watermarks %q on page 1 and %q on page 2, want the tiles in one and one", got[0], got[1])
+	}
+
+	style := DefaultStyle()
+	style.Position = Tiled
+	style.Tile = Tiling{}
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text,font_size", "1,x,1"), WithStyle(style))
+	if !errors.Is(err, ErrInvalidStyle) {
+		t.Errorf("a million tiles: got error %v, want ErrInvalidStyle", err)
+	}
+
+	style = DefaultStyle()
+	style.Position = Tiled
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text,image", "1,,logo"), WithStyle(style),
+		WithImages(map[string][]byte{"logo": createTestPNG(t, 4, 4)}))
+	if p := Problems(err); !errors.Is(err, ErrInvalidStyle) || len(p) != 1 || p[0].Line != 2 {
+		t.Errorf("tiled image: got error %v, want ErrInvalidStyle on line 2", err)
+	}
+}
+
+func TestWatermarkWithOptions_Footer(t *testing.T) {
+	var out bytes.Buffer
+	err := WatermarkWithOptions(context.Background(), nopWriteCloser{&out}, bytes.NewReader(createTestPDF(t, 3)),
+		csvString("page,watermark_text,position,margin,font_size",
+			"all,Company Confidential – Page {{page}} of {{total_pages}},footer,24,9",
+			"2,INTERNAL,left-margin,,"),
+		WithLayering())
+	if err != nil {
+		t.Fatalf("WatermarkWithOptions: %v", err)
+	}
+	r, err := Inspect(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	w := r.Pages[1].Watermarks
+	if len(w) != 2 || w[0].Text != "Company Confidential – Page 2 of 3" || w[0].FontSize != 9 || w[0].Rotation != 0 {
+		t.Fatalf("page 2 watermarks = %+v", w)
+	}
+	if w[1].Text != "INTERNAL" || w[1].Rotation != 90 {
+		t.Errorf("margin watermark = %+v, want INTERNAL turned by 90 degrees", w[1])
+	}
+
+	err = WatermarkWithOptions(context.Background(), nopWriteCloser{&bytes.Buffer{}}, bytes.NewReader(createTestPDF(t, 1)),
+		csvString("page,watermark_text,position,margin", "1,X,header,-5"))
+	if !errors.Is(err, ErrMalformedCSV) {
+		t.Errorf("negative margin: got error %v, want ErrMalformedCSV", err)
+	}
+}